@@ -17,6 +17,9 @@ This application requires a Redis instance to be running.
 To start a Redis instance with Docker, run: docker run -p 6379:6379 --name redis -d redis:6.2
 */
 
+// Upper bound on how long graceful shutdown may take
+const shutdownTimeout = 30 * time.Second
+
 func main() {
     config, err := config.LoadConfig()
     if err != nil {
@@ -24,7 +27,11 @@ func main() {
         os.Exit(1)
     }
 
-    if err := logger.InitLogger(config.LogLevel); err != nil {
+    var logOpts []logger.Option
+    if config.LogFilePath != "" {
+        logOpts = append(logOpts, logger.WithFile(config.LogFilePath, config.LogMaxSizeMB, config.LogMaxBackups, config.LogMaxAgeDays))
+    }
+    if err := logger.InitLogger(config.LogLevel, logOpts...); err != nil {
         logger.Log.Error("Failed to initialize logger", zap.Error(err))
         os.Exit(1)
     }
@@ -32,13 +39,18 @@ func main() {
 
     logger.Log.Info("Starting indexer service", zap.String("version", "1.0.0"))
 
-    // Construct the administrator with config
-    admin := administrator.New(config)
-
     // Create a cancellable context for graceful shutdown
     ctx, cancel := context.WithCancel(context.Background())
     defer cancel()
 
+    // Construct the administrator with config
+    admin, err := administrator.New(ctx, config)
+    if err != nil {
+        logger.Log.Error("Failed to create administrator", zap.Error(err))
+        logger.Log.Sync()
+        os.Exit(1)
+    }
+
     // Start background processing
     if err := admin.ProcessAndIndex(ctx); err != nil {
         logger.Log.Fatal("Failed to start indexer processing", zap.Error(err))
@@ -58,11 +70,15 @@ func main() {
     cancel() // stop reading from queue
 
     // Create a context with a timeout for shutdown
-    _, shutdownCancel := context.WithTimeout(context.Background(), 30 * time.Second)
+    shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), shutdownTimeout)
     defer shutdownCancel()
 
-    // Gracefully stop the workers and BulkIndexer
-    admin.Stop()
+    // Gracefully stop the ingestion server, workers and BulkIndexer
+    if err := admin.Stop(shutdownCtx); err != nil {
+        logger.Log.Error("Shutdown did not complete cleanly", zap.Error(err))
+        logger.Log.Sync()
+        os.Exit(1)
+    }
 
     logger.Log.Info("Shutdown complete")
 }
\ No newline at end of file
@@ -3,15 +3,26 @@ package administrator
 
 import (
     "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "strings"
+    "sync"
     "time"
     "go.uber.org/zap"
     "indexer/internal/pkg/config"
     "indexer/internal/pkg/logger"
+    "indexer/internal/pkg/metrics"
     "indexer/internal/pkg/deduplicator"
+    "indexer/internal/pkg/esclient"
     "indexer/internal/pkg/indexer"
     "indexer/internal/pkg/models"
     "indexer/internal/pkg/processor"
+    "indexer/internal/pkg/processor/spamdetector"
+    "indexer/internal/pkg/processor/urlfilter"
     "indexer/internal/pkg/queue"
+    "indexer/internal/pkg/robotscache"
     "indexer/internal/pkg/worker"
 )
 
@@ -20,60 +31,227 @@ type Administrator interface {
     EnqueuePageData(ctx context.Context, data models.PageData) error
     ProcessAndIndex(ctx context.Context) error
     StartService(port string)
-    Stop()
-    QueueDepth() int
+    Stop(ctx context.Context) error
+    QueueStats() queue.QueueStats
     WorkerCount() int
     StartTime() time.Time
+    SetNLPRateLimit(rps float64, burst int) error
 }
 
 // Implementation of the Administrator interface
 type administrator struct {
     indexer     *indexer.BulkIndexer
-    queue       *queue.Queue
+    queue       queue.FifoQueue
     processor   processor.Processor
+    processorStats *processor.ProcessorStats
+    deduper     deduper.Deduper
     workerPool  *worker.WorkerPool
+    cancelWorkers context.CancelFunc
     startTime   time.Time
     numWorkers  int
+    maxIngestBodyBytes int64
+    tlsCertFile string
+    tlsKeyFile  string
+    ingestReadTimeout  time.Duration
+    ingestWriteTimeout time.Duration
+    ingestIdleTimeout  time.Duration
+    nlpHealthCheckTimeout time.Duration
+
+    // Ingestion server, set once StartService is listening
+    serverMutex sync.Mutex
+    server      *http.Server
 }
 
-// Creates a new instance of an Administrator with a config
-func New(config *config.Config) Administrator {
-    pageQueue, err := queue.CreateQueue(config.QueueCapacity)
+// Creates a new instance of an Administrator with a config. Cancelling ctx
+// stops the bulk indexer's background flushing, Stop still sends what is
+// left. Returns an error if any of its dependencies cannot be set up.
+func New(ctx context.Context, config *config.Config) (Administrator, error) {
+    indexRoutes, err := indexer.ParseIndexRoutes(config.IndexRoutes)
+    if err != nil {
+        return nil, fmt.Errorf("failed to parse INDEX_ROUTES: %w", err)
+    }
+    indexThresholds, err := indexer.ParseIndexThresholds(config.IndexBulkThresholds)
+    if err != nil {
+        return nil, fmt.Errorf("failed to parse INDEX_BULK_THRESHOLDS: %w", err)
+    }
+
+    var queueOpts []queue.Option
+    if config.QueueDropWhenFull {
+        queueOpts = append(queueOpts, queue.WithOnFullCallback(logDroppedPage))
+    }
+    pageQueue, err := queue.CreateQueue(config.QueueCapacity, queueOpts...)
     if err != nil {
-        logger.Log.Fatal("Failed to create queue", zap.Error(err))
+        return nil, fmt.Errorf("failed to create queue: %w", err)
     }
 
-    deduper, err := deduper.NewRedisDeduper(config)
+    var dedup deduper.Deduper
+    if config.RedisClusterEnabled {
+        dedup, err = deduper.NewRedisClusterDeduper(config)
+    } else {
+        dedup, err = deduper.NewRedisDeduper(config)
+    }
     if err != nil {
-        logger.Log.Fatal("Failed to create deduper", zap.Error(err))
+        return nil, fmt.Errorf("failed to create deduper: %w", err)
     }
 
-    bulkIndexer := indexer.NewBulkIndexer(
+    // Shared by every writer so they fail over between endpoints together
+    esClient := esclient.New(config.ElasticsearchURL,
+        esclient.WithFallbackURLs(splitList(config.ElasticsearchFallbackURLs)),
+        esclient.WithBasicAuth(config.ESUsername, config.ESPassword),
+    )
+
+    indexerOpts := []indexer.Option{
+        indexer.WithClient(esClient),
+        indexer.WithDryRun(config.DryRun),
+        indexer.WithMaxConcurrentFlushes(config.BulkMaxConcurrentFlushes),
+        indexer.WithIndexRoutes(indexRoutes),
+    }
+    if config.InboundLinkCountsEnabled {
+        // Scripted upserts keep the counted links when a page is re-indexed
+        indexerOpts = append(indexerOpts,
+            indexer.WithScriptedUpserts(true),
+            indexer.WithPostFlushHook(indexer.NewLinkCountAggregator(esClient).Aggregate),
+        )
+    }
+    for index, threshold := range indexThresholds {
+        indexerOpts = append(indexerOpts, indexer.WithIndexThreshold(index, threshold))
+    }
+
+    bulkIndexer, err := indexer.NewBulkIndexer(
+        ctx,
         config.BulkThreshold,
         config.ElasticsearchURL,
         config.IndexName,
         config.FlushInterval,
         config.MaxRetries,
+        indexerOpts...,
     )
+    if err != nil {
+        dedup.Close()
+        return nil, fmt.Errorf("failed to create bulk indexer: %w", err)
+    }
 
-    proc := processor.NewProcessor(deduper, config.NlpServiceURL, config.SpamBlockThreshold)
-    
+    // Make sure the target indices exist before any worker starts flushing
+    ensureCtx, cancel := context.WithTimeout(ctx, 10 * time.Second)
+    defer cancel()
+    if err := bulkIndexer.EnsureIndex(ensureCtx, json.RawMessage(indexer.DefaultIndexMapping)); err != nil {
+        bulkIndexer.Stop()
+        dedup.Close()
+        return nil, fmt.Errorf("failed to ensure Elasticsearch index: %w", err)
+    }
+
+    var spamEvents spamdetector.SpamEventWriter = spamdetector.NoopSpamEventWriter{}
+    if !config.DryRun {
+        spamEvents = spamdetector.NewElasticsearchSpamEventWriter(esClient, config.SpamEventsIndex)
+    }
+    var categories map[string][]string
+    if config.CategoryMapFile != "" {
+        categories, err = processor.LoadCategoryMapFile(config.CategoryMapFile)
+    } else if config.CategoryMap != "" {
+        categories, err = processor.ParseCategoryMap([]byte(config.CategoryMap))
+    }
+    if err != nil {
+        bulkIndexer.Stop()
+        dedup.Close()
+        return nil, fmt.Errorf("failed to load category map: %w", err)
+    }
+
+    // Patterns from the file and the inline list apply together
+    urlPatterns := splitList(config.URLFilterPatterns)
+    if config.URLFilterFile != "" {
+        filePatterns, err := urlfilter.ReadFile(config.URLFilterFile)
+        if err != nil {
+            bulkIndexer.Stop()
+            dedup.Close()
+            return nil, fmt.Errorf("failed to load URL filter: %w", err)
+        }
+        urlPatterns = append(urlPatterns, filePatterns...)
+    }
+    urlFilter := urlfilter.New(urlPatterns)
+
+    var robots processor.RobotsChecker
+    if config.RobotsCacheTTLMinutes > 0 {
+        robots = robotscache.New(time.Duration(config.RobotsCacheTTLMinutes) * time.Minute)
+    }
+
+    proc := processor.NewProcessor(dedup, processor.ProcessorConfig{
+        NLPServiceURL:     config.NlpServiceURL,
+        SpamThreshold:     config.SpamBlockThreshold,
+        BatchSize:         config.NlpBatchSize,
+        BatchTimeout:      time.Duration(config.NlpBatchTimeoutMs) * time.Millisecond,
+        MaxBatchWait:      time.Duration(config.NlpMaxBatchWaitMs) * time.Millisecond,
+        MaxTextBytes:      config.NlpMaxTextBytes,
+        SpamEvents:        spamEvents,
+        Categories:        categories,
+        LocalNLPFallback:  config.NlpLocalFallbackEnabled,
+        SummarizeMinWords: config.SummarizeMinWordCount,
+        SkipDomains:       splitList(config.SkipDomains),
+        NLPWorkers:        config.NumNLPWorkers,
+        Robots:            robots,
+        CircuitBreakerWebhookURL: config.CircuitBreakerWebhookURL,
+    })
+
+    admin := NewWithDeps(config, proc, bulkIndexer, pageQueue, worker.WithURLFilter(urlFilter)).(*administrator)
+    admin.deduper = dedup
+    return admin, nil
+}
+
+// Creates a new instance of an Administrator around an existing processor,
+// bulk indexer and queue. Only the worker and ingest settings are read from
+// config, so tests can inject their own dependencies. Any worker options
+// are applied after those derived from config.
+func NewWithDeps(config *config.Config, proc processor.Processor, idx *indexer.BulkIndexer, q *queue.Queue, workerOpts ...worker.Option) Administrator {
     // Get number of workers from config
     numWorkers := config.NumWorkers
     if numWorkers <= 0 {
         numWorkers = 1 // Default to 1 worker if not specified
     }
     
-    wp := worker.NewWorkerPool(numWorkers, pageQueue, proc, bulkIndexer)
+    // Workers dequeue mini-batches and process their pages in parallel
+    workerProc := proc
+    if config.WorkerBatchSize > 1 {
+        workerProc = processor.NewConcurrentProcessor(proc, config.ProcessBatchConcurrency)
+    }
+
+    opts := append([]worker.Option{
+        worker.WithAutoRestart(config.WorkerAutoRestart),
+        worker.WithBatchSize(config.WorkerBatchSize),
+    }, workerOpts...)
+    wp := worker.NewWorkerPool(numWorkers, q, workerProc, idx, opts...)
     
     return &administrator{
-        indexer:     bulkIndexer,
-        queue:       pageQueue,
+        indexer:     idx,
+        queue:       q,
         processor:   proc,
+        processorStats: processor.NewProcessorStats(),
         workerPool:  wp,
         startTime:   time.Now(),
         numWorkers:  numWorkers,
+        maxIngestBodyBytes: config.MaxIngestBodyBytes,
+        tlsCertFile: config.TLSCertFile,
+        tlsKeyFile:  config.TLSKeyFile,
+        ingestReadTimeout:  time.Duration(config.IngestReadTimeoutSeconds) * time.Second,
+        ingestWriteTimeout: time.Duration(config.IngestWriteTimeoutSeconds) * time.Second,
+        ingestIdleTimeout:  time.Duration(config.IngestIdleTimeoutSeconds) * time.Second,
+        nlpHealthCheckTimeout: time.Duration(config.NlpHealthCheckTimeoutSeconds) * time.Second,
+    }
+}
+
+// Records a page dropped because it arrived while the queue was full.
+func logDroppedPage(dropped models.PageData) {
+    metrics.QueueOverflowDrops.Inc()
+    logger.Log.Warn("Queue full, dropping page", zap.String("url", dropped.URL))
+}
+
+// Splits a comma-separated config value, dropping empty entries
+func splitList(value string) []string {
+    var items []string
+    for _, item := range strings.Split(value, ",") {
+        if item = strings.TrimSpace(item); item != "" {
+            items = append(items, item)
+        }
     }
+    return items
 }
 
 func (admin *administrator) EnqueuePageData(ctx context.Context, data models.PageData) error {
@@ -83,8 +261,20 @@ func (admin *administrator) EnqueuePageData(ctx context.Context, data models.Pag
 
 // Processes and indexes the page data with parallel workers
 func (admin *administrator) ProcessAndIndex(ctx context.Context) error {
-    // Start the worker pool with the provided context
-    admin.workerPool.Start(ctx)
+    // Fail fast rather than letting the first batches trip the circuit breaker
+    if checker, ok := admin.processor.(processor.NLPHealthChecker); ok {
+        healthCtx, cancel := context.WithTimeout(ctx, admin.nlpHealthCheckTimeout)
+        err := checker.CheckNLPHealth(healthCtx)
+        cancel()
+        if err != nil {
+            return fmt.Errorf("NLP service health check failed: %w", err)
+        }
+    }
+
+    // Start the worker pool with a context Stop can cancel to abandon hung workers
+    workerCtx, cancel := context.WithCancel(ctx)
+    admin.cancelWorkers = cancel
+    admin.workerPool.Start(workerCtx)
     return nil
 }
 
@@ -94,27 +284,80 @@ func (admin *administrator) StartService(port string) {
     startIngestHTTP(admin, port)
 }
 
-// Stops the BulkIndexer and worker pool gracefully
-func (admin *administrator) Stop() {
+// Stops the ingestion server, BulkIndexer and worker pool gracefully.
+// If the workers have not finished by the time ctx is done an error is
+// returned and they are abandoned rather than interrupted: they stop taking
+// new pages, but Processor.Process takes no context, so a page already being
+// processed runs on after Stop returns and its document is never indexed.
+func (admin *administrator) Stop(ctx context.Context) error {
     logger.Log.Info("Beginning shutdown sequence")
+
+    // Stop accepting requests before the queue closes, letting in-flight ones finish
+    if server := admin.ingestServer(); server != nil {
+        logger.Log.Info("Shutting down HTTP ingestion service")
+        if err := server.Shutdown(ctx); err != nil {
+            logger.Log.Error("HTTP ingestion service did not shut down cleanly", zap.Error(err))
+        }
+    }
     
-    // First flush and stop accepting new items in the queue
+    // Then flush and stop accepting new items in the queue
     admin.queue.Close() // Assuming queue has a Close method to stop accepting new items
     
     logger.Log.Info("Waiting for worker pool to finish processing existing items")
     // Wait for workers to finish current work
-    admin.workerPool.Wait()
+    waitErr := admin.workerPool.WaitContext(ctx)
+    if waitErr != nil {
+        logger.Log.Error("Worker pool did not finish before shutdown deadline, abandoning running workers",
+            zap.Ints("worker_ids", admin.workerPool.RunningWorkers()))
+        // Keeps idle workers from picking up more pages, busy ones finish on their own
+        if admin.cancelWorkers != nil {
+            admin.cancelWorkers()
+        }
+    } else {
+        logger.Log.Info("Worker pool shutdown complete")
+    }
     
-    logger.Log.Info("Worker pool shutdown complete, stopping bulk indexer")
+    // Workers are done with the processor, or abandoned, so its NLP batching can stop
+    if stopper, ok := admin.processor.(processor.Stopper); ok {
+        logger.Log.Info("Stopping processor")
+        stopper.Stop()
+    }
+
+    logger.Log.Info("Stopping bulk indexer")
     // Then stop the BulkIndexer and wait for pending requests
     admin.indexer.Stop()
+
+    if admin.deduper != nil {
+        if err := admin.deduper.Close(); err != nil {
+            logger.Log.Warn("Failed to close deduper", zap.Error(err))
+        }
+    }
+
+    if waitErr != nil {
+        return fmt.Errorf("worker pool did not stop before shutdown deadline: %w", waitErr)
+    }
     
     logger.Log.Info("Administrator stopped gracefully")
+    return nil
+}
+
+// Records the running ingestion server so Stop can shut it down.
+func (admin *administrator) setIngestServer(server *http.Server) {
+    admin.serverMutex.Lock()
+    defer admin.serverMutex.Unlock()
+    admin.server = server
 }
 
-// Returns the current queue depth for health checks
-func (admin *administrator) QueueDepth() int {
-    return admin.queue.Length()
+// Returns the running ingestion server, or nil if it was never started.
+func (admin *administrator) ingestServer() *http.Server {
+    admin.serverMutex.Lock()
+    defer admin.serverMutex.Unlock()
+    return admin.server
+}
+
+// Returns the queue capacity, depth and lifetime counts for health checks
+func (admin *administrator) QueueStats() queue.QueueStats {
+    return admin.queue.Stats()
 }
 
 // Returns the number of workers for health checks
@@ -125,4 +368,14 @@ func (admin *administrator) WorkerCount() int {
 // Returns when the service was started for health checks
 func (admin *administrator) StartTime() time.Time {
     return admin.startTime
-}
\ No newline at end of file
+}
+
+// Updates the NLP service rate limit without restarting the service
+func (admin *administrator) SetNLPRateLimit(rps float64, burst int) error {
+    limiter, ok := admin.processor.(processor.NLPRateLimiter)
+    if !ok {
+        return errors.New("processor does not support NLP rate limiting")
+    }
+    limiter.SetNLPRateLimit(rps, burst)
+    return nil
+}
@@ -2,19 +2,233 @@ package administrator
 
 import (
     "time"
+    "errors"
     "encoding/json"
     "encoding/gob"
+    "net"
     "net/http"
     "github.com/prometheus/client_golang/prometheus/promhttp"
     "go.uber.org/zap"
     "indexer/internal/pkg/logger"
     "indexer/internal/pkg/models"
+    "indexer/internal/pkg/queue"
 )
 
 // Starts the HTTP ingestion service. This is a simple HTTP server that 
 // listens for incoming page data and provides a /health endpoint for monitoring.
 func startIngestHTTP(admin *administrator, port string) {
-    http.HandleFunc("/index", func(writer http.ResponseWriter, request *http.Request) {
+    server := newIngestServer(admin, port)
+
+    listener, err := net.Listen("tcp", server.Addr)
+    if err != nil {
+        logger.Log.Fatal("Failed to start ingestion service", zap.Error(err))
+    }
+
+    admin.setIngestServer(server)
+
+    logger.Log.Info("HTTP ingestion service listening",
+        zap.String("address", server.Addr),
+        zap.Bool("tls", admin.tlsCertFile != "" && admin.tlsKeyFile != ""))
+
+    if err := serveIngest(server, listener, admin.tlsCertFile, admin.tlsKeyFile); err != nil && !errors.Is(err, http.ErrServerClosed) {
+        logger.Log.Fatal("Failed to start ingestion service", zap.Error(err))
+    }
+}
+
+// Serves on the listener, using TLS when both a certificate and key are configured.
+func serveIngest(server *http.Server, listener net.Listener, certFile, keyFile string) error {
+    if certFile != "" && keyFile != "" {
+        return server.ServeTLS(listener, certFile, keyFile)
+    }
+    return server.Serve(listener)
+}
+
+// Builds the ingestion server on its own ServeMux, so that several
+// servers can coexist (e.g. in tests) without sharing http.DefaultServeMux.
+// The timeouts stop slow clients from holding connections open indefinitely.
+func newIngestServer(admin *administrator, port string) *http.Server {
+    return &http.Server{
+        Addr:         ":" + port,
+        Handler:      loggingMiddleware(newIngestMux(admin)),
+        ReadTimeout:  admin.ingestReadTimeout,
+        WriteTimeout: admin.ingestWriteTimeout,
+        IdleTimeout:  admin.ingestIdleTimeout,
+    }
+}
+
+// Captures the status code written by a handler.
+type statusRecorder struct {
+    http.ResponseWriter
+    status int
+}
+
+func (recorder *statusRecorder) WriteHeader(status int) {
+    recorder.status = status
+    recorder.ResponseWriter.WriteHeader(status)
+}
+
+// Allows http.ResponseController to reach the underlying writer.
+func (recorder *statusRecorder) Unwrap() http.ResponseWriter {
+    return recorder.ResponseWriter
+}
+
+// Logs the method, path, status and duration of every request.
+func loggingMiddleware(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+        start := time.Now()
+        recorder := &statusRecorder{ResponseWriter: writer, status: http.StatusOK}
+        next.ServeHTTP(recorder, request)
+        logger.Log.Info("HTTP request",
+            zap.String("method", request.Method),
+            zap.String("path", request.URL.Path),
+            zap.Int("status", recorder.status),
+            zap.Duration("duration", time.Since(start)))
+    })
+}
+
+// Registers the ingestion, metrics, health and admin endpoints on a fresh ServeMux.
+func newIngestMux(admin *administrator) *http.ServeMux {
+    mux := http.NewServeMux()
+
+    mux.HandleFunc("/v1/index", ingestHandler(admin))
+
+    // Unversioned clients are redirected, 308 keeps the method and body intact
+    mux.Handle("/index", http.RedirectHandler("/v1/index", http.StatusPermanentRedirect))
+
+    // Reserved for the next revision of the ingestion API
+    mux.HandleFunc("/v2/index", func(writer http.ResponseWriter, request *http.Request) {
+        http.Error(writer, "Not implemented", http.StatusNotImplemented)
+    })
+
+    // /metrics endpoint for Prometheus
+    mux.Handle("/metrics", promhttp.Handler())
+
+    // /health endpoint
+    mux.HandleFunc("/health", func(writer http.ResponseWriter, request *http.Request) {
+        queueStats := admin.QueueStats()
+        health := struct {
+            Status     string           `json:"status"`
+            QueueDepth int64            `json:"queue_depth"`
+            Queue      queue.QueueStats `json:"queue"`
+            Workers    int              `json:"workers"`
+            Uptime     string           `json:"uptime"`
+            StartTime  time.Time        `json:"start_time"`
+        }{
+            Status:     "OK",
+            QueueDepth: queueStats.Length,
+            Queue:      queueStats,
+            Workers:    admin.WorkerCount(),
+            Uptime:     time.Since(admin.StartTime()).String(),
+            StartTime:  admin.StartTime(),
+        }
+
+        writer.Header().Set("Content-Type", "application/json")
+        json.NewEncoder(writer).Encode(health)
+    })
+
+    // /admin/nlp/rate-limit endpoint for tuning NLP throughput at runtime
+    mux.HandleFunc("/admin/nlp/rate-limit", nlpRateLimitHandler(admin))
+
+    // /admin/processor/stats endpoint reporting skips per processing stage
+    mux.HandleFunc("/admin/processor/stats", processorStatsHandler(admin))
+
+    // /admin/workers endpoint reporting worker pool activity
+    mux.HandleFunc("/admin/workers", workersHandler(admin))
+
+    // /admin/dedup endpoint for resetting the stored page signatures
+    mux.HandleFunc("/admin/dedup", dedupHandler(admin))
+
+    return mux
+}
+
+// Handles POST requests that update the NLP rate limit, e.g. {"rps": 10, "burst": 20}.
+func nlpRateLimitHandler(admin *administrator) http.HandlerFunc {
+    return func(writer http.ResponseWriter, request *http.Request) {
+        if request.Method != http.MethodPost {
+            http.Error(writer, "method not allowed", http.StatusMethodNotAllowed)
+            return
+        }
+
+        var rateLimit struct {
+            RPS   float64 `json:"rps"`
+            Burst int     `json:"burst"`
+        }
+        if err := json.NewDecoder(request.Body).Decode(&rateLimit); err != nil {
+            http.Error(writer, "failed to decode request", http.StatusBadRequest)
+            return
+        }
+        if rateLimit.RPS <= 0 || rateLimit.Burst < 1 {
+            http.Error(writer, "rps must be positive and burst at least 1", http.StatusBadRequest)
+            return
+        }
+
+        if err := admin.SetNLPRateLimit(rateLimit.RPS, rateLimit.Burst); err != nil {
+            http.Error(writer, err.Error(), http.StatusInternalServerError)
+            logger.Log.Error("Failed to update NLP rate limit", zap.Error(err))
+            return
+        }
+
+        writer.Header().Set("Content-Type", "application/json")
+        json.NewEncoder(writer).Encode(rateLimit)
+    }
+}
+
+// Returns the processor skip counts on GET and resets them on DELETE.
+func processorStatsHandler(admin *administrator) http.HandlerFunc {
+    return func(writer http.ResponseWriter, request *http.Request) {
+        switch request.Method {
+        case http.MethodGet:
+            writer.Header().Set("Content-Type", "application/json")
+            json.NewEncoder(writer).Encode(struct {
+                Skipped map[string]int64 `json:"skipped"`
+            }{admin.processorStats.Skipped()})
+        case http.MethodDelete:
+            admin.processorStats.Reset()
+            logger.Log.Info("Processor stats reset")
+            writer.WriteHeader(http.StatusNoContent)
+        default:
+            http.Error(writer, "method not allowed", http.StatusMethodNotAllowed)
+        }
+    }
+}
+
+// Handles DELETE requests that clear every stored dedup signature.
+func dedupHandler(admin *administrator) http.HandlerFunc {
+    return func(writer http.ResponseWriter, request *http.Request) {
+        if request.Method != http.MethodDelete {
+            http.Error(writer, "method not allowed", http.StatusMethodNotAllowed)
+            return
+        }
+        // Administrators built with NewWithDeps have no deduper of their own
+        if admin.deduper == nil {
+            http.Error(writer, "deduplication is not configured", http.StatusNotImplemented)
+            return
+        }
+        if err := admin.deduper.Clear(); err != nil {
+            logger.Log.Error("Failed to clear dedup signatures", zap.Error(err))
+            http.Error(writer, "failed to clear signatures", http.StatusInternalServerError)
+            return
+        }
+        logger.Log.Info("Dedup signatures cleared")
+        writer.WriteHeader(http.StatusNoContent)
+    }
+}
+
+// Handles GET requests for the worker pool status.
+func workersHandler(admin *administrator) http.HandlerFunc {
+    return func(writer http.ResponseWriter, request *http.Request) {
+        if request.Method != http.MethodGet {
+            http.Error(writer, "method not allowed", http.StatusMethodNotAllowed)
+            return
+        }
+        writer.Header().Set("Content-Type", "application/json")
+        json.NewEncoder(writer).Encode(admin.workerPool.Status())
+    }
+}
+
+// Handles GOB-encoded page data submitted by the crawler and enqueues it.
+func ingestHandler(admin *administrator) http.HandlerFunc {
+    return func(writer http.ResponseWriter, request *http.Request) {
         var pageData models.PageData
 
         contentType := request.Header.Get("Content-Type")
@@ -24,7 +238,16 @@ func startIngestHTTP(admin *administrator, port string) {
             return
         }
 
+        // Cap the body size so oversized payloads can't exhaust memory
+        request.Body = http.MaxBytesReader(writer, request.Body, admin.maxIngestBodyBytes)
+
         if err := gob.NewDecoder(request.Body).Decode(&pageData); err != nil {
+            var maxBytesErr *http.MaxBytesError
+            if errors.As(err, &maxBytesErr) {
+                http.Error(writer, "request body too large", http.StatusRequestEntityTooLarge)
+                logger.Log.Warn("Rejected oversized payload", zap.Int64("limit_bytes", maxBytesErr.Limit))
+                return
+            }
             http.Error(writer, "failed to decode request", http.StatusBadRequest)
             logger.Log.Warn("Failed to decode incoming GOB", zap.Error(err))
             return
@@ -37,34 +260,5 @@ func startIngestHTTP(admin *administrator, port string) {
         }
         writer.WriteHeader(http.StatusAccepted)
         writer.Write([]byte("Page data enqueued"))
-    })
-
-    // /metrics endpoint for Prometheus
-    http.Handle("/metrics", promhttp.Handler())
-
-    // /health endpoint
-    http.HandleFunc("/health", func(writer http.ResponseWriter, request *http.Request) {
-        health := struct {
-            Status     string    `json:"status"`
-            QueueDepth int       `json:"queue_depth"`
-            Workers    int       `json:"workers"`
-            Uptime     string    `json:"uptime"`
-            StartTime  time.Time `json:"start_time"`
-        }{
-            Status:     "OK",
-            QueueDepth: admin.QueueDepth(),
-            Workers:    admin.WorkerCount(),
-            Uptime:     time.Since(admin.StartTime()).String(),
-            StartTime:  admin.StartTime(),
-        }
-
-        writer.Header().Set("Content-Type", "application/json")
-        json.NewEncoder(writer).Encode(health)
-    })
-
-    logger.Log.Info("HTTP ingestion service listening", zap.String("address", ":" + port))
-
-    if err := http.ListenAndServe(":" + port, nil); err != nil {
-        logger.Log.Fatal("Failed to start ingestion service", zap.Error(err))
     }
 }
@@ -3,15 +3,47 @@ package administrator
 import (
 	"bytes"
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/gob"
 	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
 	"io"
+	"math/big"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
+	"strings"
+	"sync/atomic"
 	"time"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"indexer/internal/pkg/config"
+	"indexer/internal/pkg/indexer"
+	"indexer/internal/pkg/logger"
+	"indexer/internal/pkg/metrics"
 	"indexer/internal/pkg/models"
+	"indexer/internal/pkg/processor"
+	"indexer/internal/pkg/queue"
+	"indexer/internal/pkg/testutil"
+	"indexer/internal/pkg/worker"
 )
 
+func init() {
+	// Ensure that the logger is not nil during tests.
+	logger.Log = zap.NewNop()
+}
+
 // dummyAdmin implements the Administrator interface minimally.
 // It only implements EnqueuePageData (others are no-ops) so we can verify that
 // the ingestion endpoint calls EnqueuePageData with the correct payload.
@@ -32,8 +64,9 @@ func (da *dummyAdmin) StartService(port string) {
 	// no-op for this dummy
 }
 
-func (da *dummyAdmin) Stop() {
+func (da *dummyAdmin) Stop(ctx context.Context) error {
 	// no-op for this dummy
+	return nil
 }
 
 func TestIngestHTTP(t *testing.T) {
@@ -94,3 +127,588 @@ func TestIngestHTTP(t *testing.T) {
 		t.Error("Timeout waiting for enqueued page data")
 	}
 }
+
+// rateLimitedProcessor is a no-op Processor that records NLP rate limit updates.
+type rateLimitedProcessor struct {
+	rps   float64
+	burst int
+}
+
+func (rp *rateLimitedProcessor) Process(pageData *models.PageData, doc *models.Document) error {
+	return nil
+}
+
+func (rp *rateLimitedProcessor) SetNLPRateLimit(rps float64, burst int) {
+	rp.rps = rps
+	rp.burst = burst
+}
+
+func TestNLPRateLimitHandler(t *testing.T) {
+	proc := &rateLimitedProcessor{}
+	handler := nlpRateLimitHandler(&administrator{processor: proc})
+
+	// A valid update is applied to the processor.
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, "/admin/nlp/rate-limit", bytes.NewBufferString(`{"rps": 10, "burst": 20}`))
+	handler(recorder, request)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d, body: %s", recorder.Code, recorder.Body.String())
+	}
+	if proc.rps != 10 || proc.burst != 20 {
+		t.Errorf("Expected rate limit 10/20, got %v/%d", proc.rps, proc.burst)
+	}
+
+	// Invalid values are rejected.
+	recorder = httptest.NewRecorder()
+	request = httptest.NewRequest(http.MethodPost, "/admin/nlp/rate-limit", bytes.NewBufferString(`{"rps": 0, "burst": 20}`))
+	handler(recorder, request)
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", recorder.Code)
+	}
+
+	// Only POST is allowed.
+	recorder = httptest.NewRecorder()
+	request = httptest.NewRequest(http.MethodGet, "/admin/nlp/rate-limit", nil)
+	handler(recorder, request)
+	if recorder.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405, got %d", recorder.Code)
+	}
+}
+
+// Verifies that processor stats can be read and reset over HTTP.
+func TestProcessorStatsHandler(t *testing.T) {
+	handler := processorStatsHandler(&administrator{processorStats: processor.NewProcessorStats()})
+
+	getSkipped := func() map[string]int64 {
+		recorder := httptest.NewRecorder()
+		handler(recorder, httptest.NewRequest(http.MethodGet, "/admin/processor/stats", nil))
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", recorder.Code)
+		}
+		var body struct {
+			Skipped map[string]int64 `json:"skipped"`
+		}
+		if err := json.NewDecoder(recorder.Body).Decode(&body); err != nil {
+			t.Fatalf("Failed to decode stats: %v", err)
+		}
+		return body.Skipped
+	}
+
+	metrics.HighSpamPagesSkipped.Inc()
+	if skipped := getSkipped(); skipped["high_spam"] != 1 {
+		t.Errorf("Expected 1 high spam skip, got %v", skipped)
+	}
+
+	recorder := httptest.NewRecorder()
+	handler(recorder, httptest.NewRequest(http.MethodDelete, "/admin/processor/stats", nil))
+	if recorder.Code != http.StatusNoContent {
+		t.Errorf("Expected status 204, got %d", recorder.Code)
+	}
+	if skipped := getSkipped(); skipped["high_spam"] != 0 {
+		t.Errorf("Expected stats to be reset, got %v", skipped)
+	}
+
+	recorder = httptest.NewRecorder()
+	handler(recorder, httptest.NewRequest(http.MethodPost, "/admin/processor/stats", nil))
+	if recorder.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405, got %d", recorder.Code)
+	}
+}
+
+// Verifies that the workers endpoint reports the worker pool status.
+func TestWorkersHandler(t *testing.T) {
+	q, err := queue.CreateQueue(10)
+	if err != nil {
+		t.Fatalf("Failed to create queue: %v", err)
+	}
+	admin := &administrator{workerPool: worker.NewWorkerPool(3, q, &testutil.MockProcessor{}, nil)}
+	server := httptest.NewServer(newIngestMux(admin))
+	defer server.Close()
+
+	response, err := http.Get(server.URL + "/admin/workers")
+	if err != nil {
+		t.Fatalf("Failed to query workers endpoint: %v", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", response.StatusCode)
+	}
+
+	var status worker.WorkerPoolStatus
+	if err := json.NewDecoder(response.Body).Decode(&status); err != nil {
+		t.Fatalf("Failed to decode workers status: %v", err)
+	}
+	if status != (worker.WorkerPoolStatus{NumWorkers: 3}) {
+		t.Errorf("Unexpected workers status: %+v", status)
+	}
+
+	response, err = http.Post(server.URL+"/admin/workers", "application/json", nil)
+	if err != nil {
+		t.Fatalf("Failed to send POST request: %v", err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405, got %d", response.StatusCode)
+	}
+}
+
+// stubDeduper counts Clear calls and fails them with err.
+type stubDeduper struct {
+	cleared int
+	err     error
+}
+
+func (sd *stubDeduper) IsDuplicate(signature string) bool     { return false }
+func (sd *stubDeduper) StoreSignature(signature string) error { return nil }
+func (sd *stubDeduper) Close() error                          { return nil }
+func (sd *stubDeduper) Clear() error {
+	sd.cleared++
+	return sd.err
+}
+
+// Verifies that DELETE /admin/dedup clears the stored signatures.
+func TestDedupHandler(t *testing.T) {
+	dedup := &stubDeduper{}
+	handler := dedupHandler(&administrator{deduper: dedup})
+
+	recorder := httptest.NewRecorder()
+	handler(recorder, httptest.NewRequest(http.MethodDelete, "/admin/dedup", nil))
+	if recorder.Code != http.StatusNoContent {
+		t.Errorf("Expected status 204, got %d", recorder.Code)
+	}
+	if dedup.cleared != 1 {
+		t.Errorf("Expected signatures to be cleared once, got %d", dedup.cleared)
+	}
+
+	recorder = httptest.NewRecorder()
+	handler(recorder, httptest.NewRequest(http.MethodGet, "/admin/dedup", nil))
+	if recorder.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405, got %d", recorder.Code)
+	}
+
+	dedup.err = errors.New("redis unavailable")
+	recorder = httptest.NewRecorder()
+	handler(recorder, httptest.NewRequest(http.MethodDelete, "/admin/dedup", nil))
+	if recorder.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status 500, got %d", recorder.Code)
+	}
+
+	recorder = httptest.NewRecorder()
+	dedupHandler(&administrator{})(recorder, httptest.NewRequest(http.MethodDelete, "/admin/dedup", nil))
+	if recorder.Code != http.StatusNotImplemented {
+		t.Errorf("Expected status 501 without a deduper, got %d", recorder.Code)
+	}
+}
+
+// Verifies that New reports setup failures instead of exiting.
+func TestNewReturnsError(t *testing.T) {
+	admin, err := New(context.Background(), &config.Config{QueueCapacity: 0})
+	if err == nil {
+		t.Fatal("Expected an error for an invalid queue capacity")
+	}
+	if admin != nil {
+		t.Errorf("Expected no administrator on error, got %v", admin)
+	}
+}
+
+// Verifies that an administrator built from injected dependencies processes
+// queued pages with the given processor.
+func TestNewWithDeps(t *testing.T) {
+	q, err := queue.CreateQueue(10)
+	if err != nil {
+		t.Fatalf("Failed to create queue: %v", err)
+	}
+	bulkIndexer, err := indexer.NewBulkIndexer(context.Background(), 100, "http://localhost:9200/_bulk", "test_index", 60, 0, indexer.WithDryRun(true))
+	if err != nil {
+		t.Fatalf("Failed to create BulkIndexer: %v", err)
+	}
+	proc := &testutil.MockProcessor{}
+	admin := NewWithDeps(&config.Config{NumWorkers: 2, WorkerBatchSize: 1}, proc, bulkIndexer, q)
+
+	for _, url := range []string{"https://example.com/a", "https://example.com/b"} {
+		if err := admin.EnqueuePageData(context.Background(), models.PageData{URL: url}); err != nil {
+			t.Fatalf("Failed to enqueue page data: %v", err)
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := admin.ProcessAndIndex(ctx); err != nil {
+		t.Fatalf("ProcessAndIndex error: %v", err)
+	}
+
+	deadline := time.Now().Add(3 * time.Second)
+	for atomic.LoadInt32(&proc.CallCount) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	stopCtx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := admin.Stop(stopCtx); err != nil {
+		t.Fatalf("Stop error: %v", err)
+	}
+
+	if admin.WorkerCount() != 2 {
+		t.Errorf("Expected 2 workers, got %d", admin.WorkerCount())
+	}
+	if got := atomic.LoadInt32(&proc.CallCount); got != 2 {
+		t.Errorf("Expected 2 pages to be processed, got %d", got)
+	}
+}
+
+// Verifies that Stop shuts down the ingestion server so no requests are
+// accepted once the queue is closed.
+func TestStopShutsDownIngestServer(t *testing.T) {
+	q, err := queue.CreateQueue(10)
+	if err != nil {
+		t.Fatalf("Failed to create queue: %v", err)
+	}
+	bulkIndexer, err := indexer.NewBulkIndexer(context.Background(), 100, "http://localhost:9200/_bulk", "test_index", 60, 0, indexer.WithDryRun(true))
+	if err != nil {
+		t.Fatalf("Failed to create BulkIndexer: %v", err)
+	}
+	admin := NewWithDeps(&config.Config{NumWorkers: 1, WorkerBatchSize: 1}, &testutil.MockProcessor{}, bulkIndexer, q)
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := admin.ProcessAndIndex(ctx); err != nil {
+		t.Fatalf("ProcessAndIndex error: %v", err)
+	}
+
+	// Reserve a free port for the service
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to reserve a port: %v", err)
+	}
+	port := fmt.Sprint(listener.Addr().(*net.TCPAddr).Port)
+	listener.Close()
+
+	served := make(chan struct{})
+	go func() {
+		defer close(served)
+		admin.StartService(port)
+	}()
+
+	healthURL := "http://127.0.0.1:" + port + "/health"
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		response, err := http.Get(healthURL)
+		if err == nil {
+			response.Body.Close()
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Ingestion service did not start: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	stopCtx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := admin.Stop(stopCtx); err != nil {
+		t.Fatalf("Stop error: %v", err)
+	}
+
+	select {
+	case <-served:
+	case <-time.After(3 * time.Second):
+		t.Fatal("Expected StartService to return after Stop")
+	}
+	if response, err := http.Get(healthURL); err == nil {
+		response.Body.Close()
+		t.Error("Expected requests to be refused after Stop")
+	}
+}
+
+// Encodes page data as the crawler would.
+func encodeGob(t *testing.T, pd models.PageData) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(pd); err != nil {
+		t.Fatalf("Failed to encode page data: %v", err)
+	}
+	return &buf
+}
+
+func TestIngestHandlerRejectsOversizedPayload(t *testing.T) {
+	q, err := queue.CreateQueue(10)
+	if err != nil {
+		t.Fatalf("Failed to create queue: %v", err)
+	}
+	handler := ingestHandler(&administrator{queue: q, maxIngestBodyBytes: 1024})
+
+	// A payload within the limit is enqueued.
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, "/v1/index", encodeGob(t, models.PageData{URL: "http://example.com/small"}))
+	request.Header.Set("Content-Type", "application/gob")
+	handler(recorder, request)
+	if recorder.Code != http.StatusAccepted {
+		t.Fatalf("Expected status 202, got %d, body: %s", recorder.Code, recorder.Body.String())
+	}
+
+	// A payload over the limit is rejected without being enqueued.
+	oversized := models.PageData{URL: "http://example.com/large", VisibleText: strings.Repeat("a", 4096)}
+	recorder = httptest.NewRecorder()
+	request = httptest.NewRequest(http.MethodPost, "/v1/index", encodeGob(t, oversized))
+	request.Header.Set("Content-Type", "application/gob")
+	handler(recorder, request)
+	if recorder.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status 413, got %d, body: %s", recorder.Code, recorder.Body.String())
+	}
+	if q.Length() != 1 {
+		t.Errorf("Expected only the small payload to be enqueued, got queue length %d", q.Length())
+	}
+}
+
+// Verifies that independent ingestion servers can be built side by side
+// and serve the health endpoint.
+func TestNewIngestServerIsolated(t *testing.T) {
+	for i := 0; i < 2; i++ {
+		q, err := queue.CreateQueue(10)
+		if err != nil {
+			t.Fatalf("Failed to create queue: %v", err)
+		}
+		admin := &administrator{queue: q, numWorkers: 3, startTime: time.Now()}
+		server := httptest.NewServer(newIngestServer(admin, "0").Handler)
+
+		response, err := http.Get(server.URL + "/health")
+		if err != nil {
+			server.Close()
+			t.Fatalf("Failed to query health endpoint: %v", err)
+		}
+		var health struct {
+			Status  string           `json:"status"`
+			Workers int              `json:"workers"`
+			Queue   queue.QueueStats `json:"queue"`
+		}
+		err = json.NewDecoder(response.Body).Decode(&health)
+		response.Body.Close()
+		server.Close()
+
+		if err != nil {
+			t.Fatalf("Failed to decode health response: %v", err)
+		}
+		if health.Status != "OK" || health.Workers != 3 || health.Queue.Capacity != 10 {
+			t.Errorf("Unexpected health response: %+v", health)
+		}
+	}
+}
+
+// Verifies that the unversioned path redirects to /v1/index and /v2/index is reserved.
+func TestIngestVersionedPaths(t *testing.T) {
+	q, err := queue.CreateQueue(10)
+	if err != nil {
+		t.Fatalf("Failed to create queue: %v", err)
+	}
+	server := httptest.NewServer(newIngestMux(&administrator{queue: q, maxIngestBodyBytes: 1 << 20}))
+	defer server.Close()
+
+	response, err := http.Post(server.URL+"/index", "application/gob", encodeGob(t, models.PageData{URL: "https://example.com/legacy"}))
+	if err != nil {
+		t.Fatalf("Failed to send POST request: %v", err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusAccepted {
+		t.Errorf("Expected redirected request to be accepted, got %d", response.StatusCode)
+	}
+	if response.Request.URL.Path != "/v1/index" {
+		t.Errorf("Expected redirect to /v1/index, got %s", response.Request.URL.Path)
+	}
+	if received, err := q.Remove(); err != nil || received.URL != "https://example.com/legacy" {
+		t.Errorf("Expected legacy page data in queue, got %+v (%v)", received, err)
+	}
+
+	response, err = http.Post(server.URL+"/v2/index", "application/gob", encodeGob(t, models.PageData{URL: "https://example.com/next"}))
+	if err != nil {
+		t.Fatalf("Failed to send POST request: %v", err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusNotImplemented {
+		t.Errorf("Expected status 501, got %d", response.StatusCode)
+	}
+	if !q.IsEmpty() {
+		t.Errorf("Expected /v2/index not to enqueue anything")
+	}
+}
+
+// Verifies that the ingestion server carries the configured connection timeouts.
+func TestNewIngestServerTimeouts(t *testing.T) {
+	admin := &administrator{
+		ingestReadTimeout:  10 * time.Second,
+		ingestWriteTimeout: 30 * time.Second,
+		ingestIdleTimeout:  60 * time.Second,
+	}
+	server := newIngestServer(admin, "0")
+
+	if server.ReadTimeout != 10*time.Second {
+		t.Errorf("Expected read timeout 10s, got %v", server.ReadTimeout)
+	}
+	if server.WriteTimeout != 30*time.Second {
+		t.Errorf("Expected write timeout 30s, got %v", server.WriteTimeout)
+	}
+	if server.IdleTimeout != 60*time.Second {
+		t.Errorf("Expected idle timeout 60s, got %v", server.IdleTimeout)
+	}
+}
+
+// Writes a self-signed certificate for 127.0.0.1 to dir and returns the
+// certificate and key paths along with the parsed certificate.
+func writeSelfSignedCert(t *testing.T, dir string) (string, string, *x509.Certificate) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "indexer-test"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("Failed to parse certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatalf("Failed to write certificate: %v", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatalf("Failed to write key: %v", err)
+	}
+	return certFile, keyFile, cert
+}
+
+// Verifies that the ingestion server serves TLS when a certificate and key are configured.
+func TestServeIngestTLS(t *testing.T) {
+	certFile, keyFile, cert := writeSelfSignedCert(t, t.TempDir())
+
+	q, err := queue.CreateQueue(10)
+	if err != nil {
+		t.Fatalf("Failed to create queue: %v", err)
+	}
+	admin := &administrator{queue: q, numWorkers: 1, startTime: time.Now()}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	server := newIngestServer(admin, "0")
+	go serveIngest(server, listener, certFile, keyFile)
+	defer server.Close()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	client := &http.Client{
+		Timeout:   2 * time.Second,
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}},
+	}
+
+	response, err := client.Get("https://" + listener.Addr().String() + "/health")
+	if err != nil {
+		t.Fatalf("TLS request failed: %v", err)
+	}
+	defer response.Body.Close()
+
+	if response.TLS == nil {
+		t.Error("Expected response over a TLS connection")
+	}
+	if response.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", response.StatusCode)
+	}
+}
+
+// Verifies that a valid body posted to the production ingestion handler
+// ends up in the queue as the same PageData.
+func TestIngestHandlerEnqueuesPageData(t *testing.T) {
+	q, err := queue.CreateQueue(10)
+	if err != nil {
+		t.Fatalf("Failed to create queue: %v", err)
+	}
+	server := httptest.NewServer(newIngestMux(&administrator{queue: q, maxIngestBodyBytes: 1 << 20}))
+	defer server.Close()
+
+	sent := models.PageData{
+		URL:           "https://example.com/ingest",
+		Title:         "Ingestion test",
+		VisibleText:   "Ingestion test body",
+		InternalLinks: []string{"https://example.com/about"},
+		Headings:      map[string][]string{"h1": {"Ingestion"}},
+	}
+	response, err := http.Post(server.URL+"/v1/index", "application/gob", encodeGob(t, sent))
+	if err != nil {
+		t.Fatalf("Failed to send POST request: %v", err)
+	}
+	body, _ := io.ReadAll(response.Body)
+	response.Body.Close()
+	if response.StatusCode != http.StatusAccepted {
+		t.Fatalf("Expected status 202, got %d, body: %s", response.StatusCode, string(body))
+	}
+
+	received, err := q.Remove()
+	if err != nil {
+		t.Fatalf("Expected page data in queue, got %v", err)
+	}
+	if received.URL != sent.URL || received.Title != sent.Title || received.VisibleText != sent.VisibleText {
+		t.Errorf("Enqueued data mismatch. Got %+v, expected %+v", received, sent)
+	}
+	if len(received.InternalLinks) != 1 || received.InternalLinks[0] != sent.InternalLinks[0] {
+		t.Errorf("Expected internal links %v, got %v", sent.InternalLinks, received.InternalLinks)
+	}
+	if len(received.Headings["h1"]) != 1 || received.Headings["h1"][0] != "Ingestion" {
+		t.Errorf("Expected headings %v, got %v", sent.Headings, received.Headings)
+	}
+}
+
+// Verifies that every request is logged with its method, path, status and duration.
+func TestLoggingMiddleware(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	previous := logger.Log
+	logger.Log = zap.New(core)
+	defer func() { logger.Log = previous }()
+
+	handler := loggingMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+
+	for _, path := range []string{"/health", "/missing"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	entries := logs.FilterMessage("HTTP request").All()
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 access log entries, got %d", len(entries))
+	}
+
+	expected := []struct {
+		path   string
+		status int64
+	}{
+		{"/health", http.StatusOK},
+		{"/missing", http.StatusNotFound},
+	}
+	for i, entry := range entries {
+		fields := entry.ContextMap()
+		if fields["method"] != http.MethodGet || fields["path"] != expected[i].path || fields["status"] != expected[i].status {
+			t.Errorf("Unexpected access log fields %v", fields)
+		}
+		if _, ok := fields["duration"]; !ok {
+			t.Errorf("Expected duration to be logged, got %v", fields)
+		}
+	}
+}
@@ -0,0 +1,16 @@
+package backoff
+
+import (
+    "math/rand/v2"
+    "time"
+)
+
+// Returns the exponential backoff before retry number attempt, starting at
+// base and doubling per attempt, with up to 25% jitter and capped at limit.
+// math/rand/v2 is safe for concurrent use by retrying goroutines.
+func Exponential(attempt int, base, limit time.Duration) time.Duration {
+    // Larger shifts would overflow and are past the cap anyway
+    backoff := min(base<<min(attempt, 30), limit)
+    jitter := rand.N(backoff/4 + 1)
+    return min(backoff+jitter, limit)
+}
@@ -0,0 +1,26 @@
+package backoff
+
+import (
+	"testing"
+	"time"
+)
+
+// Verifies that the backoff grows exponentially, is never zero and never
+// exceeds the cap.
+func TestExponential(t *testing.T) {
+	base, limit := time.Second, 30*time.Second
+	for attempt := 0; attempt <= 64; attempt++ {
+		for i := 0; i < 100; i++ {
+			backoff := Exponential(attempt, base, limit)
+			if backoff <= 0 {
+				t.Fatalf("Expected a positive backoff for attempt %d, got %v", attempt, backoff)
+			}
+			if backoff > limit {
+				t.Fatalf("Expected backoff for attempt %d to be at most %v, got %v", attempt, limit, backoff)
+			}
+			if attempt < 4 && backoff < base<<attempt {
+				t.Fatalf("Expected backoff for attempt %d to be at least %v, got %v", attempt, base<<attempt, backoff)
+			}
+		}
+	}
+}
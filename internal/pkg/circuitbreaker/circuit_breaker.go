@@ -1,7 +1,12 @@
 package circuitbreaker
 
 import (
+    "bytes"
+    "context"
+    "encoding/json"
     "errors"
+    "fmt"
+    "net/http"
     "sync"
     "time"
     
@@ -15,6 +20,12 @@ var (
     ErrCircuitOpen = errors.New("circuit breaker is open")
 )
 
+// Upper bound on a single webhook notification
+const webhookTimeout = 5 * time.Second
+
+// Sends webhook notifications, so a hung endpoint can't outlive webhookTimeout
+var webhookClient = &http.Client{Timeout: webhookTimeout}
+
 type CircuitBreaker struct {
     mutex            sync.Mutex
     failureCount     int
@@ -23,6 +34,21 @@ type CircuitBreaker struct {
     failureThreshold int
     serviceName      string
     state            string // "closed", "open", "half-open"
+
+    // Successful probes required in half-open state before closing
+    HalfOpenSuccessThreshold int
+    halfOpenSuccesses        int
+
+    // Notified with a JSON POST whenever the circuit opens, if set
+    WebhookURL string
+}
+
+// Body of the webhook notification sent when the circuit opens.
+type webhookPayload struct {
+    Service   string `json:"service"`
+    State     string `json:"state"`
+    Failures  int    `json:"failures"`
+    Timestamp string `json:"timestamp"`
 }
 
 func NewCircuitBreaker(serviceName string, failureThreshold int, resetTimeout time.Duration) *CircuitBreaker {
@@ -31,6 +57,7 @@ func NewCircuitBreaker(serviceName string, failureThreshold int, resetTimeout ti
         failureThreshold: failureThreshold,
         resetTimeout:     resetTimeout,
         state:            "closed",
+        HalfOpenSuccessThreshold: 1,
     }
     
     // Initialize metric with closed state (0)
@@ -46,6 +73,7 @@ func (cb *CircuitBreaker) Execute(fn func() error) error {
         // Check if we should retry (half-open)
         if time.Since(cb.lastFailure) > cb.resetTimeout {
             cb.state = "half-open"
+            cb.halfOpenSuccesses = 0
             metrics.CircuitBreakerState.WithLabelValues(cb.serviceName).Set(1)
             logger.Log.Info("Circuit half-open, allowing test request", 
                 zap.String("service", cb.serviceName))
@@ -74,14 +102,27 @@ func (cb *CircuitBreaker) Execute(fn func() error) error {
                 zap.String("service", cb.serviceName),
                 zap.Int("failures", cb.failureCount),
                 zap.Time("until", cb.lastFailure.Add(cb.resetTimeout)))
+            if cb.WebhookURL != "" {
+                go cb.notifyWebhook(cb.WebhookURL, webhookPayload{
+                    Service:   cb.serviceName,
+                    State:     cb.state,
+                    Failures:  cb.failureCount,
+                    Timestamp: cb.lastFailure.UTC().Format(time.RFC3339),
+                })
+            }
         }
         
         return err
     }
     
-    // Success - reset if we were in half-open state
+    // Success - close once enough probes have passed in half-open state
     if cb.state == "half-open" {
+        cb.halfOpenSuccesses++
+        if cb.halfOpenSuccesses < cb.HalfOpenSuccessThreshold {
+            return nil
+        }
         cb.state = "closed"
+        cb.halfOpenSuccesses = 0
         cb.failureCount = 0
         metrics.CircuitBreakerState.WithLabelValues(cb.serviceName).Set(0)
         logger.Log.Info("Circuit closed after successful test", 
@@ -95,4 +136,42 @@ func (cb *CircuitBreaker) State() string {
     cb.mutex.Lock()
     defer cb.mutex.Unlock()
     return cb.state
-}
\ No newline at end of file
+}
+
+// Posts a state change to the webhook. Runs in its own goroutine, so
+// failures are only logged.
+func (cb *CircuitBreaker) notifyWebhook(webhookURL string, payload webhookPayload) {
+    if err := postWebhook(webhookURL, payload); err != nil {
+        logger.Log.Warn("Circuit breaker webhook notification failed",
+            zap.String("service", cb.serviceName),
+            zap.Error(err))
+    }
+}
+
+// Sends the payload as JSON, giving up after webhookTimeout.
+func postWebhook(webhookURL string, payload webhookPayload) error {
+    body, err := json.Marshal(payload)
+    if err != nil {
+        return err
+    }
+
+    ctx, cancel := context.WithTimeout(context.Background(), webhookTimeout)
+    defer cancel()
+
+    request, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
+    if err != nil {
+        return err
+    }
+    request.Header.Set("Content-Type", "application/json")
+
+    response, err := webhookClient.Do(request)
+    if err != nil {
+        return err
+    }
+    defer response.Body.Close()
+
+    if response.StatusCode < 200 || response.StatusCode >= 300 {
+        return fmt.Errorf("unexpected webhook status: %d", response.StatusCode)
+    }
+    return nil
+}
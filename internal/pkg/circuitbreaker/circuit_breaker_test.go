@@ -0,0 +1,115 @@
+package circuitbreaker
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"go.uber.org/zap"
+	"indexer/internal/pkg/logger"
+)
+
+func init() {
+	// Ensure that the logger is not nil during tests.
+	logger.Log = zap.NewNop()
+}
+
+// Verifies that the circuit only closes after the configured number of
+// successful probes in half-open state.
+func TestCircuitBreakerHalfOpenSuccessThreshold(t *testing.T) {
+	cb := NewCircuitBreaker("test-half-open", 1, 10*time.Millisecond)
+	cb.HalfOpenSuccessThreshold = 3
+
+	failure := errors.New("service down")
+	success := func() error { return nil }
+
+	if err := cb.Execute(func() error { return failure }); !errors.Is(err, failure) {
+		t.Fatalf("Expected %v, got %v", failure, err)
+	}
+	if cb.State() != "open" {
+		t.Fatalf("Expected circuit to be open, got %s", cb.State())
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	for i := 1; i <= 2; i++ {
+		if err := cb.Execute(success); err != nil {
+			t.Fatalf("Expected probe %d to succeed, got %v", i, err)
+		}
+		if cb.State() != "half-open" {
+			t.Errorf("Expected circuit to stay half-open after %d successes, got %s", i, cb.State())
+		}
+	}
+
+	if err := cb.Execute(success); err != nil {
+		t.Fatalf("Expected third probe to succeed, got %v", err)
+	}
+	if cb.State() != "closed" {
+		t.Errorf("Expected circuit to close after 3 successes, got %s", cb.State())
+	}
+}
+
+// Verifies that a failed probe reopens the circuit and resets the success count.
+func TestCircuitBreakerHalfOpenFailureReopens(t *testing.T) {
+	cb := NewCircuitBreaker("test-half-open-failure", 1, 10*time.Millisecond)
+	cb.HalfOpenSuccessThreshold = 2
+
+	failure := errors.New("service down")
+	cb.Execute(func() error { return failure })
+	time.Sleep(20 * time.Millisecond)
+
+	cb.Execute(func() error { return nil })
+	cb.Execute(func() error { return failure })
+	if cb.State() != "open" {
+		t.Fatalf("Expected failed probe to reopen circuit, got %s", cb.State())
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	cb.Execute(func() error { return nil })
+	if cb.State() != "half-open" {
+		t.Errorf("Expected success count to reset after reopening, got %s", cb.State())
+	}
+}
+
+// Verifies that opening the circuit posts a JSON notification to the webhook.
+func TestCircuitBreakerWebhookOnOpen(t *testing.T) {
+	payloadCh := make(chan map[string]interface{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("Failed to decode webhook payload: %v", err)
+		}
+		payloadCh <- payload
+	}))
+	defer server.Close()
+
+	cb := NewCircuitBreaker("test-webhook", 2, time.Minute)
+	cb.WebhookURL = server.URL
+
+	failure := errors.New("service down")
+	cb.Execute(func() error { return failure })
+	select {
+	case <-payloadCh:
+		t.Fatal("Expected no notification before the circuit opens")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	cb.Execute(func() error { return failure })
+	select {
+	case payload := <-payloadCh:
+		if payload["service"] != "test-webhook" || payload["state"] != "open" {
+			t.Errorf("Unexpected webhook payload: %v", payload)
+		}
+		if payload["failures"] != float64(2) {
+			t.Errorf("Expected 2 failures in payload, got %v", payload["failures"])
+		}
+		timestamp, _ := payload["timestamp"].(string)
+		if _, err := time.Parse(time.RFC3339, timestamp); err != nil {
+			t.Errorf("Expected an RFC 3339 timestamp, got %v", payload["timestamp"])
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for webhook notification")
+	}
+}
@@ -8,7 +8,19 @@ import (
 type Config struct {
     ServerPort       string `mapstructure:"SERVER_PORT"`
     QueueCapacity    int    `mapstructure:"QUEUE_CAPACITY"`
+    QueueDropWhenFull bool  `mapstructure:"QUEUE_DROP_WHEN_FULL"` // accept and drop pages while full instead of answering 503
     NumWorkers       int    `mapstructure:"NUM_WORKERS"`
+    WorkerAutoRestart bool  `mapstructure:"WORKER_AUTO_RESTART"` // relaunch workers that panic
+    WorkerBatchSize  int    `mapstructure:"WORKER_BATCH_SIZE"`        // pages dequeued per worker iteration
+    ProcessBatchConcurrency int `mapstructure:"PROCESS_BATCH_CONCURRENCY"` // pages of a batch processed in parallel
+
+    // Ingestion config
+    MaxIngestBodyBytes int64  `mapstructure:"MAX_INGEST_BODY_BYTES"`
+    TLSCertFile        string `mapstructure:"TLS_CERT_FILE"` // TLS is enabled when both
+    TLSKeyFile         string `mapstructure:"TLS_KEY_FILE"`  // cert and key are set
+    IngestReadTimeoutSeconds  int `mapstructure:"INGEST_READ_TIMEOUT_SECONDS"`
+    IngestWriteTimeoutSeconds int `mapstructure:"INGEST_WRITE_TIMEOUT_SECONDS"`
+    IngestIdleTimeoutSeconds  int `mapstructure:"INGEST_IDLE_TIMEOUT_SECONDS"`
 
     // Existing fields remain unchanged
     ElasticsearchURL string `mapstructure:"ELASTICSEARCH_URL"`
@@ -16,49 +28,164 @@ type Config struct {
     BulkThreshold    int    `mapstructure:"BULK_THRESHOLD"`
     FlushInterval    int    `mapstructure:"FLUSH_INTERVAL"`
     MaxRetries       int    `mapstructure:"MAX_RETRIES"`
+
+    // Bulk requests in flight at once, further flushes wait for one to finish
+    BulkMaxConcurrentFlushes int `mapstructure:"BULK_MAX_CONCURRENT_FLUSHES"`
+
+    // Comma-separated domain=index pairs sending pages of a domain, including
+    // its subdomains, to their own index instead of INDEX_NAME
+    IndexRoutes string `mapstructure:"INDEX_ROUTES"`
+
+    // Comma-separated index=threshold pairs overriding BULK_THRESHOLD per index
+    IndexBulkThresholds string `mapstructure:"INDEX_BULK_THRESHOLDS"`
+
+    // Count inbound internal links on documents. Documents are then written as
+    // scripted upserts, which is slower and needs inline Painless scripts
+    InboundLinkCountsEnabled bool `mapstructure:"INBOUND_LINK_COUNTS_ENABLED"`
+
+    // Comma-separated endpoints tried in order when ELASTICSEARCH_URL fails
+    ElasticsearchFallbackURLs string `mapstructure:"ELASTICSEARCH_FALLBACK_URLS"`
+
+    // Basic Authentication for Elasticsearch, used when both are set
+    ESUsername string `mapstructure:"ES_USERNAME"`
+    ESPassword string `mapstructure:"ES_PASSWORD"`
+
+    // Process documents without writing them to Elasticsearch
+    DryRun bool `mapstructure:"DRY_RUN"`
     
     // Redis config
     RedisHost     string `mapstructure:"REDIS_HOST"`
     RedisPort     string `mapstructure:"REDIS_PORT"`
     RedisPassword string `mapstructure:"REDIS_PASSWORD"`
     RedisDB       int    `mapstructure:"REDIS_DB"`
+    RedisMaxRetries int  `mapstructure:"REDIS_MAX_RETRIES"` // retries of a failed dedup operation
+
+    // Redis TLS config, the CA and client certificate files are optional
+    RedisTLSEnabled  bool   `mapstructure:"REDIS_TLS_ENABLED"`
+    RedisTLSCertFile string `mapstructure:"REDIS_TLS_CERT_FILE"`
+    RedisTLSKeyFile  string `mapstructure:"REDIS_TLS_KEY_FILE"`
+    RedisTLSCAFile   string `mapstructure:"REDIS_TLS_CA_FILE"`
+
+    // Redis Cluster config
+    RedisClusterEnabled bool   `mapstructure:"REDIS_CLUSTER_ENABLED"`
+    RedisClusterAddrs   string `mapstructure:"REDIS_CLUSTER_ADDRS"` // comma-separated host:port list
+
+    // Redis key of the dedup signature set
+    DedupKeyPrefix string `mapstructure:"DEDUP_KEY_PREFIX"`
 
     // Processor config
-    SpamBlockThreshold int `mapstructure:"SPAM_BLOCK_THRESHOLD"`
+    SpamBlockThreshold int    `mapstructure:"SPAM_BLOCK_THRESHOLD"`
+    SpamEventsIndex    string `mapstructure:"SPAM_EVENTS_INDEX"` // index recording rejected spam pages
+
+    // Keyword to categories mapping, from a YAML file or inline YAML/JSON
+    CategoryMapFile string `mapstructure:"CATEGORY_MAP_FILE"`
+    CategoryMap     string `mapstructure:"CATEGORY_MAP"`
+
+    // Comma-separated domains that are never indexed, including their subdomains.
+    // Checked by the processor on the normalized URL and counted per domain.
+    SkipDomains string `mapstructure:"SKIP_DOMAINS"`
+
+    // URL patterns dropped by the workers before processing, from a file with
+    // one per line and comma-separated, both applied. Hosts match their
+    // subdomains, "/"-prefixed paths match on segment boundaries. Cheaper than
+    // SKIP_DOMAINS as the raw URL is checked before any processing.
+    URLFilterFile     string `mapstructure:"URL_FILTER_FILE"`
+    URLFilterPatterns string `mapstructure:"URL_FILTER_PATTERNS"`
+
+    // How long each host's robots.txt is cached. Checks fetch robots.txt from
+    // every crawled host, so they are opt-in: 0, the default, disables them.
+    RobotsCacheTTLMinutes int `mapstructure:"ROBOTS_CACHE_TTL_MINUTES"`
 
     // NLP service config
     NlpServiceURL     string `mapstructure:"NLP_SERVICE_URL"`
     NlpBatchSize      int    `mapstructure:"NLP_BATCH_SIZE"`
     NlpBatchTimeoutMs int   `mapstructure:"NLP_BATCH_TIMEOUT_MS"`
+    NlpMaxBatchWaitMs int    `mapstructure:"NLP_MAX_BATCH_WAIT_MS"` // longest a page waits in a batch, 0 for no bound
+    NumNLPWorkers     int    `mapstructure:"NLP_NUM_WORKERS"` // NLP batches sent concurrently
+    NlpMaxTextBytes   int    `mapstructure:"NLP_MAX_TEXT_BYTES"` // longer texts are truncated before NLP, 0 for no limit
+    NlpHealthCheckTimeoutSeconds int `mapstructure:"NLP_HEALTH_CHECK_TIMEOUT_SECONDS"`
+    NlpLocalFallbackEnabled bool `mapstructure:"NLP_LOCAL_FALLBACK_ENABLED"` // extract keywords locally while the NLP circuit is open
+    SummarizeMinWordCount int `mapstructure:"SUMMARIZE_MIN_WORD_COUNT"` // shorter pages are never summarized
+    CircuitBreakerWebhookURL string `mapstructure:"CIRCUIT_BREAKER_WEBHOOK_URL"` // notified when the NLP circuit opens
     
     LogLevel string `mapstructure:"LOG_LEVEL"`
+
+    // Optional log file, written alongside stdout and rotated by size
+    LogFilePath   string `mapstructure:"LOG_FILE_PATH"`
+    LogMaxSizeMB  int    `mapstructure:"LOG_MAX_SIZE_MB"`
+    LogMaxBackups int    `mapstructure:"LOG_MAX_BACKUPS"`
+    LogMaxAgeDays int    `mapstructure:"LOG_MAX_AGE_DAYS"`
 }
 
 func LoadConfig() (*Config, error) {
     // Set defaults for configuration values
     viper.SetDefault("SERVER_PORT", "8080")
     viper.SetDefault("QUEUE_CAPACITY", 1000)
+    viper.SetDefault("QUEUE_DROP_WHEN_FULL", false)
     viper.SetDefault("NUM_WORKERS", 4) // Default to 4 workers
+    viper.SetDefault("WORKER_AUTO_RESTART", false)
+    viper.SetDefault("WORKER_BATCH_SIZE", 1)
+    viper.SetDefault("PROCESS_BATCH_CONCURRENCY", 4)
+    viper.SetDefault("MAX_INGEST_BODY_BYTES", 1 << 20) // 1MB
+    viper.SetDefault("TLS_CERT_FILE", "")
+    viper.SetDefault("TLS_KEY_FILE", "")
+    viper.SetDefault("INGEST_READ_TIMEOUT_SECONDS", 10)
+    viper.SetDefault("INGEST_WRITE_TIMEOUT_SECONDS", 30)
+    viper.SetDefault("INGEST_IDLE_TIMEOUT_SECONDS", 60)
     viper.SetDefault("ELASTICSEARCH_URL", "http://localhost:9200/_bulk")
+    viper.SetDefault("ELASTICSEARCH_FALLBACK_URLS", "")
     viper.SetDefault("INDEX_NAME", "search_engine_index")
     viper.SetDefault("BULK_THRESHOLD", 3)
     viper.SetDefault("FLUSH_INTERVAL", 30)
     viper.SetDefault("MAX_RETRIES", 3)
+    viper.SetDefault("BULK_MAX_CONCURRENT_FLUSHES", 4)
+    viper.SetDefault("INDEX_ROUTES", "")
+    viper.SetDefault("INDEX_BULK_THRESHOLDS", "")
+    viper.SetDefault("INBOUND_LINK_COUNTS_ENABLED", false)
+    viper.SetDefault("ES_USERNAME", "")
+    viper.SetDefault("ES_PASSWORD", "")
+    viper.SetDefault("DRY_RUN", false)
 
     // Redis defaults
     viper.SetDefault("REDIS_HOST", "localhost")
     viper.SetDefault("REDIS_PORT", "6379")
     viper.SetDefault("REDIS_PASSWORD", "")
     viper.SetDefault("REDIS_DB", 0)
+    viper.SetDefault("REDIS_MAX_RETRIES", 3)
+    viper.SetDefault("REDIS_TLS_ENABLED", false)
+    viper.SetDefault("REDIS_TLS_CERT_FILE", "")
+    viper.SetDefault("REDIS_TLS_KEY_FILE", "")
+    viper.SetDefault("REDIS_TLS_CA_FILE", "")
+    viper.SetDefault("REDIS_CLUSTER_ENABLED", false)
+    viper.SetDefault("REDIS_CLUSTER_ADDRS", "")
+    viper.SetDefault("DEDUP_KEY_PREFIX", "deduper_signatures")
     viper.SetDefault("LOG_LEVEL", "info")
+    viper.SetDefault("LOG_FILE_PATH", "")
+    viper.SetDefault("LOG_MAX_SIZE_MB", 100)
+    viper.SetDefault("LOG_MAX_BACKUPS", 5)
+    viper.SetDefault("LOG_MAX_AGE_DAYS", 30)
 
     // Processor defaults
-    viper.SetDefault("SPAM_BLOCK_THRESHOLD", 15)
+    viper.SetDefault("SPAM_BLOCK_THRESHOLD", 10)
+    viper.SetDefault("SPAM_EVENTS_INDEX", "spam_events")
+    viper.SetDefault("CATEGORY_MAP_FILE", "")
+    viper.SetDefault("CATEGORY_MAP", "")
+    viper.SetDefault("SKIP_DOMAINS", "")
+    viper.SetDefault("URL_FILTER_FILE", "")
+    viper.SetDefault("URL_FILTER_PATTERNS", "")
+    viper.SetDefault("ROBOTS_CACHE_TTL_MINUTES", 0)
 
     // NLP service defaults
     viper.SetDefault("NLP_SERVICE_URL", "http://localhost:5000/nlp")
     viper.SetDefault("NLP_BATCH_SIZE", 10)
     viper.SetDefault("NLP_BATCH_TIMEOUT_MS", 200)
+    viper.SetDefault("NLP_MAX_BATCH_WAIT_MS", 0)
+    viper.SetDefault("NLP_NUM_WORKERS", 1)
+    viper.SetDefault("NLP_MAX_TEXT_BYTES", 0)
+    viper.SetDefault("NLP_HEALTH_CHECK_TIMEOUT_SECONDS", 5)
+    viper.SetDefault("NLP_LOCAL_FALLBACK_ENABLED", false)
+    viper.SetDefault("SUMMARIZE_MIN_WORD_COUNT", 200)
+    viper.SetDefault("CIRCUIT_BREAKER_WEBHOOK_URL", "")
 
     viper.AutomaticEnv()
 
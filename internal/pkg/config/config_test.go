@@ -3,8 +3,16 @@ package config
 import (
 	"os"
 	"testing"
+	"go.uber.org/zap"
+	"indexer/internal/pkg/logger"
+	"indexer/internal/pkg/processor/spamdetector"
 )
 
+func init() {
+	// Ensure that the logger is not nil during tests.
+	logger.Log = zap.NewNop()
+}
+
 func TestLoadConfigDefaults(t *testing.T) {
 	// Clear environment variables that might interfere.
 	os.Clearenv()
@@ -27,6 +35,9 @@ func TestLoadConfigDefaults(t *testing.T) {
 	if config.LogLevel != "info" {
 		t.Errorf("expected LogLevel to be 'info', got %s", config.LogLevel)
 	}
+	if config.SpamBlockThreshold != 10 {
+		t.Errorf("expected SpamBlockThreshold to be 10, got %d", config.SpamBlockThreshold)
+	}
 }
 
 func TestLoadConfigFromEnv(t *testing.T) {
@@ -56,3 +67,25 @@ func TestLoadConfigFromEnv(t *testing.T) {
 	os.Unsetenv("QUEUE_CAPACITY")
 	os.Unsetenv("LOG_LEVEL")
 }
+
+func TestSpamBlockThresholdFromEnv(t *testing.T) {
+	os.Setenv("SPAM_BLOCK_THRESHOLD", "1")
+	defer os.Unsetenv("SPAM_BLOCK_THRESHOLD")
+
+	config, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if config.SpamBlockThreshold != 1 {
+		t.Fatalf("expected SpamBlockThreshold to be 1, got %d", config.SpamBlockThreshold)
+	}
+
+	// A single low-weight phrase is only blocked under the configured threshold
+	text := "Visit our casino tonight"
+	if result := spamdetector.NewSpamDetector(spamdetector.DefaultSpamPhrases(), config.SpamBlockThreshold).DetectSpam(text); !result.IsHighSpam {
+		t.Errorf("expected configured threshold to block score %d", result.Score)
+	}
+	if result := spamdetector.NewSpamDetector(spamdetector.DefaultSpamPhrases(), 10).DetectSpam(text); result.IsHighSpam {
+		t.Errorf("expected default threshold not to block score %d", result.Score)
+	}
+}
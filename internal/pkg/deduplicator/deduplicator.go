@@ -3,10 +3,17 @@ package deduper
 import (
     "context"
     "crypto/sha256"
+    "crypto/tls"
+    "crypto/x509"
     "encoding/hex"
+    "errors"
     "fmt"
+    "os"
     "strings"
+    "sync/atomic"
     "time"
+    "unicode/utf8"
+    "indexer/internal/pkg/backoff"
     "indexer/internal/pkg/config"
     "indexer/internal/pkg/logger"
     "github.com/redis/go-redis/v9"
@@ -16,22 +23,58 @@ import (
 // Defines the interface for duplicate checking.
 type Deduper interface {
 	IsDuplicate(signature string) bool
-	StoreSignature(signature string)
+	StoreSignature(signature string) error
+	Clear() error // forgets every stored signature
+	Close() error // releases the connection to the backing store
 }
 
 // Implements the Deduper interface with Redis as the backing store.
+// The client may be a standalone or a cluster client.
 type redisDeduper struct {
-    client       *redis.Client
+    client       redis.UniversalClient
     redisKeyPrefix string
+    maxRetries   int // extra attempts after a failed Redis operation
+
+    // Unix nanoseconds until which Redis is treated as down and operations
+    // fail without being attempted
+    unavailableUntil atomic.Int64
+}
+
+// Backoff between Redis retries, doubled on every attempt up to maxRedisBackoff
+var redisRetryBaseBackoff = 100 * time.Millisecond
+
+const maxRedisBackoff = 2 * time.Second
+
+// How long operations fail fast after one has exhausted its retries
+var redisCooldown = 10 * time.Second
+
+// Returned instead of contacting Redis while it is known to be down
+var errRedisUnavailable = errors.New("redis unavailable, skipping until cooldown ends")
+
+// Redis key of the signature set when DEDUP_KEY_PREFIX is empty
+const defaultKeyPrefix = "deduper_signatures"
+
+// Returns the configured signature set key, or defaultKeyPrefix if unset.
+func keyPrefix(config *config.Config) string {
+    if config.DedupKeyPrefix != "" {
+        return config.DedupKeyPrefix
+    }
+    return defaultKeyPrefix
 }
 
 // Creates a new instance of redisDeduper.
-// We store dedup signatures in a Redis SET, e.g. "deduper_signatures".
+// We store dedup signatures in a Redis SET named by DEDUP_KEY_PREFIX.
 func NewRedisDeduper(config *config.Config) (Deduper, error) {
+    tlsConfig, err := newRedisTLSConfig(config)
+    if err != nil {
+        return nil, err
+    }
+
     rdb := redis.NewClient(&redis.Options{
-        Addr:     fmt.Sprintf("%s:%s", config.RedisHost, config.RedisPort),
-        Password: config.RedisPassword, // "" if no auth
-        DB:       config.RedisDB,
+        Addr:      fmt.Sprintf("%s:%s", config.RedisHost, config.RedisPort),
+        Password:  config.RedisPassword, // "" if no auth
+        DB:        config.RedisDB,
+        TLSConfig: tlsConfig, // nil for plain-text connections
     })
 
     // Test connection
@@ -49,16 +92,97 @@ func NewRedisDeduper(config *config.Config) (Deduper, error) {
 
     return &redisDeduper{
         client:         rdb,
-        redisKeyPrefix: "deduper_signatures", // could be configurable
+        redisKeyPrefix: keyPrefix(config),
+        maxRetries:     config.RedisMaxRetries,
     }, nil
 }
 
-// IsDuplicate checks if signature is in Redis.
-func (redisDeduper *redisDeduper) IsDuplicate(signature string) bool {
-    ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+// Builds the TLS settings for the Redis connection, or returns nil when TLS
+// is disabled. The CA file and client certificate are both optional.
+func newRedisTLSConfig(config *config.Config) (*tls.Config, error) {
+    if !config.RedisTLSEnabled {
+        return nil, nil
+    }
+
+    tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+    if config.RedisTLSCAFile != "" {
+        caPEM, err := os.ReadFile(config.RedisTLSCAFile)
+        if err != nil {
+            return nil, fmt.Errorf("failed to read Redis CA file: %w", err)
+        }
+        pool := x509.NewCertPool()
+        if !pool.AppendCertsFromPEM(caPEM) {
+            return nil, errors.New("no certificates found in Redis CA file")
+        }
+        tlsConfig.RootCAs = pool
+    }
+
+    if config.RedisTLSCertFile != "" || config.RedisTLSKeyFile != "" {
+        cert, err := tls.LoadX509KeyPair(config.RedisTLSCertFile, config.RedisTLSKeyFile)
+        if err != nil {
+            return nil, fmt.Errorf("failed to load Redis client certificate: %w", err)
+        }
+        tlsConfig.Certificates = []tls.Certificate{cert}
+    }
+
+    return tlsConfig, nil
+}
+
+// Creates a new instance of redisDeduper backed by a Redis Cluster.
+// The cluster nodes are read from the comma-separated REDIS_CLUSTER_ADDRS.
+func NewRedisClusterDeduper(config *config.Config) (Deduper, error) {
+    var addrs []string
+    for _, addr := range strings.Split(config.RedisClusterAddrs, ",") {
+        if addr = strings.TrimSpace(addr); addr != "" {
+            addrs = append(addrs, addr)
+        }
+    }
+    if len(addrs) == 0 {
+        return nil, errors.New("no Redis cluster addresses configured")
+    }
+
+    tlsConfig, err := newRedisTLSConfig(config)
+    if err != nil {
+        return nil, err
+    }
+
+    rdb := redis.NewClusterClient(&redis.ClusterOptions{
+        Addrs:     addrs,
+        Password:  config.RedisPassword, // "" if no auth
+        TLSConfig: tlsConfig, // nil for plain-text connections
+    })
+
+    // Test connection
+    context, cancel := context.WithTimeout(context.Background(), 2 * time.Second)
     defer cancel()
+    if err := rdb.Ping(context).Err(); err != nil {
+        logger.Log.Error("Failed to connect to Redis cluster", zap.Error(err))
+        return nil, err
+    }
+
+    logger.Log.Info("Connected to Redis cluster successfully", zap.Strings("addrs", addrs))
 
-    exists, err := redisDeduper.client.SIsMember(ctx, redisDeduper.redisKeyPrefix, signature).Result()
+    return &redisDeduper{
+        client:         rdb,
+        redisKeyPrefix: keyPrefix(config),
+        maxRetries:     config.RedisMaxRetries,
+    }, nil
+}
+
+// IsDuplicate checks if signature is in Redis.
+func (redisDeduper *redisDeduper) IsDuplicate(signature string) bool {
+    var exists bool
+    err := redisDeduper.retryWithBackoff(func(ctx context.Context) error {
+        var err error
+        exists, err = redisDeduper.client.SIsMember(ctx, redisDeduper.redisKeyPrefix, signature).Result()
+        return err
+    })
+    if errors.Is(err, errRedisUnavailable) {
+        // Already logged when the outage started
+        logger.Log.Debug("Redis IsDuplicate check skipped", zap.Error(err))
+        return false
+    }
     if err != nil {
         // If there's an error, assume not duplicate so we don't block indexing. 
         logger.Log.Error("Redis IsDuplicate check failed", zap.Error(err))
@@ -68,17 +192,73 @@ func (redisDeduper *redisDeduper) IsDuplicate(signature string) bool {
 }
 
 // Adds the signature to the Redis SET.
-func (redisDeduper *redisDeduper) StoreSignature(signature string) {
+func (redisDeduper *redisDeduper) StoreSignature(signature string) error {
+    err := redisDeduper.retryWithBackoff(func(ctx context.Context) error {
+        return redisDeduper.client.SAdd(ctx, redisDeduper.redisKeyPrefix, signature).Err()
+    })
+    if err != nil {
+        return fmt.Errorf("failed to store signature in Redis: %w", err)
+    }
+    return nil
+}
+
+// Removes every signature from the Redis SET.
+func (redisDeduper *redisDeduper) Clear() error {
     ctx, cancel := context.WithTimeout(context.Background(), time.Second)
     defer cancel()
-    if err := redisDeduper.client.SAdd(ctx, redisDeduper.redisKeyPrefix, signature).Err(); err != nil {
-        logger.Log.Error("Failed to store signature in Redis", zap.Error(err))
+    if err := redisDeduper.client.Del(ctx, redisDeduper.redisKeyPrefix).Err(); err != nil {
+        return fmt.Errorf("failed to clear signatures in Redis: %w", err)
+    }
+    return nil
+}
+
+// Closes the Redis client.
+func (redisDeduper *redisDeduper) Close() error {
+    return redisDeduper.client.Close()
+}
+
+// Runs op with a 1s timeout per attempt, retrying up to maxRetries times
+// with jittered exponential backoff so brief Redis hiccups don't fail it.
+// Returns the last error once every attempt has failed, after which every
+// operation fails with errRedisUnavailable for redisCooldown, so pages
+// aren't held up by timeouts during an outage.
+func (redisDeduper *redisDeduper) retryWithBackoff(op func(ctx context.Context) error) error {
+    if time.Now().UnixNano() < redisDeduper.unavailableUntil.Load() {
+        return errRedisUnavailable
+    }
+
+    var err error
+    for attempt := 0; ; attempt++ {
+        ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+        err = op(ctx)
+        cancel()
+        if err == nil {
+            return nil
+        }
+        if attempt >= redisDeduper.maxRetries {
+            logger.Log.Warn("Redis unavailable, skipping Redis operations",
+                zap.Error(err),
+                zap.Duration("cooldown", redisCooldown),
+            )
+            redisDeduper.unavailableUntil.Store(time.Now().Add(redisCooldown).UnixNano())
+            return err
+        }
+        logger.Log.Debug("Redis operation failed, retrying", zap.Error(err), zap.Int("attempt", attempt))
+        time.Sleep(backoff.Exponential(attempt, redisRetryBaseBackoff, maxRedisBackoff))
     }
 }
 
-// Creates a SHA-256 hash of the text.
+// Texts shorter than this, in characters, are too short to deduplicate reliably
+var MinSignatureLength = 20
+
+// Creates a SHA-256 hash of the text. Returns an empty signature for texts
+// shorter than MinSignatureLength, meaning they should not be deduplicated.
 func GenerateSignature(text string) string {
+    text = strings.TrimSpace(text)
+    if utf8.RuneCountInString(text) < MinSignatureLength {
+        return ""
+    }
     // A simple SHA-256 hash of the text
-    sum := sha256.Sum256([]byte(strings.TrimSpace(text)))
+    sum := sha256.Sum256([]byte(text))
     return hex.EncodeToString(sum[:])
 }
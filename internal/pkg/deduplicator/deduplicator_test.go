@@ -2,8 +2,13 @@ package deduper
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
+	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 	"indexer/internal/pkg/config"
 	"indexer/internal/pkg/logger"
@@ -22,6 +27,8 @@ func TestRedisDeduper(t *testing.T) {
 		RedisPort:     "6379",
 		RedisPassword: "",
 		RedisDB:       0,
+		// A unique key per run keeps tests sharing a Redis instance apart
+		DedupKeyPrefix: fmt.Sprintf("deduper_test_%d", time.Now().UnixNano()),
 	}
 
 	// Create a new redisDeduper.
@@ -31,15 +38,10 @@ func TestRedisDeduper(t *testing.T) {
 	}
 
 	// Clear the Redis set used for deduplication before testing.
-	context, cancel := context.WithTimeout(context.Background(), 2 * time.Second)
-	defer cancel()
-	redisDeduper, ok := deduper.(*redisDeduper)
-	if !ok {
-		t.Fatal("Type assertion to *redisDeduper failed")
-	}
-	if err := redisDeduper.client.Del(context, redisDeduper.redisKeyPrefix).Err(); err != nil {
+	if err := deduper.Clear(); err != nil {
 		t.Fatalf("Failed to clear Redis set: %v", err)
 	}
+	defer deduper.Clear()
 
 	signature := "testsignature"
 
@@ -49,7 +51,9 @@ func TestRedisDeduper(t *testing.T) {
 	}
 
 	// Store the signature.
-	deduper.StoreSignature(signature)
+	if err := deduper.StoreSignature(signature); err != nil {
+		t.Fatalf("Failed to store signature: %v", err)
+	}
 
 	// Give Redis a moment to persist the signature.
 	time.Sleep(100 * time.Millisecond)
@@ -59,3 +63,183 @@ func TestRedisDeduper(t *testing.T) {
 		t.Error("Expected signature to be detected as duplicate after storing")
 	}
 }
+
+// Verifies that the signature set key falls back to the default prefix.
+func TestKeyPrefix(t *testing.T) {
+	if got := keyPrefix(&config.Config{}); got != defaultKeyPrefix {
+		t.Errorf("Expected default prefix %q, got %q", defaultKeyPrefix, got)
+	}
+	if got := keyPrefix(&config.Config{DedupKeyPrefix: "custom"}); got != "custom" {
+		t.Errorf("Expected configured prefix, got %q", got)
+	}
+}
+
+// Verifies that texts below MinSignatureLength get an empty signature.
+func TestGenerateSignatureMinLength(t *testing.T) {
+	if signature := GenerateSignature("  Short page  "); signature != "" {
+		t.Errorf("Expected no signature for short text, got %q", signature)
+	}
+
+	long := "A page with enough text to hash"
+	signature := GenerateSignature(long)
+	if len(signature) != 64 {
+		t.Errorf("Expected a SHA-256 signature, got %q", signature)
+	}
+	if GenerateSignature("  "+long+"\n") != signature {
+		t.Error("Expected surrounding whitespace to be ignored")
+	}
+
+	defer func(length int) { MinSignatureLength = length }(MinSignatureLength)
+	MinSignatureLength = 0
+	if GenerateSignature("Short page") == "" {
+		t.Error("Expected short text to be hashed when the minimum is disabled")
+	}
+}
+
+// Validates that a cluster deduper cannot be created without any node addresses.
+func TestRedisClusterDeduperRequiresAddrs(t *testing.T) {
+	config := &config.Config{
+		RedisClusterEnabled: true,
+		RedisClusterAddrs:   " , ",
+	}
+
+	if _, err := NewRedisClusterDeduper(config); err == nil {
+		t.Error("Expected error when no cluster addresses are configured")
+	}
+}
+
+// Verifies how the Redis TLS settings are built from config.
+func TestNewRedisTLSConfig(t *testing.T) {
+	tlsConfig, err := newRedisTLSConfig(&config.Config{})
+	if err != nil || tlsConfig != nil {
+		t.Errorf("Expected no TLS config when disabled, got %v, %v", tlsConfig, err)
+	}
+
+	tlsConfig, err = newRedisTLSConfig(&config.Config{RedisTLSEnabled: true})
+	if err != nil || tlsConfig == nil {
+		t.Fatalf("Expected TLS config when enabled, got %v, %v", tlsConfig, err)
+	}
+	if tlsConfig.RootCAs != nil || len(tlsConfig.Certificates) != 0 {
+		t.Errorf("Expected system roots and no client certificate by default")
+	}
+
+	dir := t.TempDir()
+	badCA := filepath.Join(dir, "ca.pem")
+	if err := os.WriteFile(badCA, []byte("not a certificate"), 0600); err != nil {
+		t.Fatalf("Failed to write CA file: %v", err)
+	}
+	if _, err := newRedisTLSConfig(&config.Config{RedisTLSEnabled: true, RedisTLSCAFile: badCA}); err == nil {
+		t.Error("Expected error for CA file without certificates, got nil")
+	}
+	if _, err := newRedisTLSConfig(&config.Config{RedisTLSEnabled: true, RedisTLSCAFile: filepath.Join(dir, "missing.pem")}); err == nil {
+		t.Error("Expected error for missing CA file, got nil")
+	}
+	if _, err := newRedisTLSConfig(&config.Config{RedisTLSEnabled: true, RedisTLSCertFile: badCA, RedisTLSKeyFile: badCA}); err == nil {
+		t.Error("Expected error for invalid client certificate, got nil")
+	}
+}
+
+// Redis client whose set commands fail for the first failures calls.
+type flakyRedisClient struct {
+	redis.UniversalClient
+	failures int
+	calls    int
+}
+
+func (client *flakyRedisClient) fail() error {
+	client.calls++
+	if client.calls <= client.failures {
+		return errors.New("connection reset")
+	}
+	return nil
+}
+
+func (client *flakyRedisClient) SIsMember(ctx context.Context, key string, member interface{}) *redis.BoolCmd {
+	cmd := redis.NewBoolCmd(ctx)
+	if err := client.fail(); err != nil {
+		cmd.SetErr(err)
+	} else {
+		cmd.SetVal(true)
+	}
+	return cmd
+}
+
+func (client *flakyRedisClient) SAdd(ctx context.Context, key string, members ...interface{}) *redis.IntCmd {
+	cmd := redis.NewIntCmd(ctx)
+	if err := client.fail(); err != nil {
+		cmd.SetErr(err)
+	} else {
+		cmd.SetVal(1)
+	}
+	return cmd
+}
+
+// Verifies that Redis operations are retried until they succeed or the
+// retries run out.
+func TestRedisDeduperRetries(t *testing.T) {
+	defer func(backoff time.Duration) { redisRetryBaseBackoff = backoff }(redisRetryBaseBackoff)
+	redisRetryBaseBackoff = time.Millisecond
+
+	tests := []struct {
+		failures  int
+		succeeded bool
+	}{
+		{0, true},
+		{3, true},
+		{4, false},
+	}
+
+	for _, tc := range tests {
+		client := &flakyRedisClient{failures: tc.failures}
+		deduper := &redisDeduper{client: client, redisKeyPrefix: "test", maxRetries: 3}
+		if got := deduper.IsDuplicate("signature"); got != tc.succeeded {
+			t.Errorf("IsDuplicate after %d failures = %v, expected %v", tc.failures, got, tc.succeeded)
+		}
+		if expected := min(tc.failures+1, 4); client.calls != expected {
+			t.Errorf("Expected %d IsDuplicate attempts after %d failures, got %d", expected, tc.failures, client.calls)
+		}
+
+		client = &flakyRedisClient{failures: tc.failures}
+		deduper = &redisDeduper{client: client, redisKeyPrefix: "test", maxRetries: 3}
+		if err := deduper.StoreSignature("signature"); (err == nil) != tc.succeeded {
+			t.Errorf("StoreSignature after %d failures returned %v", tc.failures, err)
+		}
+	}
+}
+
+// Verifies that once an operation exhausts its retries, operations fail
+// without contacting Redis until the cooldown ends.
+func TestRedisDeduperFailsFastDuringOutage(t *testing.T) {
+	defer func(backoff, cooldown time.Duration) {
+		redisRetryBaseBackoff, redisCooldown = backoff, cooldown
+	}(redisRetryBaseBackoff, redisCooldown)
+	redisRetryBaseBackoff = time.Millisecond
+	redisCooldown = 50 * time.Millisecond
+
+	client := &flakyRedisClient{failures: 4}
+	deduper := &redisDeduper{client: client, redisKeyPrefix: "test", maxRetries: 3}
+	if deduper.IsDuplicate("signature") {
+		t.Fatal("Expected IsDuplicate to report false when Redis is down")
+	}
+	if client.calls != 4 {
+		t.Fatalf("Expected 4 attempts before Redis is marked unavailable, got %d", client.calls)
+	}
+
+	if deduper.IsDuplicate("signature") {
+		t.Error("Expected IsDuplicate to report false during the cooldown")
+	}
+	if err := deduper.StoreSignature("signature"); !errors.Is(err, errRedisUnavailable) {
+		t.Errorf("Expected StoreSignature to fail with errRedisUnavailable during the cooldown, got %v", err)
+	}
+	if client.calls != 4 {
+		t.Errorf("Expected no Redis calls during the cooldown, got %d", client.calls-4)
+	}
+
+	time.Sleep(redisCooldown)
+	if !deduper.IsDuplicate("signature") {
+		t.Error("Expected IsDuplicate to contact Redis again after the cooldown")
+	}
+	if client.calls != 5 {
+		t.Errorf("Expected 1 attempt after the cooldown, got %d", client.calls-4)
+	}
+}
@@ -0,0 +1,105 @@
+package docid
+
+import (
+    "crypto/sha256"
+    "encoding/hex"
+    "fmt"
+    "net/url"
+    "strings"
+    "unicode"
+    "golang.org/x/text/runes"
+    "golang.org/x/text/transform"
+    "golang.org/x/text/unicode/norm"
+)
+
+// Returns a stable document ID based on canonicalStr if available, else urlStr.
+func Generate(urlStr, canonicalStr string) string {
+    if strings.TrimSpace(canonicalStr) != "" {
+        return Sanitize(trimTrailingSlash(canonicalStr))
+    }
+    return Sanitize(trimTrailingSlash(urlStr))
+}
+
+// Strips trailing slashes from the URL path so "/page" and "/page/" map to
+// the same document. The root path is always represented as "/".
+func trimTrailingSlash(raw string) string {
+    raw = strings.TrimSpace(raw)
+    parsed, err := url.Parse(raw)
+    if err != nil {
+        return strings.TrimRight(raw, "/")
+    }
+
+    parsed.Path = strings.TrimRight(parsed.Path, "/")
+    parsed.RawPath = strings.TrimRight(parsed.RawPath, "/")
+    if parsed.Path == "" && parsed.Host != "" {
+        parsed.Path = "/"
+        parsed.RawPath = ""
+    }
+    return parsed.String()
+}
+
+// Longest document ID Sanitize returns
+const maxIDLength = 100
+
+// Hex digits of the URL hash appended to escaped or over-long IDs
+const hashLength = 32
+
+// Sanitizes an ID to remove problematic characters and ensure it's URL-safe.
+// Percent-encoded characters are decoded and accents are stripped first, so
+// internationalized URLs keep their characters. Non-ASCII letters and digits
+// with no ASCII equivalent are written as "_u" and six hex digits of their
+// code point followed by "_". IDs with such escapes, or longer than
+// maxIDLength, are cut short and end in a hash of the whole URL instead, so
+// distinct URLs never share an ID.
+func Sanitize(raw string) string {
+    if decoded, err := url.PathUnescape(raw); err == nil {
+        raw = decoded
+    }
+    raw = transliterate(raw)
+
+    // Remove protocols
+    clean := strings.ReplaceAll(raw, "http://", "")
+    clean = strings.ReplaceAll(clean, "https://", "")
+    normalized := clean
+    
+    // Replace problematic characters
+    clean = strings.ReplaceAll(clean, "/", "_")
+    clean = strings.ReplaceAll(clean, "?", "_")
+    clean = strings.ReplaceAll(clean, "&", "_")
+    clean = strings.ReplaceAll(clean, "=", "_")
+    clean = strings.ReplaceAll(clean, "#", "_")
+    clean = strings.ReplaceAll(clean, " ", "_")
+    clean = strings.ReplaceAll(clean, ":", "_")
+    
+    // Remove any remaining invalid characters
+    var result strings.Builder
+    escaped := false
+    for _, r := range clean {
+        if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' || r == '.' || r == '-' {
+            result.WriteRune(r)
+        } else if r > unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+            fmt.Fprintf(&result, "_u%06x_", r)
+            escaped = true
+        }
+    }
+    
+    // Keep it short, without letting a cut or an escape merge two URLs
+    resultStr := result.String()
+    if escaped || len(resultStr) > maxIDLength {
+        sum := sha256.Sum256([]byte(normalized))
+        prefix := resultStr[:min(len(resultStr), maxIDLength-hashLength-1)]
+        resultStr = prefix + "_" + hex.EncodeToString(sum[:])[:hashLength]
+    }
+    
+    return resultStr
+}
+
+// Strips diacritics so accented Latin letters map to plain ASCII ones,
+// e.g. "café" becomes "cafe". Other scripts are left unchanged.
+func transliterate(text string) string {
+    stripped, _, err := transform.String(transform.Chain(norm.NFKD, runes.Remove(runes.In(unicode.Mn)), norm.NFC), text)
+    if err != nil {
+        return text
+    }
+    return stripped
+}
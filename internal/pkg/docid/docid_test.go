@@ -0,0 +1,122 @@
+package docid
+
+import (
+	"strings"
+	"testing"
+)
+
+// Verifies the IDs generated for a range of URLs.
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		name      string
+		url       string
+		canonical string
+		expected  string
+	}{
+		{"plain url", "https://example.com/page", "", "example.com_page"},
+		{"http scheme", "http://example.com/page", "", "example.com_page"},
+		{"root", "https://example.com", "", "example.com_"},
+		{"canonical preferred", "https://example.com/page?ref=1", "https://example.com/page", "example.com_page"},
+		{"blank canonical ignored", "https://example.com/page", "   ", "example.com_page"},
+		{"query and fragment", "https://example.com/search?q=go&page=2#top", "", "example.com_search_q_go_page_2_top"},
+		{"port", "https://example.com:8080/page", "", "example.com_8080_page"},
+		{"invalid characters dropped", "https://example.com/a~b!c", "", "example.com_abc"},
+		{"plus not decoded as space", "https://example.com/a+b", "", "example.com_ab"},
+		{"spaces", "https://example.com/a b", "", "example.com_a_b"},
+		{"empty", "", "", ""},
+	}
+
+	for _, tc := range tests {
+		if got := Generate(tc.url, tc.canonical); got != tc.expected {
+			t.Errorf("%s: Generate(%q, %q) = %q, expected %q", tc.name, tc.url, tc.canonical, got, tc.expected)
+		}
+	}
+}
+
+// Verifies that IDs are cut to 100 characters without long URLs sharing one.
+func TestSanitizeLength(t *testing.T) {
+	id := Sanitize("https://example.com/" + strings.Repeat("a", 200))
+	if len(id) != 100 {
+		t.Errorf("Expected ID of 100 characters, got %d", len(id))
+	}
+	if id == Sanitize("https://example.com/"+strings.Repeat("a", 201)) {
+		t.Error("Expected long URLs sharing a prefix to get different IDs")
+	}
+}
+
+// Verifies that URLs differing only by a trailing slash share a document ID.
+func TestGenerateTrailingSlash(t *testing.T) {
+	tests := []struct {
+		a, b string
+		same bool
+	}{
+		{"https://example.com/page", "https://example.com/page/", true},
+		{"https://example.com/a/b", "https://example.com/a/b//", true},
+		{"https://example.com", "https://example.com/", true},
+		{"https://example.com/page?q=1", "https://example.com/page/?q=1", true},
+		{" https://example.com/page/ ", "https://example.com/page", true},
+		{"https://example.com/page", "https://example.com/other/", false},
+		{"https://example.com/page", "https://example.com/page/child", false},
+		{"https://example.com/page?q=1", "https://example.com/page?q=2", false},
+	}
+
+	for _, tc := range tests {
+		idA, idB := Generate(tc.a, ""), Generate(tc.b, "")
+		if (idA == idB) != tc.same {
+			t.Errorf("Generate(%q) = %q, Generate(%q) = %q, expected same=%v", tc.a, idA, tc.b, idB, tc.same)
+		}
+	}
+
+	// The canonical URL is normalized the same way.
+	if Generate("https://example.com/x", "https://example.com/page/") != Generate("https://example.com/page", "") {
+		t.Error("Expected canonical URL with trailing slash to match the bare URL")
+	}
+}
+
+// Verifies that non-ASCII and percent-encoded URLs keep their characters
+// in the document ID.
+func TestSanitizeNonASCII(t *testing.T) {
+	tests := []struct {
+		raw      string
+		expected string
+	}{
+		{"https://example.com/café", "example.com_cafe"},
+		{"https://example.com/caf%C3%A9", "example.com_cafe"},
+		{"https://example.com/Ünïcödé", "example.com_Unicode"},
+		{"https://example.com/中文", "example.com__u004e2d__u006587__3d6f4b35f252910b703a24731283b9a4"},
+		{"https://example.com/%E4%B8%AD%E6%96%87", "example.com__u004e2d__u006587__3d6f4b35f252910b703a24731283b9a4"},
+		{"https://example.com/عربي", "example.com__u000639__u000631__u000628__u00064a__3a824911959c0d16906d923aa08f7f51"},
+	}
+
+	for _, tc := range tests {
+		if got := Sanitize(tc.raw); got != tc.expected {
+			t.Errorf("Sanitize(%q) = %q, expected %q", tc.raw, got, tc.expected)
+		}
+	}
+
+	if Sanitize("https://example.com/中文") == Sanitize("https://example.com/日本") {
+		t.Error("Expected different Chinese paths to get different IDs")
+	}
+}
+
+// Verifies that escaped code points can't collide with other URLs.
+func TestSanitizeEscapeCollisions(t *testing.T) {
+	longCJK := "https://example.com/" + strings.Repeat("中", 40)
+	tests := []struct {
+		a, b string
+	}{
+		{"https://example.com/ع1", "https://example.com/掑"},
+		{"https://example.com/u4e2d", "https://example.com/中"},
+		{"https://example.com/_u004e2d_", "https://example.com/中"},
+		{longCJK + "文", longCJK + "字"},
+	}
+
+	for _, tc := range tests {
+		if idA, idB := Sanitize(tc.a), Sanitize(tc.b); idA == idB {
+			t.Errorf("Sanitize(%q) and Sanitize(%q) both gave %q", tc.a, tc.b, idA)
+		}
+	}
+	if id := Sanitize(longCJK); len(id) > 100 {
+		t.Errorf("Expected ID of at most 100 characters, got %d", len(id))
+	}
+}
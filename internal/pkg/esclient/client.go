@@ -0,0 +1,191 @@
+package esclient
+
+import (
+    "bytes"
+    "context"
+    "fmt"
+    "io"
+    "net/http"
+    "strings"
+    "sync/atomic"
+    "time"
+    "go.uber.org/zap"
+    "indexer/internal/pkg/logger"
+    "indexer/internal/pkg/metrics"
+)
+
+// How often the primary is tried first again while a fallback is active
+var primaryProbeInterval = time.Minute
+
+// Sends requests to Elasticsearch through a primary endpoint and optional
+// fallbacks. Requests go to the active endpoint and fail over to the rest
+// in order, the first endpoint to succeed becoming the active one. While a
+// fallback is active the primary is tried first again every
+// primaryProbeInterval, so traffic returns to it once it recovers.
+//
+// Safe for concurrent use, and meant to be shared by everything writing to
+// the same cluster so they fail over together.
+type Client struct {
+    urls       []string // as configured, primary first
+    username   string
+    password   string
+    httpClient *http.Client
+
+    active           atomic.Int32 // index into urls
+    nextPrimaryProbe atomic.Int64 // Unix nanoseconds
+}
+
+// Configures optional Client behaviour.
+type Option func(*Client)
+
+// Adds endpoints to fail over to, in order, when the primary fails.
+func WithFallbackURLs(urls []string) Option {
+    return func(client *Client) {
+        client.urls = append(client.urls, urls...)
+    }
+}
+
+// Sends Basic Authentication credentials with every request. Credentials
+// are only used when both username and password are non-empty.
+func WithBasicAuth(username, password string) Option {
+    return func(client *Client) {
+        client.username = username
+        client.password = password
+    }
+}
+
+// Uses httpClient to send requests instead of http.DefaultClient.
+func WithHTTPClient(httpClient *http.Client) Option {
+    return func(client *Client) {
+        client.httpClient = httpClient
+    }
+}
+
+// Creates a new Client for the cluster at elasticURL, which may point at the
+// cluster root or at its _bulk endpoint.
+func New(elasticURL string, opts ...Option) *Client {
+    client := &Client{
+        urls:       []string{elasticURL},
+        httpClient: http.DefaultClient,
+    }
+    for _, opt := range opts {
+        opt(client)
+    }
+    client.recordActiveEndpoint(0)
+    return client
+}
+
+// Calls send with the root URL of each endpoint, starting at the active one,
+// until it succeeds. Returns the last error if every endpoint failed.
+func (client *Client) Try(send func(baseURL string) error) error {
+    start := client.startEndpoint()
+
+    var err error
+    for i := 0; i < len(client.urls); i++ {
+        endpoint := (start + i) % len(client.urls)
+        if err = send(ClusterURL(client.urls[endpoint])); err == nil {
+            client.setActive(endpoint)
+            return nil
+        }
+    }
+    return err
+}
+
+// Sends a request with body to path on the cluster. Endpoints that cannot be
+// reached or answer with a server error are failed over; any other response
+// is returned for the caller to inspect and close.
+func (client *Client) Do(ctx context.Context, method, path, contentType string, body []byte) (*http.Response, error) {
+    var response *http.Response
+    err := client.Try(func(baseURL string) error {
+        var reader io.Reader
+        if body != nil {
+            reader = bytes.NewReader(body)
+        }
+        request, err := http.NewRequestWithContext(ctx, method, baseURL+path, reader)
+        if err != nil {
+            return err
+        }
+        if contentType != "" {
+            request.Header.Set("Content-Type", contentType)
+        }
+        client.SetBasicAuth(request)
+
+        resp, err := client.httpClient.Do(request)
+        if err != nil {
+            return err
+        }
+        if resp.StatusCode >= 500 {
+            resp.Body.Close()
+            return fmt.Errorf("%s %s returned status: %d", method, baseURL+path, resp.StatusCode)
+        }
+        response = resp
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+    return response, nil
+}
+
+// Returns the HTTP client requests are sent with.
+func (client *Client) HTTPClient() *http.Client {
+    return client.httpClient
+}
+
+// Returns the endpoint requests currently go to, as configured.
+func (client *Client) ActiveURL() string {
+    return client.urls[client.active.Load()]
+}
+
+// Adds the configured Basic Authentication header to a request.
+func (client *Client) SetBasicAuth(request *http.Request) {
+    if client.username != "" && client.password != "" {
+        request.SetBasicAuth(client.username, client.password)
+    }
+}
+
+// Returns the endpoint to try first, which is the primary once the probe
+// interval has passed and the active one otherwise.
+func (client *Client) startEndpoint() int {
+    active := client.active.Load()
+    if active == 0 {
+        return 0
+    }
+    next := client.nextPrimaryProbe.Load()
+    now := time.Now().UnixNano()
+    if now >= next && client.nextPrimaryProbe.CompareAndSwap(next, now+int64(primaryProbeInterval)) {
+        return 0
+    }
+    return int(active)
+}
+
+// Makes endpoint the active one, logging and recording any change.
+func (client *Client) setActive(endpoint int) {
+    previous := client.active.Swap(int32(endpoint))
+    if int(previous) == endpoint {
+        return
+    }
+    if endpoint == 0 {
+        logger.Log.Info("Returned to primary Elasticsearch endpoint", zap.String("endpoint", client.urls[0]))
+    } else {
+        logger.Log.Warn("Failed over to Elasticsearch endpoint", zap.String("endpoint", client.urls[endpoint]))
+        client.nextPrimaryProbe.Store(time.Now().Add(primaryProbeInterval).UnixNano())
+    }
+    client.recordActiveEndpoint(endpoint)
+}
+
+// Marks the endpoint at the given position as active in the metrics.
+func (client *Client) recordActiveEndpoint(active int) {
+    for i, endpoint := range client.urls {
+        value := 0.0
+        if i == active {
+            value = 1
+        }
+        metrics.ElasticsearchActiveEndpoint.WithLabelValues(endpoint).Set(value)
+    }
+}
+
+// Derives the cluster root URL from an endpoint that may point at _bulk.
+func ClusterURL(elasticURL string) string {
+    return strings.TrimSuffix(strings.TrimSuffix(elasticURL, "/"), "/_bulk")
+}
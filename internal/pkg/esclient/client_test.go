@@ -0,0 +1,128 @@
+package esclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+	"go.uber.org/zap"
+	"indexer/internal/pkg/logger"
+)
+
+func init() {
+	logger.Log = zap.NewNop() // Set up a no-op logger to avoid nil pointer dereferences in tests.
+}
+
+// Starts a server answering every request with the status held in status.
+func newStatusServer(t *testing.T, status *atomic.Int32, requests *atomic.Int32) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(int(status.Load()))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// Verifies that requests fail over on server errors, stick to the endpoint
+// that answered and return client errors without failing over.
+func TestClientFailover(t *testing.T) {
+	var primaryStatus, fallbackStatus, primaryRequests, fallbackRequests atomic.Int32
+	primaryStatus.Store(http.StatusServiceUnavailable)
+	fallbackStatus.Store(http.StatusOK)
+	primary := newStatusServer(t, &primaryStatus, &primaryRequests)
+	fallback := newStatusServer(t, &fallbackStatus, &fallbackRequests)
+
+	client := New(primary.URL+"/_bulk", WithFallbackURLs([]string{fallback.URL}))
+	for i := 0; i < 2; i++ {
+		response, err := client.Do(context.Background(), http.MethodHead, "/index", "", nil)
+		if err != nil {
+			t.Fatalf("Expected request %d to succeed on the fallback, got %v", i+1, err)
+		}
+		response.Body.Close()
+	}
+	if client.ActiveURL() != fallback.URL {
+		t.Errorf("Expected the fallback to be active, got %s", client.ActiveURL())
+	}
+	if primaryRequests.Load() != 1 || fallbackRequests.Load() != 2 {
+		t.Errorf("Expected 1 primary and 2 fallback requests, got %d and %d", primaryRequests.Load(), fallbackRequests.Load())
+	}
+
+	fallbackStatus.Store(http.StatusNotFound)
+	response, err := client.Do(context.Background(), http.MethodHead, "/index", "", nil)
+	if err != nil {
+		t.Fatalf("Expected a client error to be returned, got %v", err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusNotFound || primaryRequests.Load() != 1 {
+		t.Errorf("Expected a 404 from the fallback without trying the primary, got %d after %d primary requests", response.StatusCode, primaryRequests.Load())
+	}
+
+	fallbackStatus.Store(http.StatusInternalServerError)
+	if _, err := client.Do(context.Background(), http.MethodHead, "/index", "", nil); err == nil {
+		t.Error("Expected an error once every endpoint fails")
+	}
+}
+
+// Verifies that the primary is tried first again once the probe interval
+// passes, and becomes active again once it recovers.
+func TestClientReturnsToPrimary(t *testing.T) {
+	defer func(interval time.Duration) { primaryProbeInterval = interval }(primaryProbeInterval)
+	primaryProbeInterval = 50 * time.Millisecond
+
+	var primaryStatus, fallbackStatus, primaryRequests, fallbackRequests atomic.Int32
+	primaryStatus.Store(http.StatusServiceUnavailable)
+	fallbackStatus.Store(http.StatusOK)
+	primary := newStatusServer(t, &primaryStatus, &primaryRequests)
+	fallback := newStatusServer(t, &fallbackStatus, &fallbackRequests)
+
+	client := New(primary.URL, WithFallbackURLs([]string{fallback.URL}))
+	send := func() {
+		t.Helper()
+		response, err := client.Do(context.Background(), http.MethodGet, "/", "", nil)
+		if err != nil {
+			t.Fatalf("Expected request to succeed, got %v", err)
+		}
+		response.Body.Close()
+	}
+
+	send()
+	if client.ActiveURL() != fallback.URL {
+		t.Fatalf("Expected the fallback to be active, got %s", client.ActiveURL())
+	}
+
+	// Within the interval the primary is left alone
+	send()
+	if primaryRequests.Load() != 1 {
+		t.Errorf("Expected the primary not to be probed within the interval, got %d requests", primaryRequests.Load())
+	}
+
+	primaryStatus.Store(http.StatusOK)
+	time.Sleep(primaryProbeInterval)
+	send()
+	if client.ActiveURL() != primary.URL {
+		t.Errorf("Expected the primary to be active again, got %s", client.ActiveURL())
+	}
+}
+
+// Verifies that credentials are only sent when both are set.
+func TestClientBasicAuth(t *testing.T) {
+	tests := []struct {
+		username, password string
+		expected           bool
+	}{
+		{"elastic", "secret", true},
+		{"elastic", "", false},
+	}
+
+	for _, tc := range tests {
+		client := New("http://localhost:9200", WithBasicAuth(tc.username, tc.password))
+		request, _ := http.NewRequest(http.MethodGet, "http://localhost:9200", nil)
+		client.SetBasicAuth(request)
+		username, password, ok := request.BasicAuth()
+		if ok != tc.expected || (ok && (username != tc.username || password != tc.password)) {
+			t.Errorf("SetBasicAuth(%q, %q) gave %q, %q, %v", tc.username, tc.password, username, password, ok)
+		}
+	}
+}
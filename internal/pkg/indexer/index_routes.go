@@ -0,0 +1,90 @@
+package indexer
+
+import (
+    "fmt"
+    "net/url"
+    "strconv"
+    "strings"
+    "indexer/internal/pkg/models"
+)
+
+// Parses comma-separated domain=index pairs, as in INDEX_ROUTES, into a map
+// from lower-cased domain to index name.
+func ParseIndexRoutes(value string) (map[string]string, error) {
+    routes := make(map[string]string)
+    err := parsePairs(value, func(domain, index string) error {
+        routes[strings.ToLower(domain)] = index
+        return nil
+    })
+    return routes, err
+}
+
+// Parses comma-separated index=threshold pairs, as in INDEX_BULK_THRESHOLDS.
+func ParseIndexThresholds(value string) (map[string]int, error) {
+    thresholds := make(map[string]int)
+    err := parsePairs(value, func(index, threshold string) error {
+        n, err := strconv.Atoi(threshold)
+        if err != nil {
+            return fmt.Errorf("invalid bulk threshold for index %q: %w", index, err)
+        }
+        thresholds[index] = n
+        return nil
+    })
+    return thresholds, err
+}
+
+// Calls add with the trimmed key and value of every key=value pair in the
+// comma-separated value, skipping empty entries.
+func parsePairs(value string, add func(key, value string) error) error {
+    for _, pair := range strings.Split(value, ",") {
+        if pair = strings.TrimSpace(pair); pair == "" {
+            continue
+        }
+        key, val, ok := strings.Cut(pair, "=")
+        key, val = strings.TrimSpace(key), strings.TrimSpace(val)
+        if !ok || key == "" || val == "" {
+            return fmt.Errorf("invalid pair %q, expected key=value", pair)
+        }
+        if err := add(key, val); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// Routes documents whose host, or one of its parent domains, is in routes
+// to the mapped index. Other documents go to the default index.
+func DomainRouter(routes map[string]string) IndexRouter {
+    return func(doc *models.Document) string {
+        parsed, err := url.Parse(doc.URL)
+        if err != nil {
+            return ""
+        }
+        host := strings.ToLower(parsed.Hostname())
+        for host != "" {
+            if index, ok := routes[host]; ok {
+                return index
+            }
+            _, parent, found := strings.Cut(host, ".")
+            if !found {
+                break
+            }
+            host = parent
+        }
+        return ""
+    }
+}
+
+// Routes documents by domain as DomainRouter does, and has EnsureIndex
+// create the routed indices alongside the default one.
+func WithIndexRoutes(routes map[string]string) Option {
+    return func(indexer *BulkIndexer) {
+        if len(routes) == 0 {
+            return
+        }
+        indexer.router = DomainRouter(routes)
+        for _, index := range routes {
+            indexer.routedIndices = append(indexer.routedIndices, index)
+        }
+    }
+}
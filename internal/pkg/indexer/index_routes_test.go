@@ -0,0 +1,117 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"indexer/internal/pkg/models"
+)
+
+// Verifies that domain=index and index=threshold lists are parsed and
+// malformed entries rejected.
+func TestParseIndexRoutesAndThresholds(t *testing.T) {
+	routes, err := ParseIndexRoutes(" News.example.com = news_index, ,blog.example.org=blog_index")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(routes) != 2 || routes["news.example.com"] != "news_index" || routes["blog.example.org"] != "blog_index" {
+		t.Errorf("Unexpected routes %v", routes)
+	}
+	if routes, err := ParseIndexRoutes(""); err != nil || len(routes) != 0 {
+		t.Errorf("Expected no routes for an empty value, got %v, %v", routes, err)
+	}
+	for _, value := range []string{"example.com", "=news_index", "example.com="} {
+		if _, err := ParseIndexRoutes(value); err == nil {
+			t.Errorf("Expected error for routes %q, got nil", value)
+		}
+	}
+
+	thresholds, err := ParseIndexThresholds("news_index=1,blog_index=50")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if thresholds["news_index"] != 1 || thresholds["blog_index"] != 50 {
+		t.Errorf("Unexpected thresholds %v", thresholds)
+	}
+	if _, err := ParseIndexThresholds("news_index=many"); err == nil {
+		t.Error("Expected error for a non-numeric threshold, got nil")
+	}
+}
+
+// Verifies that documents are routed by their host or a parent domain.
+func TestDomainRouter(t *testing.T) {
+	router := DomainRouter(map[string]string{"example.com": "example_index", "news.example.org": "news_index"})
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{"https://example.com/page", "example_index"},
+		{"https://blog.Example.com/page", "example_index"},
+		{"https://news.example.org/story", "news_index"},
+		{"https://example.org/", ""},
+		{"https://notexample.com/", ""},
+		{"::invalid", ""},
+	}
+	for _, tc := range tests {
+		if got := router(&models.Document{URL: tc.url}); got != tc.expected {
+			t.Errorf("router(%q) = %q, expected %q", tc.url, got, tc.expected)
+		}
+	}
+}
+
+// Verifies that EnsureIndex creates every routed index alongside the
+// default one, and that post-flush hooks are given the routed index.
+func TestBulkIndexerIndexRoutes(t *testing.T) {
+	var mu sync.Mutex
+	var created []string
+	hookIndexCh := make(chan string, 1)
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodHead:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		case http.MethodPut:
+			mu.Lock()
+			created = append(created, strings.TrimPrefix(r.URL.Path, "/"))
+			mu.Unlock()
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer testServer.Close()
+
+	indexer, err := NewBulkIndexer(context.Background(), 1, testServer.URL, "default_index", 60, 0,
+		WithIndexRoutes(map[string]string{"news.example.com": "news_index", "blog.example.com": "news_index"}),
+		WithPostFlushHook(func(index string, docs []*models.Document) {
+			hookIndexCh <- index
+		}))
+	if err != nil {
+		t.Fatalf("Failed to create BulkIndexer: %v", err)
+	}
+	defer indexer.Stop()
+
+	if err := indexer.EnsureIndex(context.Background(), json.RawMessage(DefaultIndexMapping)); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	mu.Lock()
+	sort.Strings(created)
+	if len(created) != 2 || created[0] != "default_index" || created[1] != "news_index" {
+		t.Errorf("Expected default_index and news_index to be created once each, got %v", created)
+	}
+	mu.Unlock()
+
+	indexer.AddDocumentToIndexerPayload(&models.Document{URL: "https://news.example.com/story"})
+	select {
+	case index := <-hookIndexCh:
+		if index != "news_index" {
+			t.Errorf("Expected the post-flush hook to get news_index, got %q", index)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("Timed out waiting for the post-flush hook")
+	}
+}
@@ -4,12 +4,18 @@ import (
     "bytes"
     "context"
     "encoding/json"
-    "math/rand"
+    "fmt"
+    "io"
     "net/http"
+    "net/url"
     "strings"
     "sync"
+    "sync/atomic"
     "time"
     "go.uber.org/zap"
+    "indexer/internal/pkg/backoff"
+    "indexer/internal/pkg/docid"
+    "indexer/internal/pkg/esclient"
     "indexer/internal/pkg/logger"
     "indexer/internal/pkg/models"
     "indexer/internal/pkg/metrics"
@@ -18,66 +24,310 @@ import (
 // Buffers documents until threshold or flush interval is reached.
 type BulkIndexer struct {
     mutex         sync.Mutex
-    buffer        []*models.Document
+    buffers       map[string][]*models.Document // pending documents per index
+    bufferedAt    map[string]time.Time          // arrival of the oldest pending document per index
     threshold     int
+    thresholds    map[string]int // per-index overrides of threshold
+    router        IndexRouter
+    routedIndices []string // created by EnsureIndex alongside indexName
     flushChannel  chan struct{}
 
     elasticURL    string
     indexName     string
 
+    // Sends requests and fails over between endpoints, built from elasticURL
+    // and clientOpts unless given with WithClient
+    client     *esclient.Client
+    clientOpts []esclient.Option
+
     flushInterval time.Duration
     maxRetries    int
     wg            sync.WaitGroup
 
+    // Bounds the bulk requests in flight, a slot is held for each send
+    maxConcurrentFlushes int
+    flushSlots           chan struct{}
+
+    // Hooks run after each successful bulk request
+    postFlushHooks []PostFlushHook
+
+    // Called after every bulk request, successful or not
+    onFlushComplete func(count int, err error)
+
+    // Builds payloads as usual but never sends them
+    dryRun bool
+
+    // Writes documents as scripted upserts that keep their inbound links
+    scriptedUpserts bool
+
+    // Reuses NDJSON payload buffers across flushes
+    bufferPool sync.Pool
+
+    // Unix nanoseconds until which the indexer reports itself unhealthy
+    unhealthyUntil atomic.Int64
     
-    done chan struct{} // for stopping the flush goroutine
+    done        chan struct{} // for stopping the flush goroutine
+    flusherDone chan struct{} // closed once the flush goroutine has exited
+}
+
+// Painless script that replaces a stored document with params.doc while
+// keeping the inbound links recorded by the LinkCountAggregator, which a
+// plain index action would wipe on every re-crawl. Used with
+// WithScriptedUpserts.
+const upsertDocumentScript = "def sources = ctx._source.inbound_link_sources; " +
+    "ctx._source.clear(); ctx._source.putAll(params.doc); " +
+    "if (sources != null) { ctx._source.inbound_link_sources = sources; ctx._source.inbound_link_count = sources.size() }"
+
+// Times Elasticsearch retries an update that raced another update of the
+// same document, such as a link count update
+const updateRetriesOnConflict = 3
+
+// Bulk requests in flight at once unless WithMaxConcurrentFlushes is given
+const defaultMaxConcurrentFlushes = 4
+
+// How long the indexer reports itself unhealthy after a bulk request fails
+// on every endpoint. Once it passes, writes are let through again to probe
+// whether Elasticsearch has recovered.
+var unhealthyCooldown = 10 * time.Second
+
+// Configures optional BulkIndexer behaviour.
+type Option func(*BulkIndexer)
+
+// Picks the index a document is written to. An empty result means the
+// default index.
+type IndexRouter func(doc *models.Document) string
+
+// Routes documents to indices other than the default one.
+func WithIndexRouter(router IndexRouter) Option {
+    return func(indexer *BulkIndexer) {
+        indexer.router = router
+    }
+}
+
+// Overrides the flush threshold for documents routed to indexName.
+func WithIndexThreshold(indexName string, threshold int) Option {
+    return func(indexer *BulkIndexer) {
+        indexer.thresholds[indexName] = threshold
+    }
+}
+
+// Called with the index and documents of a flush once Elasticsearch has
+// accepted them.
+type PostFlushHook func(index string, docs []*models.Document)
+
+// Registers a hook to run after every successful flush. Hooks run in the
+// flush goroutine, so long-running work should be kept to a minimum.
+func WithPostFlushHook(hook PostFlushHook) Option {
+    return func(indexer *BulkIndexer) {
+        indexer.postFlushHooks = append(indexer.postFlushHooks, hook)
+    }
+}
+
+// Registers fn to be called with the number of documents and the resulting
+// error once each bulk request completes, including failed ones. fn runs in
+// the flush goroutine, so it should return quickly.
+func WithOnFlushComplete(fn func(count int, err error)) Option {
+    return func(indexer *BulkIndexer) {
+        indexer.onFlushComplete = fn
+    }
+}
+
+// Adds endpoints to fail over to, in order, when the primary URL keeps
+// failing after all retries. Ignored when WithClient is given.
+func WithFallbackURLs(urls []string) Option {
+    return func(indexer *BulkIndexer) {
+        indexer.clientOpts = append(indexer.clientOpts, esclient.WithFallbackURLs(urls))
+    }
+}
+
+// Sends every request through client instead of one built from the
+// Elasticsearch URL, so failover is shared with other writers to the cluster.
+func WithClient(client *esclient.Client) Option {
+    return func(indexer *BulkIndexer) {
+        indexer.client = client
+    }
+}
+
+// Limits how many bulk requests may be in flight at once. Further flushes
+// wait for a request to complete, so a slow Elasticsearch slows flushing
+// down rather than piling up requests.
+func WithMaxConcurrentFlushes(n int) Option {
+    return func(indexer *BulkIndexer) {
+        indexer.maxConcurrentFlushes = n
+    }
 }
 
-// Creates a new BulkIndexer.
-func NewBulkIndexer(threshold int, elasticURL, indexName string, flushIntervalSeconds, maxRetries int) *BulkIndexer {
+// Enables dry-run mode, where flushes log the payload instead of
+// writing it to Elasticsearch.
+func WithDryRun(dryRun bool) Option {
+    return func(indexer *BulkIndexer) {
+        indexer.dryRun = dryRun
+    }
+}
+
+// Writes documents as scripted upserts instead of index actions, so the
+// inbound links recorded by a LinkCountAggregator survive re-indexing. Each
+// write becomes a read-modify-write of the stored document, which is slower,
+// and the cluster must allow inline Painless scripts.
+func WithScriptedUpserts(enabled bool) Option {
+    return func(indexer *BulkIndexer) {
+        indexer.scriptedUpserts = enabled
+    }
+}
+
+// Sends Basic Authentication credentials with every Elasticsearch request.
+// Credentials are only used when both username and password are non-empty.
+// Ignored when WithClient is given.
+func WithBasicAuth(username, password string) Option {
+    return func(indexer *BulkIndexer) {
+        indexer.clientOpts = append(indexer.clientOpts, esclient.WithBasicAuth(username, password))
+    }
+}
+
+// Creates a new BulkIndexer. Returns an error if the threshold or flush
+// interval is below 1 or the Elasticsearch URL is not a valid absolute URL.
+// Cancelling ctx stops the background flushing after a final flush; later
+// documents are only sent by ForceFlush or Stop.
+func NewBulkIndexer(ctx context.Context, threshold int, elasticURL, indexName string, flushIntervalSeconds, maxRetries int, opts ...Option) (*BulkIndexer, error) {
+    if threshold < 1 {
+        return nil, fmt.Errorf("bulk threshold must be at least 1, got %d", threshold)
+    }
+    if flushIntervalSeconds < 1 {
+        return nil, fmt.Errorf("flush interval must be at least 1 second, got %d", flushIntervalSeconds)
+    }
+    if elasticURL == "" {
+        return nil, fmt.Errorf("elasticsearch URL must not be empty")
+    }
+    if parsed, err := url.Parse(elasticURL); err != nil || parsed.Scheme == "" || parsed.Host == "" {
+        return nil, fmt.Errorf("invalid elasticsearch URL %q", elasticURL)
+    }
+
     indexer := &BulkIndexer{
-        buffer:         make([]*models.Document, 0, threshold),
+        buffers:        make(map[string][]*models.Document),
+        bufferedAt:     make(map[string]time.Time),
         threshold:      threshold,
+        thresholds:     make(map[string]int),
         flushChannel:   make(chan struct{}, 1),
         elasticURL:     elasticURL,
         indexName:      indexName,
         flushInterval:  time.Duration(flushIntervalSeconds) * time.Second,
         maxRetries:     maxRetries,
+        maxConcurrentFlushes: defaultMaxConcurrentFlushes,
         done:           make(chan struct{}),
+        flusherDone:    make(chan struct{}),
+    }
+    indexer.bufferPool.New = func() interface{} { return new(bytes.Buffer) }
+    for _, opt := range opts {
+        opt(indexer)
+    }
+    for name, indexThreshold := range indexer.thresholds {
+        if indexThreshold < 1 {
+            return nil, fmt.Errorf("bulk threshold for index %q must be at least 1, got %d", name, indexThreshold)
+        }
+    }
+    if indexer.maxConcurrentFlushes < 1 {
+        return nil, fmt.Errorf("max concurrent flushes must be at least 1, got %d", indexer.maxConcurrentFlushes)
+    }
+    indexer.flushSlots = make(chan struct{}, indexer.maxConcurrentFlushes)
+    if indexer.client == nil {
+        indexer.client = esclient.New(elasticURL, indexer.clientOpts...)
+    }
+    go indexer.startFlushing(ctx)
+    return indexer, nil
+}
+
+// Checks that the default index and any routed indices exist, creating
+// those that don't with the given mapping.
+func (indexer *BulkIndexer) EnsureIndex(ctx context.Context, mappingJSON json.RawMessage) error {
+    ensured := make(map[string]struct{})
+    for _, index := range append([]string{indexer.indexName}, indexer.routedIndices...) {
+        if _, ok := ensured[index]; ok {
+            continue
+        }
+        ensured[index] = struct{}{}
+        if err := indexer.ensureIndex(ctx, index, mappingJSON); err != nil {
+            return err
+        }
     }
-    go indexer.startFlushing()
-    return indexer
+    return nil
 }
 
-// Runs in a goroutine and triggers flush on signal or interval
-func (indexer *BulkIndexer) startFlushing() {
+// Checks that an index exists and creates it with the given mapping if not.
+func (indexer *BulkIndexer) ensureIndex(ctx context.Context, index string, mappingJSON json.RawMessage) error {
+    if indexer.dryRun {
+        logger.Log.Info("Dry run, skipping index check", zap.String("index", index))
+        return nil
+    }
+
+    indexPath := "/" + index
+
+    response, err := indexer.client.Do(ctx, http.MethodHead, indexPath, "", nil)
+    if err != nil {
+        return fmt.Errorf("failed to check index %q: %w", index, err)
+    }
+    response.Body.Close()
+
+    switch {
+    case response.StatusCode == http.StatusOK:
+        logger.Log.Info("Elasticsearch index exists", zap.String("index", index))
+        return nil
+    case response.StatusCode != http.StatusNotFound:
+        return fmt.Errorf("unexpected status checking index %q: %d", index, response.StatusCode)
+    }
+
+    response, err = indexer.client.Do(ctx, http.MethodPut, indexPath, "application/json", mappingJSON)
+    if err != nil {
+        return fmt.Errorf("failed to create index %q: %w", index, err)
+    }
+    defer response.Body.Close()
+
+    if response.StatusCode < 200 || response.StatusCode >= 300 {
+        return fmt.Errorf("unexpected status creating index %q: %d", index, response.StatusCode)
+    }
+
+    logger.Log.Info("Created Elasticsearch index", zap.String("index", index))
+    return nil
+}
+
+// Runs in a goroutine and triggers flush on signal or interval until
+// the indexer is stopped or ctx is cancelled
+func (indexer *BulkIndexer) startFlushing(ctx context.Context) {
+    defer close(indexer.flusherDone)
     ticker := time.NewTicker(indexer.flushInterval)
     defer ticker.Stop()
 
     for {
         select {
         case <-indexer.done:
-            // Final flush before shutdown
-            logger.Log.Info("BulkIndexer received done signal, flushing before exit")
+            // Stop sends whatever is left
+            return
+        case <-ctx.Done():
+            logger.Log.Info("BulkIndexer context cancelled, flushing before exit")
             indexer.flush()
             return
         case <-indexer.flushChannel:
-            indexer.flush()
+            indexer.startFlush(false)
         case <-ticker.C:
             indexer.flush()
         }
     }
 }
 
-// Adds a doc to the buffer and signals flush if threshold is met.
+// Adds a doc to its index's buffer and signals flush if that index's
+// threshold is met.
 func (indexer *BulkIndexer) AddDocumentToIndexerPayload(doc *models.Document) {
+    index := indexer.indexFor(doc)
+
     indexer.mutex.Lock()
-    indexer.buffer = append(indexer.buffer, doc)
-    count := len(indexer.buffer)
+    if len(indexer.buffers[index]) == 0 {
+        indexer.bufferedAt[index] = time.Now()
+    }
+    indexer.buffers[index] = append(indexer.buffers[index], doc)
+    count := len(indexer.buffers[index])
     indexer.mutex.Unlock()
 
     // If threshold is reached, signal a flush
-    if count >= indexer.threshold {
+    if count >= indexer.thresholdFor(index) {
         select {
         case indexer.flushChannel <- struct{}{}:
         default:
@@ -86,144 +336,307 @@ func (indexer *BulkIndexer) AddDocumentToIndexerPayload(doc *models.Document) {
     }
 }
 
-// Builds NDJSON payload and sends it to Elasticsearch.
+// Returns the index a document is routed to.
+func (indexer *BulkIndexer) indexFor(doc *models.Document) string {
+    if indexer.router != nil {
+        if index := indexer.router(doc); index != "" {
+            return index
+        }
+    }
+    return indexer.indexName
+}
+
+// Returns the flush threshold for an index.
+func (indexer *BulkIndexer) thresholdFor(index string) int {
+    if threshold, ok := indexer.thresholds[index]; ok {
+        return threshold
+    }
+    return indexer.threshold
+}
+
+// Flushes the buffered documents immediately and blocks until the resulting
+// bulk requests, including retries and post-flush hooks, have completed.
+func (indexer *BulkIndexer) ForceFlush() {
+    if done := indexer.startFlush(true); done != nil {
+        <-done
+    }
+}
+
+// Builds NDJSON payloads for every index and sends them to Elasticsearch.
 func (indexer *BulkIndexer) flush() {
+    indexer.startFlush(true)
+}
+
+// Sends the buffered documents in the background, one bulk request per index.
+// Unless all is set, only indices that reached their threshold are flushed.
+// Returns a channel closed once every send completes, or nil if nothing was sent.
+func (indexer *BulkIndexer) startFlush(all bool) <-chan struct{} {
     indexer.mutex.Lock()
-    if len(indexer.buffer) == 0 {
-        indexer.mutex.Unlock()
-        return
+    pending := make(map[string][]*models.Document)
+    var oldestAge time.Duration
+    for index, docs := range indexer.buffers {
+        if len(docs) == 0 {
+            continue
+        }
+        oldestAge = max(oldestAge, time.Since(indexer.bufferedAt[index]))
+        if all || len(docs) >= indexer.thresholdFor(index) {
+            pending[index] = docs
+            delete(indexer.buffers, index)
+            delete(indexer.bufferedAt, index)
+        }
     }
-    docsToIndex := indexer.buffer
-    indexer.buffer = make([]*models.Document, 0, indexer.threshold)
     indexer.mutex.Unlock()
+    metrics.OldestDocumentAge.Set(oldestAge.Seconds())
+
+    var sends sync.WaitGroup
+    for index, docs := range pending {
+        if done := indexer.flushIndex(index, docs); done != nil {
+            sends.Add(1)
+            go func() {
+                defer sends.Done()
+                <-done
+            }()
+        }
+    }
+    if len(pending) == 0 {
+        return nil
+    }
 
+    allDone := make(chan struct{})
+    go func() {
+        sends.Wait()
+        close(allDone)
+    }()
+    return allDone
+}
+
+// Sends the documents of one index in the background. Returns a channel
+// closed once the send completes, or nil if nothing was sent.
+func (indexer *BulkIndexer) flushIndex(index string, docsToIndex []*models.Document) <-chan struct{} {
     metrics.BulkFlushes.Inc()
 
-    // Build NDJSON
-    var ndjsonPayload bytes.Buffer
-    for _, doc := range docsToIndex {
+    // Build NDJSON in a pooled buffer, returned once the payload is no longer needed
+    ndjsonPayload := indexer.bufferPool.Get().(*bytes.Buffer)
+    ndjsonPayload.Reset()
+    writeBulkPayload(ndjsonPayload, index, docsToIndex, indexer.scriptedUpserts)
+
+    if indexer.dryRun {
+        logger.Log.Info("Dry run, skipping Elasticsearch write", zap.String("index", index), zap.Int("count", len(docsToIndex)))
+        logger.Log.Debug("Dry run bulk payload", zap.String("payload", ndjsonPayload.String()))
+        metrics.DryRunDocuments.Add(float64(len(docsToIndex)))
+        indexer.bufferPool.Put(ndjsonPayload)
+        return nil
+    }
+
+    logger.Log.Info("Flushing documents to Elasticsearch", zap.String("index", index), zap.Int("count", len(docsToIndex)))
+    done := make(chan struct{})
+    // Wait for a free slot before sending, released once the request and its hooks finish
+    indexer.flushSlots <- struct{}{}
+    indexer.wg.Add(1)
+    go func() {
+        defer indexer.wg.Done()
+        defer close(done)
+        defer func() { <-indexer.flushSlots }()
+        // sendBulkRequest returns only once the transport is done reading
+        // the payload, so the buffer can't be reused while still being sent
+        err := indexer.sendBulkRequest(ndjsonPayload.Bytes())
+        indexer.bufferPool.Put(ndjsonPayload)
+        if indexer.onFlushComplete != nil {
+            indexer.onFlushComplete(len(docsToIndex), err)
+        }
+        if err != nil {
+            return
+        }
+        for _, hook := range indexer.postFlushHooks {
+            hook(index, docsToIndex)
+        }
+    }()
+    return done
+}
+
+// Appends an NDJSON index action for each document to payload, or a
+// scripted upsert when scripted is set.
+func writeBulkPayload(payload *bytes.Buffer, index string, docs []*models.Document, scripted bool) {
+    for _, doc := range docs {
         // Generate doc ID from URL or canonical URL
-        docID := generateDocID(doc.URL, doc.CanonicalURL)
-        meta := map[string]map[string]string{
-            "index": {
-                "_index": indexer.indexName,
-                "_id":    docID,
-            },
-        }
-        metaLine, err := json.Marshal(meta)
+        docID := docid.Generate(doc.URL, doc.CanonicalURL)
+        action := map[string]interface{}{
+            "_index": index,
+            "_id":    docID,
+        }
+        actionName := "index"
+        var source interface{} = doc
+        if scripted {
+            // Upsert rather than index, so inbound links survive a re-crawl
+            actionName = "update"
+            action["retry_on_conflict"] = updateRetriesOnConflict
+            source = map[string]interface{}{
+                "scripted_upsert": true,
+                "script": map[string]interface{}{
+                    "source": upsertDocumentScript,
+                    "lang":   "painless",
+                    "params": map[string]interface{}{"doc": doc},
+                },
+                "upsert": struct{}{},
+            }
+        }
+
+        metaLine, err := json.Marshal(map[string]interface{}{actionName: action})
         if err != nil {
             logger.Log.Error("Failed to marshal meta line", zap.Error(err))
             continue
         }
-        ndjsonPayload.Write(metaLine)
-        ndjsonPayload.WriteByte('\n')
-
-        docLine, err := json.Marshal(doc)
+        docLine, err := json.Marshal(source)
         if err != nil {
             logger.Log.Error("Failed to marshal document", zap.Error(err))
             continue
         }
-        ndjsonPayload.Write(docLine)
-        ndjsonPayload.WriteByte('\n')
+        payload.Write(metaLine)
+        payload.WriteByte('\n')
+        payload.Write(docLine)
+        payload.WriteByte('\n')
     }
-
-    logger.Log.Info("Flushing documents to Elasticsearch", zap.Int("count", len(docsToIndex)))
-    indexer.wg.Add(1)
-    go func() {
-        defer indexer.wg.Done()
-        indexer.sendBulkRequest(ndjsonPayload.Bytes(), 0)
-    }()
 }
 
-// Gracefully stops the BulkIndexer (e.g., called during shutdown).
+// Gracefully stops the BulkIndexer (e.g., called during shutdown), sending
+// every buffered document first, including those added after ctx was cancelled.
 func (indexer *BulkIndexer) Stop() {
     close(indexer.done)
+    <-indexer.flusherDone
+
+    logger.Log.Info("BulkIndexer stopping, flushing before exit")
+    indexer.flush()
     indexer.wg.Wait() // Wait for in-flight requests to finish
 }
 
-// Tries to POST the NDJSON to Elasticsearch, with optional retries.
-func (indexer *BulkIndexer) sendBulkRequest(payload []byte, attempt int) {
-    request, err := http.NewRequestWithContext(context.Background(), "POST", indexer.elasticURL, bytes.NewReader(payload))
+// Sends the NDJSON to the active endpoint, failing over to the remaining
+// endpoints in order once the retries on one are exhausted.
+func (indexer *BulkIndexer) sendBulkRequest(payload []byte) error {
+    metrics.BulkRequestSizeBytes.Observe(float64(len(payload)))
+
+    err := indexer.client.Try(func(baseURL string) error {
+        return indexer.sendBulkRequestTo(baseURL+"/_bulk", payload, 0)
+    })
+    if err != nil {
+        metrics.BulkFailures.Inc()
+        indexer.unhealthyUntil.Store(time.Now().Add(unhealthyCooldown).UnixNano())
+        return err
+    }
+    indexer.unhealthyUntil.Store(0)
+    return nil
+}
+
+// Reports whether Elasticsearch is accepting writes. After a bulk request
+// fails on every endpoint the indexer is unhealthy for unhealthyCooldown,
+// or until a later request succeeds.
+func (indexer *BulkIndexer) IsHealthy() bool {
+    return time.Now().UnixNano() >= indexer.unhealthyUntil.Load()
+}
+
+// Tries to POST the NDJSON to an Elasticsearch endpoint, with optional retries.
+// Returns an error once all attempts have failed.
+func (indexer *BulkIndexer) sendBulkRequestTo(endpoint string, payload []byte, attempt int) error {
+    body := newPayloadBody(payload)
+    // net/http may still be sending the body after the response arrives
+    defer body.wait()
+
+    request, err := http.NewRequestWithContext(context.Background(), "POST", endpoint, body)
     if err != nil {
+        body.Close()
         logger.Log.Error("Failed to create bulk request", zap.Error(err))
-        return
+        return err
     }
+    // Unknown for a custom body, and without GetBody the transport never
+    // replays it behind our back
+    request.ContentLength = int64(len(payload))
     request.Header.Set("Content-Type", "application/x-ndjson")
+    indexer.client.SetBasicAuth(request)
 
-    response, err := http.DefaultClient.Do(request)
+    response, err := indexer.client.HTTPClient().Do(request)
     if err != nil {
-        logger.Log.Error("Bulk request failed", zap.Error(err), zap.Int("attempt", attempt))
+        logger.Log.Error("Bulk request failed", zap.Error(err), zap.String("endpoint", endpoint), zap.Int("attempt", attempt))
         // Retry if we haven't exceeded maxRetries
         if attempt < indexer.maxRetries {
-            time.Sleep(backoffDuration(attempt))
-            indexer.sendBulkRequest(payload, attempt + 1)
-        } else {
-            metrics.BulkFailures.Inc()
+            time.Sleep(backoff.Exponential(attempt, baseBackoff, maxBackoff))
+            return indexer.sendBulkRequestTo(endpoint, payload, attempt + 1)
         }
-        return
+        return err
     }
     defer response.Body.Close()
 
     if response.StatusCode >= 200 && response.StatusCode < 300 {
         logger.Log.Info("Bulk indexing successful", zap.Int("status_code", response.StatusCode))
-    } else {
-        logger.Log.Warn("Bulk indexing failed", zap.Int("status_code", response.StatusCode), zap.Int("attempt", attempt))
-        // Retry on non-2xx if we haven't exceeded maxRetries
-        if attempt < indexer.maxRetries {
-            time.Sleep(backoffDuration(attempt))
-            indexer.sendBulkRequest(payload, attempt+1)
-        } else {
-            metrics.BulkFailures.Inc()
-        }
+        return nil
+    }
+
+    logger.Log.Warn("Bulk indexing failed",
+        zap.Int("status_code", response.StatusCode),
+        zap.String("reason", errorReason(response.Body)),
+        zap.String("endpoint", endpoint),
+        zap.Int("attempt", attempt))
+    // Retry on non-2xx if we haven't exceeded maxRetries
+    if attempt < indexer.maxRetries {
+        time.Sleep(backoff.Exponential(attempt, baseBackoff, maxBackoff))
+        return indexer.sendBulkRequestTo(endpoint, payload, attempt+1)
     }
+    return fmt.Errorf("bulk request failed with status: %d", response.StatusCode)
 }
 
-// Returns a simple exponential backoff time.
-func backoffDuration(attempt int) time.Duration {
-    base := time.Second
-    // Exponential backoff, plus a little jitter
-    backoff := time.Duration(1<<attempt) * base
-    jitter := time.Duration(rand.Intn(1000)) * time.Millisecond
-    return backoff + jitter
+// Request body over a pooled payload that reports when the transport has
+// closed it. net/http may keep reading a request body after the response
+// arrives, e.g. when Elasticsearch rejects an upload early, but always
+// closes it once done.
+type payloadBody struct {
+    *bytes.Reader
+    closeOnce sync.Once
+    closed    chan struct{}
 }
 
-// Returns a stable ID based on canonicalURL if available, else URL.
-// Additional hashing or slugification may be used for a consistent ID in future.
-func generateDocID(urlStr, canonicalStr string) string {
-    if strings.TrimSpace(canonicalStr) != "" {
-        return sanitizeID(canonicalStr)
-    }
-    return sanitizeID(urlStr)
+func newPayloadBody(payload []byte) *payloadBody {
+    return &payloadBody{Reader: bytes.NewReader(payload), closed: make(chan struct{})}
 }
 
-// Sanitize the ID to remove problematic characters and ensure it's URL-safe.
-func sanitizeID(raw string) string {
-    // Remove protocols
-    clean := strings.ReplaceAll(raw, "http://", "")
-    clean = strings.ReplaceAll(clean, "https://", "")
-    
-    // Replace problematic characters
-    clean = strings.ReplaceAll(clean, "/", "_")
-    clean = strings.ReplaceAll(clean, "?", "_")
-    clean = strings.ReplaceAll(clean, "&", "_")
-    clean = strings.ReplaceAll(clean, "=", "_")
-    clean = strings.ReplaceAll(clean, "#", "_")
-    clean = strings.ReplaceAll(clean, " ", "_")
-    clean = strings.ReplaceAll(clean, ":", "_")
-    
-    // Remove any remaining invalid characters
-    var result strings.Builder
-    for _, r := range clean {
-        if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' || r == '.' || r == '-' {
-            result.WriteRune(r)
-        }
+func (body *payloadBody) Close() error {
+    body.closeOnce.Do(func() { close(body.closed) })
+    return nil
+}
+
+// Blocks until the transport has closed the body.
+func (body *payloadBody) wait() {
+    <-body.closed
+}
+
+// Upper bound on how much of an error response is read
+const maxErrorBodyBytes = 4 << 10 // 4KB
+
+// Extracts error.reason from an Elasticsearch error response, falling back
+// to the raw body when it is not in the expected shape.
+func errorReason(body io.Reader) string {
+    data, err := io.ReadAll(io.LimitReader(body, maxErrorBodyBytes))
+    if err != nil {
+        return ""
     }
-    
-    // Keep it short
-    resultStr := result.String()
-    if len(resultStr) > 100 {
-        resultStr = resultStr[:100]
+
+    var response struct {
+        Error json.RawMessage `json:"error"`
     }
-    
-    return resultStr
+    if err := json.Unmarshal(data, &response); err == nil && len(response.Error) > 0 {
+        var detail struct {
+            Reason string `json:"reason"`
+        }
+        if err := json.Unmarshal(response.Error, &detail); err == nil && detail.Reason != "" {
+            return detail.Reason
+        }
+        var message string
+        if err := json.Unmarshal(response.Error, &message); err == nil {
+            return message
+        }
+    }
+    return strings.TrimSpace(string(data))
 }
+
+// Bounds of the retry backoff
+const (
+    baseBackoff = time.Second
+    maxBackoff  = 30 * time.Second
+)
@@ -3,17 +3,25 @@ package indexer
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 	"go.uber.org/zap"
+	dto "github.com/prometheus/client_model/go"
+	"indexer/internal/pkg/docid"
+	"indexer/internal/pkg/esclient"
+	"indexer/internal/pkg/metrics"
 	"indexer/internal/pkg/models"
 	"indexer/internal/pkg/logger"
+	"indexer/internal/pkg/testutil"
 )
 
 func init() {
@@ -24,26 +32,19 @@ func init() {
 // Verifies that when the threshold is met, the BulkIndexer 
 // flushes documents to the (simulated) Elasticsearch endpoint.
 func TestBulkIndexerFlushSuccess(t *testing.T) {
-	// Create a channel to capture the request payload.
-	payloadCh := make(chan []byte, 1)
-
 	// Create a test server that always returns a 200 OK.
-	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		body, err := io.ReadAll(r.Body)
-		if err != nil {
-			t.Errorf("Failed to read request body: %v", err)
-		}
-		payloadCh <- body
-		w.WriteHeader(http.StatusOK)
-	}))
+	testServer := testutil.NewEsMockServer()
 	defer testServer.Close()
 
-	// Create a BulkIndexer with a threshold of 2 docs and a long flush interval (so flush is triggered by threshold).
-	threshold := 2
-	flushIntervalSeconds := 60  // long enough so that flush comes only from threshold
+	// Create a BulkIndexer with a high threshold and a long flush interval (so flush comes only from ForceFlush).
+	threshold := 10
+	flushIntervalSeconds := 60  // long enough so that no timed flush occurs
 	maxRetries := 0             // no retries needed
 	indexName := "test_index"
-	indexer := NewBulkIndexer(threshold, testServer.URL, indexName, flushIntervalSeconds, maxRetries)
+	indexer, err := NewBulkIndexer(context.Background(), threshold, testServer.URL, indexName, flushIntervalSeconds, maxRetries)
+	if err != nil {
+		t.Fatalf("Failed to create BulkIndexer: %v", err)
+	}
 	defer indexer.Stop()
 
 	// Create two dummy documents.
@@ -62,60 +63,63 @@ func TestBulkIndexerFlushSuccess(t *testing.T) {
 	indexer.AddDocumentToIndexerPayload(doc1)
 	indexer.AddDocumentToIndexerPayload(doc2)
 
-	// Wait for the flush to occur.
-	select {
-	case payload := <-payloadCh:
-		// The NDJSON payload should consist of 2 documents, each with a meta line and a doc line.
-		// We'll split the payload by newline.
-		scanner := bufio.NewScanner(bytes.NewReader(payload))
-		var lines []string
-		for scanner.Scan() {
-			line := scanner.Text()
-			if strings.TrimSpace(line) != "" {
-				lines = append(lines, line)
-			}
-		}
-		expectedLines := threshold * 2
-		if len(lines) != expectedLines {
-			t.Errorf("Expected %d NDJSON lines (2 per document), got %d", expectedLines, len(lines))
-		}
+	// Flush and wait for the request to complete.
+	indexer.ForceFlush()
+	payloads := testServer.Payloads()
+	if len(payloads) != 1 {
+		t.Fatalf("Expected one flush payload once ForceFlush returned, got %d", len(payloads))
+	}
 
-		// Optionally, decode and verify one meta line.
-		var meta map[string]map[string]string
-		if err := json.Unmarshal([]byte(lines[0]), &meta); err != nil {
-			t.Errorf("Failed to unmarshal meta line: %v", err)
+	// The NDJSON payload should consist of 2 documents, each with a meta line and a doc line.
+	// We'll split the payload by newline.
+	scanner := bufio.NewScanner(bytes.NewReader(payloads[0]))
+	var lines []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
 		}
-		if meta["index"]["_index"] != indexName {
-			t.Errorf("Expected _index to be %q, got %q", indexName, meta["index"]["_index"])
-		}
-	case <-time.After(3 * time.Second):
-		t.Error("Timed out waiting for flush payload")
+	}
+	expectedLines := 2 * 2
+	if len(lines) != expectedLines {
+		t.Fatalf("Expected %d NDJSON lines (2 per document), got %d", expectedLines, len(lines))
+	}
+
+	// Optionally, decode and verify one meta line.
+	var meta map[string]map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &meta); err != nil {
+		t.Errorf("Failed to unmarshal meta line: %v", err)
+	}
+	if meta["index"]["_index"] != indexName {
+		t.Errorf("Expected _index to be %q, got %q", indexName, meta["index"]["_index"])
+	}
+
+	var indexed models.Document
+	if err := json.Unmarshal([]byte(lines[1]), &indexed); err != nil {
+		t.Fatalf("Failed to unmarshal document line: %v", err)
+	}
+	if indexed.URL != doc1.URL {
+		t.Errorf("Expected the document source of %s, got %s", doc1.URL, lines[1])
 	}
 }
 
 // Verifies that the retry mechanism is exercised when the simulated
 // Elasticsearch endpoint returns error codes.
 func TestBulkIndexerRetry(t *testing.T) {
-	var attemptCount int32 // use atomic counter
-
 	// Create a test server that returns HTTP 500 for the first two attempts,
 	// then returns HTTP 200.
-	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		count := atomic.AddInt32(&attemptCount, 1)
-		if count < 3 {
-			w.WriteHeader(http.StatusInternalServerError)
-		} else {
-			w.WriteHeader(http.StatusOK)
-		}
-	}))
+	testServer := testutil.NewEsMockServer(http.StatusInternalServerError, http.StatusInternalServerError, http.StatusOK)
 	defer testServer.Close()
 
-	// Use a threshold of 1 so that flush is triggered immediately.
-	threshold := 1
-	flushIntervalSeconds := 60 // long flush interval; threshold triggers flush
+	// Use a high threshold so the flush only comes from ForceFlush.
+	threshold := 10
+	flushIntervalSeconds := 60 // long flush interval
 	maxRetries := 3            // allow up to 3 attempts
 	indexName := "retry_index"
-	indexer := NewBulkIndexer(threshold, testServer.URL, indexName, flushIntervalSeconds, maxRetries)
+	indexer, err := NewBulkIndexer(context.Background(), threshold, testServer.URL, indexName, flushIntervalSeconds, maxRetries)
+	if err != nil {
+		t.Fatalf("Failed to create BulkIndexer: %v", err)
+	}
 	defer indexer.Stop()
 
 	// Create a dummy document.
@@ -124,15 +128,737 @@ func TestBulkIndexerRetry(t *testing.T) {
 		Title: "Retry Test",
 	}
 
-	// Add the document to trigger flush.
+	// Flush and wait for the retries to complete.
 	indexer.AddDocumentToIndexerPayload(doc)
+	indexer.ForceFlush()
+
+	// Verify that 3 attempts were made, each with the same payload.
+	payloads := testServer.Payloads()
+	if len(payloads) != 3 {
+		t.Fatalf("Expected 3 attempts, got %d", len(payloads))
+	}
+	if !bytes.Equal(payloads[0], payloads[2]) {
+		t.Error("Expected retries to resend the same payload")
+	}
+}
+
+// Verifies that the flush complete callback reports the document count and
+// error of every bulk request.
+func TestBulkIndexerOnFlushComplete(t *testing.T) {
+	testServer := testutil.NewEsMockServer(http.StatusOK, http.StatusInternalServerError)
+	defer testServer.Close()
+
+	type flushResult struct {
+		count int
+		err   error
+	}
+	var results []flushResult
+	indexer, err := NewBulkIndexer(context.Background(), 10, testServer.URL, "callback_index", 60, 0, WithOnFlushComplete(func(count int, err error) {
+		results = append(results, flushResult{count, err})
+	}))
+	if err != nil {
+		t.Fatalf("Failed to create BulkIndexer: %v", err)
+	}
+	defer indexer.Stop()
+
+	indexer.AddDocumentToIndexerPayload(&models.Document{URL: "http://example.com/a"})
+	indexer.AddDocumentToIndexerPayload(&models.Document{URL: "http://example.com/b"})
+	indexer.ForceFlush()
+	indexer.AddDocumentToIndexerPayload(&models.Document{URL: "http://example.com/c"})
+	indexer.ForceFlush()
+
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 callbacks, got %d", len(results))
+	}
+	if results[0].count != 2 || results[0].err != nil {
+		t.Errorf("Expected 2 documents without error, got %d and %v", results[0].count, results[0].err)
+	}
+	if results[1].count != 1 || results[1].err == nil {
+		t.Errorf("Expected 1 document with an error, got %d and %v", results[1].count, results[1].err)
+	}
+}
+
+// Verifies that a successful flush triggers the LinkCountAggregator hook,
+// which sends one scripted upsert per linked document.
+func TestLinkCountAggregatorPostFlush(t *testing.T) {
+	testServer := testutil.NewEsMockServer()
+	defer testServer.Close()
+
+	client := esclient.New(testServer.URL)
+	aggregator := NewLinkCountAggregator(client)
+	indexer, err := NewBulkIndexer(context.Background(), 2, testServer.URL, "links_index", 60, 0, WithClient(client), WithPostFlushHook(aggregator.Aggregate))
+	if err != nil {
+		t.Fatalf("Failed to create BulkIndexer: %v", err)
+	}
+	defer indexer.Stop()
+
+	indexer.AddDocumentToIndexerPayload(&models.Document{
+		URL:           "https://example.com/a",
+		InternalLinks: []string{"https://example.com/c", "https://example.com/a"},
+	})
+	indexer.AddDocumentToIndexerPayload(&models.Document{
+		URL:           "https://example.com/b",
+		InternalLinks: []string{"https://example.com/c", "https://example.com/c"},
+	})
+
+	// The first request is the bulk index, the second is the link count update.
+	requests := testServer.WaitForRequests(2, 3*time.Second)
+	if len(requests) != 2 {
+		t.Fatalf("Expected a bulk request and a link count update, got %d requests", len(requests))
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(requests[1].Body)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected a single update action (2 lines), got %d lines", len(lines))
+	}
+
+	var meta map[string]map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &meta); err != nil {
+		t.Fatalf("Failed to unmarshal meta line: %v", err)
+	}
+	if meta["update"]["_id"] != docid.Generate("https://example.com/c", "") || meta["update"]["_index"] != "links_index" {
+		t.Errorf("Unexpected update target %v in %v", meta["update"]["_id"], meta["update"]["_index"])
+	}
+
+	var update struct {
+		ScriptedUpsert bool                   `json:"scripted_upsert"`
+		Upsert         map[string]interface{} `json:"upsert"`
+		Script         struct {
+			Params map[string][]string `json:"params"`
+		} `json:"script"`
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &update); err != nil {
+		t.Fatalf("Failed to unmarshal update line: %v", err)
+	}
+	if !update.ScriptedUpsert || update.Upsert == nil {
+		t.Errorf("Expected a scripted upsert so missing targets are created, got %s", lines[1])
+	}
+	expected := []string{docid.Generate("https://example.com/a", ""), docid.Generate("https://example.com/b", "")}
+	if sources := update.Script.Params["sources"]; len(sources) != 2 || sources[0] != expected[0] || sources[1] != expected[1] {
+		t.Errorf("Expected inbound link sources %v, got %v", expected, sources)
+	}
+}
+
+// Verifies that links to a page indexed after the linking page are kept: the
+// link update creates a stub for the target, and the target is then written
+// as a scripted upsert that keeps the stub's inbound links.
+func TestLinkCountAggregatorLinkBeforeTarget(t *testing.T) {
+	testServer := testutil.NewEsMockServer()
+	defer testServer.Close()
+
+	client := esclient.New(testServer.URL)
+	aggregator := NewLinkCountAggregator(client)
+	indexer, err := NewBulkIndexer(context.Background(), 1, testServer.URL, "links_index", 60, 0,
+		WithClient(client), WithScriptedUpserts(true), WithPostFlushHook(aggregator.Aggregate))
+	if err != nil {
+		t.Fatalf("Failed to create BulkIndexer: %v", err)
+	}
+	defer indexer.Stop()
+
+	targetID := docid.Generate("https://example.com/target", "")
+	indexer.AddDocumentToIndexerPayload(&models.Document{
+		URL:           "https://example.com/linking",
+		InternalLinks: []string{"https://example.com/target"},
+	})
+	// The linking page, then the link update for the target
+	if requests := testServer.WaitForRequests(2, 3*time.Second); len(requests) != 2 {
+		t.Fatalf("Expected a bulk request and a link count update, got %d requests", len(requests))
+	}
+
+	indexer.AddDocumentToIndexerPayload(&models.Document{URL: "https://example.com/target"})
+	requests := testServer.WaitForRequests(3, 3*time.Second)
+	if len(requests) != 3 {
+		t.Fatalf("Expected the target to be flushed, got %d requests", len(requests))
+	}
+
+	type bulkAction struct {
+		Update struct {
+			ID string `json:"_id"`
+		} `json:"update"`
+	}
+	type bulkUpsert struct {
+		ScriptedUpsert bool                   `json:"scripted_upsert"`
+		Upsert         map[string]interface{} `json:"upsert"`
+		Script         struct {
+			Source string `json:"source"`
+		} `json:"script"`
+	}
+	for i, expectedScript := range map[int]string{1: inboundLinkScript, 2: upsertDocumentScript} {
+		lines := strings.Split(strings.TrimSpace(string(requests[i].Body)), "\n")
+		if len(lines) != 2 {
+			t.Fatalf("Expected a single action in request %d, got %d lines", i, len(lines))
+		}
+		var action bulkAction
+		var upsert bulkUpsert
+		if err := json.Unmarshal([]byte(lines[0]), &action); err != nil {
+			t.Fatalf("Failed to unmarshal meta line: %v", err)
+		}
+		if err := json.Unmarshal([]byte(lines[1]), &upsert); err != nil {
+			t.Fatalf("Failed to unmarshal upsert line: %v", err)
+		}
+		if action.Update.ID != targetID {
+			t.Errorf("Expected request %d to update %s, got %s", i, targetID, lines[0])
+		}
+		if !upsert.ScriptedUpsert || upsert.Upsert == nil || upsert.Script.Source != expectedScript {
+			t.Errorf("Expected request %d to be a scripted upsert, got %s", i, lines[1])
+		}
+	}
+}
+
+// Verifies that link count updates carry the configured basic auth credentials.
+func TestLinkCountAggregatorBasicAuth(t *testing.T) {
+	testServer := testutil.NewEsMockServer()
+	defer testServer.Close()
+
+	aggregator := NewLinkCountAggregator(esclient.New(testServer.URL, esclient.WithBasicAuth("elastic", "secret")))
+	aggregator.Aggregate("links_index", []*models.Document{{
+		URL:           "https://example.com/a",
+		InternalLinks: []string{"https://example.com/b"},
+	}})
+
+	requests := testServer.Requests()
+	if len(requests) != 1 {
+		t.Fatalf("Expected a link count update request, got %d requests", len(requests))
+	}
+	request := &http.Request{Header: requests[0].Header}
+	if username, password, _ := request.BasicAuth(); username != "elastic" || password != "secret" {
+		t.Errorf("Expected basic auth elastic:secret, got %q:%q", username, password)
+	}
+}
+
+// Verifies that EnsureIndex creates a missing index with the supplied mapping
+// and leaves an existing index untouched.
+func TestBulkIndexerEnsureIndex(t *testing.T) {
+	var exists int32
+	var putCount int32
+	putBody := make(chan []byte, 1)
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/mapped_index" {
+			t.Errorf("Unexpected path %q", r.URL.Path)
+		}
+		switch r.Method {
+		case http.MethodHead:
+			if atomic.LoadInt32(&exists) == 1 {
+				w.WriteHeader(http.StatusOK)
+			} else {
+				w.WriteHeader(http.StatusNotFound)
+			}
+		case http.MethodPut:
+			atomic.AddInt32(&putCount, 1)
+			body, _ := io.ReadAll(r.Body)
+			putBody <- body
+			atomic.StoreInt32(&exists, 1)
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer testServer.Close()
+
+	indexer, err := NewBulkIndexer(context.Background(), 1, testServer.URL+"/_bulk", "mapped_index", 60, 0)
+	if err != nil {
+		t.Fatalf("Failed to create BulkIndexer: %v", err)
+	}
+	defer indexer.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	if err := indexer.EnsureIndex(ctx, json.RawMessage(DefaultIndexMapping)); err != nil {
+		t.Fatalf("Expected no error creating index, got %v", err)
+	}
+	if body := <-putBody; !json.Valid(body) {
+		t.Errorf("Expected a valid JSON mapping, got %s", body)
+	}
+
+	if err := indexer.EnsureIndex(ctx, json.RawMessage(DefaultIndexMapping)); err != nil {
+		t.Fatalf("Expected no error for existing index, got %v", err)
+	}
+	if atomic.LoadInt32(&putCount) != 1 {
+		t.Errorf("Expected the index to be created once, got %d creations", putCount)
+	}
+}
+
+// Verifies that the BulkIndexer fails over to a fallback endpoint when the
+// primary keeps failing, and keeps using it for subsequent flushes.
+func TestBulkIndexerFailover(t *testing.T) {
+	primary := testutil.NewEsMockServer(http.StatusServiceUnavailable)
+	defer primary.Close()
+	fallback := testutil.NewEsMockServer()
+	defer fallback.Close()
+
+	indexer, err := NewBulkIndexer(context.Background(), 1, primary.URL, "failover_index", 60, 0, WithFallbackURLs([]string{fallback.URL}))
+	if err != nil {
+		t.Fatalf("Failed to create BulkIndexer: %v", err)
+	}
+	defer indexer.Stop()
+
+	for i := 0; i < 2; i++ {
+		indexer.AddDocumentToIndexerPayload(&models.Document{URL: "http://example.com/failover"})
+		if got := len(fallback.WaitForRequests(i+1, 3*time.Second)); got != i+1 {
+			t.Fatalf("Timed out waiting for fallback flush %d", i+1)
+		}
+
+		// The active endpoint is switched once the fallback response is handled.
+		deadline := time.Now().Add(time.Second)
+		for indexer.client.ActiveURL() != fallback.URL && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	if got := len(primary.Payloads()); got != 1 {
+		t.Errorf("Expected the primary to be tried once before failing over, got %d", got)
+	}
+	if got := len(fallback.Payloads()); got != 2 {
+		t.Errorf("Expected 2 requests to the fallback, got %d", got)
+	}
+}
+
+// Verifies that the indexer reports itself unhealthy after a failed bulk
+// request until a later request succeeds or the cooldown passes.
+func TestBulkIndexerIsHealthy(t *testing.T) {
+	testServer := testutil.NewEsMockServer(http.StatusServiceUnavailable)
+	defer testServer.Close()
+
+	indexer, err := NewBulkIndexer(context.Background(), 10, testServer.URL, "health_index", 60, 0)
+	if err != nil {
+		t.Fatalf("Failed to create BulkIndexer: %v", err)
+	}
+	defer indexer.Stop()
+
+	if !indexer.IsHealthy() {
+		t.Fatal("Expected a new indexer to be healthy")
+	}
+
+	indexer.AddDocumentToIndexerPayload(&models.Document{URL: "http://example.com/fail"})
+	indexer.ForceFlush()
+	if indexer.IsHealthy() {
+		t.Fatal("Expected the indexer to be unhealthy after a failed flush")
+	}
+
+	testServer.SetStatusCodes(http.StatusOK)
+	indexer.AddDocumentToIndexerPayload(&models.Document{URL: "http://example.com/ok"})
+	indexer.ForceFlush()
+	if !indexer.IsHealthy() {
+		t.Error("Expected the indexer to be healthy after a successful flush")
+	}
+
+	defer func(cooldown time.Duration) { unhealthyCooldown = cooldown }(unhealthyCooldown)
+	unhealthyCooldown = 10 * time.Millisecond
+	testServer.SetStatusCodes(http.StatusServiceUnavailable)
+	indexer.AddDocumentToIndexerPayload(&models.Document{URL: "http://example.com/fail-again"})
+	indexer.ForceFlush()
+	time.Sleep(20 * time.Millisecond)
+	if !indexer.IsHealthy() {
+		t.Error("Expected the indexer to be healthy again once the cooldown passed")
+	}
+}
+
+// Verifies that configured credentials are sent as a Basic Authentication
+// header on index checks and bulk requests.
+func TestBulkIndexerBasicAuth(t *testing.T) {
+	testServer := testutil.NewEsMockServer()
+	defer testServer.Close()
+
+	indexer, err := NewBulkIndexer(context.Background(), 1, testServer.URL+"/_bulk", "auth_index", 60, 0, WithBasicAuth("elastic", "secret"))
+	if err != nil {
+		t.Fatalf("Failed to create BulkIndexer: %v", err)
+	}
+	defer indexer.Stop()
+
+	if err := indexer.EnsureIndex(context.Background(), json.RawMessage(DefaultIndexMapping)); err != nil {
+		t.Fatalf("Expected EnsureIndex to succeed, got %v", err)
+	}
+	indexer.AddDocumentToIndexerPayload(&models.Document{URL: "http://example.com/auth"})
+
+	// The index check, then the bulk request
+	requests := testServer.WaitForRequests(2, 3*time.Second)
+	if len(requests) != 2 || requests[1].Method != http.MethodPost {
+		t.Fatalf("Expected an index check and a bulk request, got %d requests", len(requests))
+	}
+	for _, recorded := range requests {
+		request := &http.Request{Header: recorded.Header}
+		if username, password, ok := request.BasicAuth(); !ok || username != "elastic" || password != "secret" {
+			t.Errorf("Expected %s %s to carry credentials, got %q:%q", recorded.Method, recorded.Path, username, password)
+		}
+	}
+}
+
+// Verifies that no Authorization header is sent unless both the username
+// and password are set.
+func TestBulkIndexerBasicAuthIncomplete(t *testing.T) {
+	testServer := testutil.NewEsMockServer()
+	defer testServer.Close()
+
+	indexer, err := NewBulkIndexer(context.Background(), 1, testServer.URL, "auth_index", 60, 0, WithBasicAuth("elastic", ""))
+	if err != nil {
+		t.Fatalf("Failed to create BulkIndexer: %v", err)
+	}
+	defer indexer.Stop()
+
+	indexer.AddDocumentToIndexerPayload(&models.Document{URL: "http://example.com/no-auth"})
+	requests := testServer.WaitForRequests(1, 3*time.Second)
+	if len(requests) != 1 {
+		t.Fatal("Timed out waiting for bulk request")
+	}
+	if header := requests[0].Header.Get("Authorization"); header != "" {
+		t.Errorf("Expected no Authorization header, got %q", header)
+	}
+}
+
+// Verifies that a dry-run indexer never contacts Elasticsearch.
+func TestBulkIndexerDryRun(t *testing.T) {
+	testServer := testutil.NewEsMockServer()
+	defer testServer.Close()
+
+	indexer, err := NewBulkIndexer(context.Background(), 1, testServer.URL, "dry_run_index", 60, 0, WithDryRun(true))
+	if err != nil {
+		t.Fatalf("Failed to create BulkIndexer: %v", err)
+	}
+
+	if err := indexer.EnsureIndex(context.Background(), json.RawMessage(DefaultIndexMapping)); err != nil {
+		t.Errorf("Expected no error from EnsureIndex in dry-run mode, got %v", err)
+	}
+	indexer.AddDocumentToIndexerPayload(&models.Document{URL: "http://example.com/dry-run"})
+	time.Sleep(200 * time.Millisecond)
+	indexer.Stop()
+
+	if got := len(testServer.Requests()); got != 0 {
+		t.Errorf("Expected no requests in dry-run mode, got %d", got)
+	}
+}
+
+// Verifies that NewBulkIndexer rejects invalid parameters.
+func TestNewBulkIndexerValidation(t *testing.T) {
+	tests := []struct {
+		name          string
+		threshold     int
+		elasticURL    string
+		flushInterval int
+	}{
+		{"zero threshold", 0, "http://localhost:9200/_bulk", 30},
+		{"empty URL", 10, "", 30},
+		{"relative URL", 10, "localhost:9200", 30},
+		{"unparseable URL", 10, "http://[::1", 30},
+		{"zero flush interval", 10, "http://localhost:9200/_bulk", 0},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			indexer, err := NewBulkIndexer(context.Background(), tc.threshold, tc.elasticURL, "test_index", tc.flushInterval, 0)
+			if err == nil {
+				indexer.Stop()
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+
+	if indexer, err := NewBulkIndexer(context.Background(), 1, "http://localhost:9200/_bulk", "test_index", 1, 0, WithMaxConcurrentFlushes(0)); err == nil {
+		indexer.Stop()
+		t.Error("Expected error for zero max concurrent flushes, got nil")
+	}
 
-	// Wait enough time for the retries to complete.
-	time.Sleep(5 * time.Second)
+	indexer, err := NewBulkIndexer(context.Background(), 1, "http://localhost:9200/_bulk", "test_index", 1, 0)
+	if err != nil {
+		t.Fatalf("Expected valid parameters to be accepted, got %v", err)
+	}
+	indexer.Stop()
+}
+
+// Verifies that no more bulk requests than the configured limit are in flight.
+func TestBulkIndexerMaxConcurrentFlushes(t *testing.T) {
+	testServer := testutil.NewEsMockServer()
+	defer testServer.Close()
+	testServer.SetDelay(50 * time.Millisecond)
+
+	// Every document goes to its own index, so one flush sends several requests
+	router := func(doc *models.Document) string {
+		return doc.Title
+	}
+	indexer, err := NewBulkIndexer(context.Background(), 10, testServer.URL, "test_index", 60, 0,
+		WithIndexRouter(router), WithMaxConcurrentFlushes(2))
+	if err != nil {
+		t.Fatalf("Failed to create BulkIndexer: %v", err)
+	}
+	defer indexer.Stop()
+
+	for i := 0; i < 6; i++ {
+		indexer.AddDocumentToIndexerPayload(&models.Document{URL: fmt.Sprintf("https://example.com/%d", i), Title: fmt.Sprintf("index_%d", i)})
+	}
+	indexer.ForceFlush()
+
+	if got := len(testServer.Requests()); got != 6 {
+		t.Errorf("Expected 6 bulk requests, got %d", got)
+	}
+	if got := testServer.MaxInFlight(); got != 2 {
+		t.Errorf("Expected at most 2 bulk requests in flight, got %d", got)
+	}
+}
+
+// Verifies that routed documents are flushed per index using that index's threshold.
+func TestBulkIndexerIndexThresholds(t *testing.T) {
+	testServer := testutil.NewEsMockServer()
+	defer testServer.Close()
+
+	router := func(doc *models.Document) string {
+		if doc.QualityScore >= 80 {
+			return "priority_index"
+		}
+		return ""
+	}
+	indexer, err := NewBulkIndexer(context.Background(), 3, testServer.URL, "default_index", 60, 0,
+		WithIndexRouter(router), WithIndexThreshold("priority_index", 1))
+	if err != nil {
+		t.Fatalf("Failed to create BulkIndexer: %v", err)
+	}
+	defer indexer.Stop()
+
+	indexer.AddDocumentToIndexerPayload(&models.Document{URL: "https://example.com/low1", QualityScore: 10})
+	indexer.AddDocumentToIndexerPayload(&models.Document{URL: "https://example.com/high", QualityScore: 90})
 
-	// Verify that at least 3 attempts were made.
-	if atomic.LoadInt32(&attemptCount) < 3 {
-		t.Errorf("Expected at least 3 attempts, got %d", attemptCount)
+	// Only the priority index has reached its threshold
+	requests := testServer.WaitForRequests(1, 3*time.Second)
+	if len(requests) != 1 {
+		t.Fatal("Timed out waiting for priority flush")
+	}
+	if payload := string(requests[0].Body); !strings.Contains(payload, `"_index":"priority_index"`) || strings.Contains(payload, "default_index") {
+		t.Errorf("Expected a priority_index-only payload, got %s", payload)
+	}
+
+	indexer.AddDocumentToIndexerPayload(&models.Document{URL: "https://example.com/low2", QualityScore: 10})
+	indexer.ForceFlush()
+	payloads := testServer.Payloads()
+	if len(payloads) != 2 {
+		t.Fatalf("Expected default index payload once ForceFlush returned, got %d requests", len(payloads))
+	}
+	if strings.Count(string(payloads[1]), `"_index":"default_index"`) != 2 {
+		t.Errorf("Expected both default documents in one payload, got %s", payloads[1])
+	}
+
+	if _, err := NewBulkIndexer(context.Background(), 3, testServer.URL, "default_index", 60, 0, WithIndexThreshold("bad_index", 0)); err == nil {
+		t.Error("Expected error for invalid per-index threshold, got nil")
 	}
 }
 
+// Verifies that the reason is extracted from Elasticsearch error bodies.
+func TestErrorReason(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{"object error", `{"error":{"type":"mapper_parsing_exception","reason":"failed to parse field [load_time]"},"status":400}`, "failed to parse field [load_time]"},
+		{"string error", `{"error":"Incorrect HTTP method","status":405}`, "Incorrect HTTP method"},
+		{"plain text", "Bad Gateway\n", "Bad Gateway"},
+		{"empty body", "", ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if reason := errorReason(strings.NewReader(tc.body)); reason != tc.expected {
+				t.Errorf("Expected reason %q, got %q", tc.expected, reason)
+			}
+		})
+	}
+
+	long := strings.Repeat("x", 2*maxErrorBodyBytes)
+	if reason := errorReason(strings.NewReader(long)); len(reason) != maxErrorBodyBytes {
+		t.Errorf("Expected body to be capped at %d bytes, got %d", maxErrorBodyBytes, len(reason))
+	}
+}
+
+// Measures the allocations of building and sending a bulk payload, which
+// reuses pooled buffers across flushes.
+func BenchmarkBulkIndexerFlush(b *testing.B) {
+	testServer := testutil.NewEsMockServer()
+	defer testServer.Close()
+
+	indexer, err := NewBulkIndexer(context.Background(), 1000, testServer.URL, "bench_index", 60, 0)
+	if err != nil {
+		b.Fatalf("Failed to create BulkIndexer: %v", err)
+	}
+	defer indexer.Stop()
+
+	docs := make([]*models.Document, 100)
+	for i := range docs {
+		docs[i] = &models.Document{
+			URL:         fmt.Sprintf("https://example.com/page/%d", i),
+			Title:       "Benchmark page",
+			VisibleText: strings.Repeat("benchmark text ", 200),
+		}
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		<-indexer.flushIndex("bench_index", docs)
+		testServer.Reset() // keep the recorded payloads from piling up
+	}
+}
+
+// Verifies that flushes report how long the oldest document was buffered.
+func TestBulkIndexerOldestDocumentAge(t *testing.T) {
+	indexer, err := NewBulkIndexer(context.Background(), 100, "http://localhost:9200/_bulk", "test_index", 60, 0, WithDryRun(true))
+	if err != nil {
+		t.Fatalf("Failed to create BulkIndexer: %v", err)
+	}
+	defer indexer.Stop()
+
+	gaugeValue := func() float64 {
+		var metric dto.Metric
+		if err := metrics.OldestDocumentAge.Write(&metric); err != nil {
+			t.Fatalf("Failed to read gauge: %v", err)
+		}
+		return metric.GetGauge().GetValue()
+	}
+
+	indexer.AddDocumentToIndexerPayload(&models.Document{URL: "https://example.com/old"})
+	time.Sleep(50 * time.Millisecond)
+	indexer.AddDocumentToIndexerPayload(&models.Document{URL: "https://example.com/new"})
+	indexer.ForceFlush()
+
+	if age := gaugeValue(); age < 0.05 || age > 5 {
+		t.Errorf("Expected the oldest document age to be at least 50ms, got %vs", age)
+	}
+
+	indexer.ForceFlush()
+	if age := gaugeValue(); age != 0 {
+		t.Errorf("Expected age 0 with an empty buffer, got %vs", age)
+	}
+}
+
+// Verifies that the size of each bulk request payload is recorded.
+func TestBulkIndexerRequestSize(t *testing.T) {
+	testServer := testutil.NewEsMockServer()
+	defer testServer.Close()
+
+	indexer, err := NewBulkIndexer(context.Background(), 10, testServer.URL, "test_index", 60, 0)
+	if err != nil {
+		t.Fatalf("Failed to create BulkIndexer: %v", err)
+	}
+	defer indexer.Stop()
+
+	histogram := func() *dto.Histogram {
+		var metric dto.Metric
+		if err := metrics.BulkRequestSizeBytes.Write(&metric); err != nil {
+			t.Fatalf("Failed to read histogram: %v", err)
+		}
+		return metric.GetHistogram()
+	}
+	before := histogram()
+
+	indexer.AddDocumentToIndexerPayload(&models.Document{URL: "https://example.com/page", Title: "Page"})
+	indexer.ForceFlush()
+
+	payloads := testServer.Payloads()
+	if len(payloads) != 1 {
+		t.Fatalf("Expected 1 bulk request, got %d", len(payloads))
+	}
+	after := histogram()
+	if count := after.GetSampleCount() - before.GetSampleCount(); count != 1 {
+		t.Errorf("Expected 1 observation, got %d", count)
+	}
+	if size := after.GetSampleSum() - before.GetSampleSum(); size != float64(len(payloads[0])) {
+		t.Errorf("Expected observed size %d, got %v", len(payloads[0]), size)
+	}
+}
+
+// Verifies that cancelling the indexer's context flushes buffered documents,
+// and that Stop still sends documents added afterwards.
+func TestBulkIndexerContextCancel(t *testing.T) {
+	testServer := testutil.NewEsMockServer()
+	defer testServer.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	indexer, err := NewBulkIndexer(ctx, 10, testServer.URL, "test_index", 60, 0)
+	if err != nil {
+		t.Fatalf("Failed to create BulkIndexer: %v", err)
+	}
+
+	indexer.AddDocumentToIndexerPayload(&models.Document{URL: "https://example.com/page"})
+
+	deadline := time.Now().Add(3 * time.Second)
+	for len(testServer.Payloads()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := len(testServer.Payloads()); got != 1 {
+		t.Fatalf("Expected buffered document to be flushed on cancellation, got %d requests", got)
+	}
+
+	// Documents added after cancellation are left to Stop
+	indexer.AddDocumentToIndexerPayload(&models.Document{URL: "https://example.com/late"})
+	indexer.Stop()
+	payloads := testServer.Payloads()
+	if len(payloads) != 2 || !strings.Contains(string(payloads[1]), "https://example.com/late") {
+		t.Errorf("Expected Stop to flush the document added after cancellation, got %d requests", len(payloads))
+	}
+}
+
+// Transport that answers at once and only finishes reading and closes the
+// request body later, as net/http does when a server rejects an upload early.
+type lateBodyCloseTransport struct {
+	bodyClosed atomic.Bool
+}
+
+func (transport *lateBodyCloseTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		io.Copy(io.Discard, request.Body)
+		transport.bodyClosed.Store(true)
+		request.Body.Close()
+	}()
+	return &http.Response{
+		StatusCode: http.StatusRequestEntityTooLarge,
+		Body:       io.NopCloser(strings.NewReader("")),
+		Request:    request,
+	}, nil
+}
+
+// Verifies that a bulk request only returns, letting its pooled payload be
+// reused, once the transport has finished with the request body.
+func TestBulkIndexerWaitsForRequestBody(t *testing.T) {
+	transport := &lateBodyCloseTransport{}
+	client := esclient.New("http://localhost:9200/_bulk", esclient.WithHTTPClient(&http.Client{Transport: transport}))
+	indexer, err := NewBulkIndexer(context.Background(), 10, "http://localhost:9200/_bulk", "test_index", 60, 0, WithClient(client))
+	if err != nil {
+		t.Fatalf("Failed to create BulkIndexer: %v", err)
+	}
+	defer indexer.Stop()
+
+	if err := indexer.sendBulkRequest([]byte("{}\n")); err == nil {
+		t.Error("Expected the rejected request to fail")
+	}
+	if !transport.bodyClosed.Load() {
+		t.Error("Expected sendBulkRequest to wait for the transport to close the body")
+	}
+}
+
+// Compares building bulk payloads in pooled buffers, as flushIndex does,
+// against a fresh buffer per flush.
+func BenchmarkBulkPayload(b *testing.B) {
+	docs := make([]*models.Document, 100)
+	for i := range docs {
+		docs[i] = &models.Document{
+			URL:         fmt.Sprintf("https://example.com/page%d", i),
+			Title:       "Benchmark page",
+			VisibleText: strings.Repeat("benchmark text ", 200),
+		}
+	}
+
+	b.Run("pooled", func(b *testing.B) {
+		pool := sync.Pool{New: func() interface{} { return new(bytes.Buffer) }}
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			payload := pool.Get().(*bytes.Buffer)
+			payload.Reset()
+			writeBulkPayload(payload, "bench_index", docs, false)
+			pool.Put(payload)
+		}
+	})
+	b.Run("unpooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			writeBulkPayload(new(bytes.Buffer), "bench_index", docs, false)
+		}
+	})
+}
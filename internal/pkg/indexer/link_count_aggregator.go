@@ -0,0 +1,153 @@
+package indexer
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "time"
+    "go.uber.org/zap"
+    "indexer/internal/pkg/docid"
+    "indexer/internal/pkg/esclient"
+    "indexer/internal/pkg/logger"
+    "indexer/internal/pkg/metrics"
+    "indexer/internal/pkg/models"
+)
+
+// Painless script that records the IDs of documents linking to a document
+// in inbound_link_sources and sets inbound_link_count to how many there are.
+// Sources already recorded are skipped, so re-crawling a linking page
+// doesn't count its links twice.
+const inboundLinkScript = "if (ctx._source.inbound_link_sources == null) { ctx._source.inbound_link_sources = new ArrayList() } " +
+    "boolean changed = false; " +
+    "for (String source : params.sources) { " +
+    "if (!ctx._source.inbound_link_sources.contains(source)) { ctx._source.inbound_link_sources.add(source); changed = true } } " +
+    "if (changed) { ctx._source.inbound_link_count = ctx._source.inbound_link_sources.size() } else { ctx.op = 'noop' }"
+
+// Maintains inbound_link_count on documents referenced by the
+// internal_links of freshly indexed documents, counting each linking
+// document once. Links a page drops on a later crawl are not removed.
+//
+// A linked page that is not indexed yet gets a stub document holding only
+// the inbound link fields, which the page fills in once it arrives. The
+// BulkIndexer must write with WithScriptedUpserts for the links to survive
+// that, and the cluster must allow inline Painless scripts.
+//
+// Linked documents are looked up in the index the linking documents were
+// written to. Internal links stay on the same host, so this holds as long
+// as documents are routed by domain. They are also looked up by the linked
+// URL alone, so links to a page indexed under a different canonical URL
+// are counted on a stub that page never replaces.
+type LinkCountAggregator struct {
+    client  *esclient.Client
+    timeout time.Duration
+}
+
+// Creates a new LinkCountAggregator that sends updates through client.
+func NewLinkCountAggregator(client *esclient.Client) *LinkCountAggregator {
+    return &LinkCountAggregator{
+        client:  client,
+        timeout: 10 * time.Second,
+    }
+}
+
+// Collects the internal links across docs and sends one scripted upsert per
+// linked document. Intended for use as a PostFlushHook.
+func (aggregator *LinkCountAggregator) Aggregate(index string, docs []*models.Document) {
+    sources := inboundLinkSources(docs)
+    if len(sources) == 0 {
+        return
+    }
+
+    payload, err := buildLinkUpdatePayload(index, sources)
+    if err != nil {
+        logger.Log.Error("Failed to build inbound link update payload", zap.Error(err))
+        metrics.LinkCountUpdateFailures.Inc()
+        return
+    }
+
+    if err := aggregator.send(payload); err != nil {
+        logger.Log.Warn("Inbound link count update failed", zap.Error(err))
+        metrics.LinkCountUpdateFailures.Inc()
+        return
+    }
+
+    logger.Log.Debug("Updated inbound link counts", zap.Int("documents", len(sources)))
+}
+
+// Maps each linked URL to the IDs of the documents linking to it,
+// ignoring self-links.
+func inboundLinkSources(docs []*models.Document) map[string][]string {
+    sources := make(map[string][]string)
+    for _, doc := range docs {
+        sourceID := docid.Generate(doc.URL, doc.CanonicalURL)
+        seen := make(map[string]struct{})
+        for _, link := range doc.InternalLinks {
+            if link == "" || link == doc.URL {
+                continue
+            }
+            if _, dup := seen[link]; dup {
+                continue
+            }
+            seen[link] = struct{}{}
+            sources[link] = append(sources[link], sourceID)
+        }
+    }
+    return sources
+}
+
+// Builds an NDJSON payload of scripted upserts against index. Linked pages
+// that are not indexed yet are created as stubs, so their links are kept.
+func buildLinkUpdatePayload(index string, sources map[string][]string) ([]byte, error) {
+    var payload bytes.Buffer
+    for link, linkSources := range sources {
+        meta := map[string]map[string]interface{}{
+            "update": {
+                "_index":            index,
+                "_id":               docid.Generate(link, ""),
+                "retry_on_conflict": updateRetriesOnConflict,
+            },
+        }
+        update := map[string]interface{}{
+            "scripted_upsert": true,
+            "script": map[string]interface{}{
+                "source": inboundLinkScript,
+                "lang":   "painless",
+                "params": map[string][]string{"sources": linkSources},
+            },
+            "upsert": struct{}{},
+        }
+
+        metaLine, err := json.Marshal(meta)
+        if err != nil {
+            return nil, err
+        }
+        updateLine, err := json.Marshal(update)
+        if err != nil {
+            return nil, err
+        }
+        payload.Write(metaLine)
+        payload.WriteByte('\n')
+        payload.Write(updateLine)
+        payload.WriteByte('\n')
+    }
+    return payload.Bytes(), nil
+}
+
+// POSTs the update payload to the bulk endpoint.
+func (aggregator *LinkCountAggregator) send(payload []byte) error {
+    ctx, cancel := context.WithTimeout(context.Background(), aggregator.timeout)
+    defer cancel()
+
+    response, err := aggregator.client.Do(ctx, http.MethodPost, "/_bulk", "application/x-ndjson", payload)
+    if err != nil {
+        return err
+    }
+    defer response.Body.Close()
+
+    if response.StatusCode < 200 || response.StatusCode >= 300 {
+        return fmt.Errorf("bulk update returned status: %d", response.StatusCode)
+    }
+    return nil
+}
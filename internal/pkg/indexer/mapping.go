@@ -0,0 +1,59 @@
+package indexer
+
+// Default Elasticsearch mapping for models.Document, used when the
+// index does not exist yet.
+const DefaultIndexMapping = `{
+  "mappings": {
+    "properties": {
+      "url":                { "type": "keyword" },
+      "canonical_url":      { "type": "keyword" },
+      "title":              { "type": "text" },
+      "meta_description":   { "type": "text" },
+      "meta_keywords":      { "type": "text" },
+      "visible_text":       { "type": "text" },
+      "word_count":         { "type": "integer" },
+      "entities":           { "type": "keyword" },
+      "keywords":           { "type": "keyword" },
+      "summary":            { "type": "text" },
+      "language":           { "type": "keyword" },
+      "internal_links":     { "type": "keyword" },
+      "external_links":     { "type": "keyword" },
+      "anchor_texts":       { "type": "text" },
+      "internal_link_count": { "type": "integer" },
+      "outbound_link_count": { "type": "integer" },
+      "h1_count":           { "type": "integer" },
+      "h2_count":           { "type": "integer" },
+      "h3_count":           { "type": "integer" },
+      "alt_text_count":     { "type": "integer" },
+      "has_alt_texts_coverage": { "type": "boolean" },
+      "structured_data": {
+        "properties": {
+          "@context":       { "type": "keyword" },
+          "@type":          { "type": "keyword" }
+        }
+      },
+      "open_graph": {
+        "properties": {
+          "og:title":       { "type": "text" },
+          "og:description": { "type": "text" },
+          "og:image":       { "type": "keyword" }
+        }
+      },
+      "date_published":     { "type": "date" },
+      "date_modified":      { "type": "date" },
+      "categories":         { "type": "keyword" },
+      "tags":               { "type": "keyword" },
+      "social_links":       { "type": "keyword" },
+      "load_time":          { "type": "long" },
+      "is_secure":          { "type": "boolean" },
+      "quality_score":      { "type": "integer" },
+      "spam_score":         { "type": "integer" },
+      "spam_signals":       { "type": "keyword" },
+      "inbound_link_count": { "type": "integer" },
+      "inbound_link_sources": { "type": "keyword" },
+      "fetch_error":        { "type": "text" },
+      "crawled_at":         { "type": "date" },
+      "indexed_at":         { "type": "date" }
+    }
+  }
+}`
@@ -3,14 +3,42 @@ package logger
 import (
     "go.uber.org/zap"
     "go.uber.org/zap/zapcore"
+    "gopkg.in/natefinch/lumberjack.v2"
     "strings"
 )
 
 // Global logger instance
 var Log *zap.Logger
 
-// Sets up a global Zap logger with the given log level.
-func InitLogger(logLevel string) error {
+// Configures optional logger behaviour
+type Option func(*options)
+
+type options struct {
+    file *lumberjack.Logger
+}
+
+// Also writes logs to the file at path, rotating it once it reaches
+// maxSizeMB. At most maxBackups rotated files are kept, for up to
+// maxAgeDays; zero keeps them all.
+func WithFile(path string, maxSizeMB, maxBackups, maxAgeDays int) Option {
+    return func(opts *options) {
+        opts.file = &lumberjack.Logger{
+            Filename:   path,
+            MaxSize:    maxSizeMB,
+            MaxBackups: maxBackups,
+            MaxAge:     maxAgeDays,
+        }
+    }
+}
+
+// Sets up a global Zap logger with the given log level. Logs always go to
+// stdout, and additionally to a rotated file when WithFile is given.
+func InitLogger(logLevel string, opts ...Option) error {
+    var settings options
+    for _, opt := range opts {
+        opt(&settings)
+    }
+
     var level zapcore.Level
 
     // Convert string level to zapcore.Level
@@ -53,6 +81,13 @@ func InitLogger(logLevel string) error {
         return err
     }
 
+    if settings.file != nil {
+        fileCore := zapcore.NewCore(zapcore.NewJSONEncoder(config.EncoderConfig), zapcore.AddSync(settings.file), config.Level)
+        log = log.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+            return zapcore.NewTee(core, fileCore)
+        }))
+    }
+
     Log = log
     return nil
 }
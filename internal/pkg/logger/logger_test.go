@@ -0,0 +1,32 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Verifies that logs are written to the configured file.
+func TestInitLoggerWithFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "indexer.log")
+	if err := InitLogger("info", WithFile(path, 1, 1, 1)); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer func() { Log = nil }()
+
+	Log.Debug("filtered out")
+	Log.Info("written to file")
+	Log.Sync()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read log file: %v", err)
+	}
+	if !strings.Contains(string(data), `"message":"written to file"`) {
+		t.Errorf("Expected info entry in log file, got %s", data)
+	}
+	if strings.Contains(string(data), "filtered out") {
+		t.Errorf("Expected debug entry to be filtered, got %s", data)
+	}
+}
@@ -35,6 +35,155 @@ var BulkFailures = promauto.NewCounter(prometheus.CounterOpts{
     Help: "Total number of bulk requests that failed",
 })
 
+// Size of each bulk request body. Payloads near Elasticsearch's
+// http.max_content_length are rejected with 413 errors.
+var BulkRequestSizeBytes = promauto.NewHistogram(prometheus.HistogramOpts{
+    Name:    "indexer_bulk_request_size_bytes",
+    Help:    "Size in bytes of bulk request payloads sent to Elasticsearch",
+    Buckets: prometheus.ExponentialBuckets(1024, 10, 6), // From 1KB to ~100MB
+})
+
+// Counts documents that would have been indexed in dry-run mode.
+var DryRunDocuments = promauto.NewCounter(prometheus.CounterOpts{
+    Name: "indexer_dry_run_documents_total",
+    Help: "Total number of documents flushed in dry-run mode without being sent to Elasticsearch",
+})
+
+// Age of the oldest buffered document when a flush runs. Documents should
+// not wait much longer than the flush interval, so values above twice the
+// interval are worth alerting on.
+var OldestDocumentAge = promauto.NewGauge(prometheus.GaugeOpts{
+    Name: "indexer_oldest_document_age_seconds",
+    Help: "Time the oldest buffered document had been waiting at the last flush",
+})
+
+// Marks which Elasticsearch endpoint currently receives bulk requests.
+var ElasticsearchActiveEndpoint = promauto.NewGaugeVec(
+    prometheus.GaugeOpts{
+        Name: "indexer_elasticsearch_active_endpoint",
+        Help: "Elasticsearch endpoint currently receiving bulk requests (1=active, 0=standby)",
+    },
+    []string{"endpoint"},
+)
+
+// Captures how many inbound link count updates failed.
+var LinkCountUpdateFailures = promauto.NewCounter(prometheus.CounterOpts{
+    Name: "indexer_link_count_update_failures_total",
+    Help: "Total number of inbound link count update requests that failed",
+})
+
+// Distribution of word counts across enriched documents.
+var DocumentWordCount = promauto.NewHistogram(prometheus.HistogramOpts{
+    Name: "indexer_document_word_count",
+    Help: "Distribution of word counts of enriched documents",
+    Buckets: []float64{50, 100, 300, 500, 1000, 2000, 5000, 10000, 50000},
+})
+
+// Counts pages skipped because of their HTTP status code.
+var NonIndexableStatusCodes = promauto.NewCounterVec(
+    prometheus.CounterOpts{
+        Name: "indexer_non_indexable_status_codes_total",
+        Help: "Total number of pages skipped due to a non-200 HTTP status code",
+    },
+    []string{"code"},
+)
+
+// Counts pages skipped because they had no visible text after cleanup.
+var EmptyContentSkipped = promauto.NewCounter(
+    prometheus.CounterOpts{
+        Name: "indexer_empty_content_skipped_total",
+        Help: "Total number of pages skipped due to empty visible text",
+    },
+)
+
+// Counts pages skipped because their visible text looks like binary data.
+var BinaryContentSkipped = promauto.NewCounter(
+    prometheus.CounterOpts{
+        Name: "indexer_binary_content_skipped_total",
+        Help: "Total number of pages skipped because their visible text is binary data",
+    },
+)
+
+// Counts pages skipped because their robots meta tag contains noindex.
+var RobotsNoIndexSkipped = promauto.NewCounter(
+    prometheus.CounterOpts{
+        Name: "indexer_robots_noindex_skipped_total",
+        Help: "Total number of pages skipped due to a robots noindex directive",
+    },
+)
+
+// Counts pages skipped because their host's robots.txt disallows them.
+var RobotsTxtDisallowed = promauto.NewCounter(
+    prometheus.CounterOpts{
+        Name: "indexer_robots_txt_disallowed_total",
+        Help: "Total number of pages skipped because robots.txt disallows them",
+    },
+)
+
+// Counts pages dropped because they arrived while the queue was full.
+var QueueOverflowDrops = promauto.NewCounter(
+    prometheus.CounterOpts{
+        Name: "indexer_queue_overflow_drops_total",
+        Help: "Total number of pages dropped because they arrived while the queue was full",
+    },
+)
+
+// Counts full-queue pages never handed to the onFull callback because its
+// backlog was full too.
+var QueueOnFullBacklogDrops = promauto.NewCounter(
+    prometheus.CounterOpts{
+        Name: "indexer_queue_on_full_backlog_drops_total",
+        Help: "Total number of pages not handed to the queue's onFull callback because its backlog was full",
+    },
+)
+
+// Counts pages dropped by the URL filter before processing.
+var URLFilterRejections = promauto.NewCounter(
+    prometheus.CounterOpts{
+        Name: "indexer_url_filter_rejections_total",
+        Help: "Total number of pages dropped by the URL filter before processing",
+    },
+)
+
+// Counts pages skipped because their domain is on the skip list. Only the
+// first domains seen get their own label, the rest are counted as "other".
+var SkippedDomains = promauto.NewCounterVec(
+    prometheus.CounterOpts{
+        Name: "indexer_skipped_domains_total",
+        Help: "Total number of pages skipped because their domain is on the skip list",
+    },
+    []string{"domain"},
+)
+
+// Processing pipeline metrics
+var (
+    WorkerRestarts = promauto.NewCounter(prometheus.CounterOpts{
+        Name: "indexer_worker_restarts_total",
+        Help: "Total number of workers restarted after a panic",
+    })
+
+    ActiveWorkers = promauto.NewGauge(prometheus.GaugeOpts{
+        Name: "indexer_active_workers",
+        Help: "Number of workers currently processing pages rather than waiting on the queue",
+    })
+
+    WorkerBackpressurePauses = promauto.NewCounter(prometheus.CounterOpts{
+        Name: "indexer_worker_backpressure_pauses_total",
+        Help: "Total number of times a worker paused because the bulk indexer was unhealthy",
+    })
+
+    ProcessLatency = promauto.NewHistogram(prometheus.HistogramOpts{
+        Name: "indexer_process_latency_seconds",
+        Help: "Time taken to run a page through the processor",
+        Buckets: prometheus.DefBuckets,
+    })
+
+    ProcessErrors = promauto.NewCounter(prometheus.CounterOpts{
+        Name: "indexer_process_errors_total",
+        Help: "Total number of pages the processor rejected or failed on",
+    })
+)
+
 // Language detection metrics
 var (
     // NonEnglishPagesSkipped counts skipped non-English pages
@@ -57,6 +206,15 @@ var (
     })
 )
 
+// LanguageBreakdown counts processed documents by detected language
+var LanguageBreakdown = promauto.NewCounterVec(
+    prometheus.CounterOpts{
+        Name: "indexer_documents_by_language_total",
+        Help: "Total number of processed documents by detected language",
+    },
+    []string{"language"},
+)
+
 // Spam detection metrics
 var (
     HighSpamPagesSkipped = promauto.NewCounter(prometheus.CounterOpts{
@@ -75,6 +233,11 @@ var (
         Help: "Time taken to perform spam detection",
         Buckets: prometheus.DefBuckets,
     })
+
+    SpamEventsDropped = promauto.NewCounter(prometheus.CounterOpts{
+        Name: "indexer_spam_events_dropped_total",
+        Help: "Total number of spam events not recorded because the backlog was full or the write failed",
+    })
 )
 
 // NLP service metrics
@@ -95,6 +258,17 @@ var (
         Buckets: prometheus.ExponentialBuckets(0.1, 2, 10), // From 100ms to ~100s
     })
     
+    NlpItemLatency = promauto.NewHistogram(prometheus.HistogramOpts{
+        Name: "indexer_nlp_item_latency_seconds",
+        Help: "Time from a document entering an NLP batch until its result is returned",
+        Buckets: prometheus.ExponentialBuckets(0.01, 2, 14), // From 10ms to ~80s
+    })
+    
+    NlpFallbacks = promauto.NewCounter(prometheus.CounterOpts{
+        Name: "indexer_nlp_fallbacks_total",
+        Help: "Total number of documents enriched locally while the NLP service was unavailable",
+    })
+    
     NlpBatchCount = promauto.NewCounter(prometheus.CounterOpts{
         Name: "indexer_nlp_batch_count_total",
         Help: "Total number of batches sent to the NLP service",
@@ -105,6 +279,16 @@ var (
         Help: "Size of batches sent to the NLP service",
         Buckets: []float64{1, 2, 5, 10, 20, 50, 100},
     })
+
+    NlpBatchOverdueItems = promauto.NewCounter(prometheus.CounterOpts{
+        Name: "indexer_nlp_batch_overdue_items_total",
+        Help: "Total number of documents that waited longer than the maximum wait time in an NLP batch",
+    })
+
+    NlpConcurrentWorkers = promauto.NewGauge(prometheus.GaugeOpts{
+        Name: "indexer_nlp_concurrent_workers",
+        Help: "Number of NLP workers currently sending a batch to the NLP service",
+    })
     
     CircuitBreakerState = promauto.NewGaugeVec(
         prometheus.GaugeOpts{
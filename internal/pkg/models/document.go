@@ -18,16 +18,27 @@ type Document struct {
 	CanonicalURL     string         `json:"canonical_url"`
 	Title            string         `json:"title"`
 	MetaDescription  string         `json:"meta_description"`
+	MetaKeywords     string         `json:"meta_keywords"`
 	VisibleText      string         `json:"visible_text"`
+	WordCount        int            `json:"word_count"`
 	Entities         []string       `json:"entities"`
 	Keywords         []string       `json:"keywords"`
+	Summary          string         `json:"summary"`
 	Language         string         `json:"language"`
 	InternalLinks    []string       `json:"internal_links"`
 	ExternalLinks    []string       `json:"external_links"`
-	StructuredData   StructuredData `json:"structured_data"`
+	AnchorTexts      []string       `json:"anchor_texts"` // text of the page's outgoing links
+	InternalLinkCount int           `json:"internal_link_count"`
+	OutboundLinkCount int           `json:"outbound_link_count"`
+	H1Count          int            `json:"h1_count"`
+	H2Count          int            `json:"h2_count"`
+	H3Count          int            `json:"h3_count"`
+	AltTextCount     int            `json:"alt_text_count"`
+	HasAltTextsCoverage bool        `json:"has_alt_texts_coverage"`
+	StructuredData   []StructuredData `json:"structured_data"` // one entry per JSON-LD block
 	OpenGraph        OpenGraph      `json:"open_graph"`
-	DatePublished    time.Time      `json:"date_published"`
-	DateModified     time.Time      `json:"date_modified"`
+	DatePublished    *time.Time     `json:"date_published,omitempty"` // nil when the page has no date
+	DateModified     *time.Time     `json:"date_modified,omitempty"`
 	Categories       []string       `json:"categories"`
 	Tags             []string       `json:"tags"`
 	SocialLinks      []string       `json:"social_links"`
@@ -35,14 +46,17 @@ type Document struct {
 	IsSecure         bool           `json:"is_secure"`
 	QualityScore     int        	`json:"quality_score"` // Out of 100
 	SpamScore        int        	`json:"spam_score"`    // Out of 100
+	SpamSignals      []string       `json:"spam_signals,omitempty"` // spam phrases matched in the text
 	InboundLinkCount int            `json:"inbound_link_count"`
-	LastCrawled      time.Time      `json:"last_crawled"`
+	FetchError       string         `json:"fetch_error,omitempty"` // crawler error for partially fetched pages
+	CrawledAt        *time.Time     `json:"crawled_at,omitempty"` // when the crawler fetched the page, nil if not reported
+	IndexedAt        time.Time      `json:"indexed_at"`           // when the page was processed for indexing
 }
 
-// Structured data block.
+// Structured data node, one per JSON-LD object on the page.
 type StructuredData struct {
-	Context string `json:"@context"`
-	Type    string `json:"@type"`
+	Context string   `json:"@context"`
+	Type    []string `json:"@type"` // a node may have several types
 }
 
 // OpenGraph metadata.
@@ -23,6 +23,11 @@ type PageData struct {
     SocialLinks     []string            `json:"social_links"`
     VisibleText     string              `json:"visible_text"`
     LoadTime        time.Duration       `json:"load_time"`
+    FetchDurationMs int64               `json:"fetch_duration_ms"` // preferred over LoadTime when set
     IsSecure        bool                `json:"is_secure"`
     FetchError      string              `json:"fetch_error"`
+    HTTPStatusCode  int                 `json:"http_status_code"`
+    Robots          string              `json:"robots"` // content of the robots meta tag
+    NeedsSummary    bool                `json:"needs_summary"` // request a summary from the NLP service
+    CrawledAt       time.Time           `json:"crawled_at"` // when the crawler fetched the page
 }
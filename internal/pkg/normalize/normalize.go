@@ -0,0 +1,78 @@
+package normalize
+
+import (
+    "errors"
+    "net/url"
+    "strings"
+    "indexer/internal/pkg/models"
+)
+
+// Returns a copy of the page data with its visible text cleaned up and its
+// URLs normalized. The primary URL must be valid, while an invalid canonical
+// URL is left as is and invalid links are dropped.
+func Normalize(pd models.PageData) (models.PageData, error) {
+    normalizedURL, err := URL(pd.URL)
+    if err != nil {
+        return pd, err
+    }
+    pd.URL = normalizedURL
+
+    pd.VisibleText = CleanText(pd.VisibleText)
+
+    if canonical, err := URL(pd.CanonicalURL); err == nil {
+        pd.CanonicalURL = canonical
+    }
+
+    pd.InternalLinks = URLs(pd.InternalLinks)
+    pd.ExternalLinks = URLs(pd.ExternalLinks)
+
+    return pd, nil
+}
+
+// Removes extra whitespace and newlines.
+func CleanText(input string) string {
+    return strings.Join(strings.Fields(strings.TrimSpace(input)), " ")
+}
+
+// Trims, parses, and normalizes a URL.
+func URL(rawURL string) (string, error) {
+    rawURL = strings.TrimSpace(rawURL)
+    if rawURL == "" {
+        return "", errors.New("empty URL")
+    }
+    
+    // Handle relative URLs
+    if !strings.Contains(rawURL, "://") && !strings.HasPrefix(rawURL, "//") {
+        return "", errors.New("relative URL without base")
+    }
+    
+    // Handle scheme-relative URLs (starting with //)
+    if strings.HasPrefix(rawURL, "//") {
+        rawURL = "https:" + rawURL
+    }
+    
+    parsedURL, err := url.Parse(rawURL)
+    if err != nil {
+        return "", err
+    }
+    
+    // Ensure scheme is set
+    if parsedURL.Scheme == "" {
+        parsedURL.Scheme = "https"
+    }
+    
+    parsedURL.Scheme = strings.ToLower(parsedURL.Scheme)
+    parsedURL.Host = strings.ToLower(parsedURL.Host)
+    return parsedURL.String(), nil
+}
+
+// Processes a slice of URLs and returns only those that are valid.
+func URLs(urls []string) []string {
+    var result []string
+    for _, link := range urls {
+        if normalized, err := URL(link); err == nil {
+            result = append(result, normalized)
+        }
+    }
+    return result
+}
@@ -0,0 +1,119 @@
+package normalize
+
+import (
+	"reflect"
+	"testing"
+	"indexer/internal/pkg/models"
+)
+
+// Verifies URL normalization for absolute, scheme-relative and invalid URLs.
+func TestURL(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		valid    bool
+	}{
+		{"https://Example.COM/Path", "https://example.com/Path", true},
+		{"  HTTP://example.com/a?b=c  ", "http://example.com/a?b=c", true},
+		{"//cdn.example.com/script.js", "https://cdn.example.com/script.js", true},
+		{"", "", false},
+		{"   ", "", false},
+		{"/relative/path", "", false},
+		{"example.com/no-scheme", "", false},
+		{"http://[::1", "", false},
+	}
+
+	for _, tc := range tests {
+		got, err := URL(tc.input)
+		if tc.valid {
+			if err != nil {
+				t.Errorf("Expected %q to be valid, got %v", tc.input, err)
+			} else if got != tc.expected {
+				t.Errorf("Expected %q to normalize to %q, got %q", tc.input, tc.expected, got)
+			}
+		} else if err == nil {
+			t.Errorf("Expected error for %q, got %q", tc.input, got)
+		}
+	}
+}
+
+// Verifies that invalid URLs are dropped from a list.
+func TestURLs(t *testing.T) {
+	got := URLs([]string{"https://A.com/x", "relative", "", "//b.com"})
+	expected := []string{"https://a.com/x", "https://b.com"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+	if URLs(nil) != nil {
+		t.Errorf("Expected nil for no URLs")
+	}
+}
+
+// Verifies that whitespace is collapsed.
+func TestCleanText(t *testing.T) {
+	tests := map[string]string{
+		"":                          "",
+		"  \n\t ":                   "",
+		"hello":                     "hello",
+		"  hello   \n\n world \t!": "hello world !",
+	}
+	for input, expected := range tests {
+		if got := CleanText(input); got != expected {
+			t.Errorf("Expected CleanText(%q) to be %q, got %q", input, expected, got)
+		}
+	}
+}
+
+// Verifies that Normalize cleans every field and leaves the input untouched.
+func TestNormalize(t *testing.T) {
+	input := models.PageData{
+		URL:           "HTTPS://Example.com/Page",
+		CanonicalURL:  "//Example.com/canonical",
+		VisibleText:   "  Some \n text  ",
+		InternalLinks: []string{"https://EXAMPLE.com/a", "not-a-url"},
+		ExternalLinks: []string{"http://Other.org"},
+		Title:         "Unchanged",
+	}
+
+	got, err := Normalize(input)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if got.URL != "https://example.com/Page" {
+		t.Errorf("Unexpected URL %q", got.URL)
+	}
+	if got.CanonicalURL != "https://example.com/canonical" {
+		t.Errorf("Unexpected canonical URL %q", got.CanonicalURL)
+	}
+	if got.VisibleText != "Some text" {
+		t.Errorf("Unexpected visible text %q", got.VisibleText)
+	}
+	if !reflect.DeepEqual(got.InternalLinks, []string{"https://example.com/a"}) {
+		t.Errorf("Unexpected internal links %v", got.InternalLinks)
+	}
+	if !reflect.DeepEqual(got.ExternalLinks, []string{"http://other.org"}) {
+		t.Errorf("Unexpected external links %v", got.ExternalLinks)
+	}
+	if got.Title != "Unchanged" {
+		t.Errorf("Expected other fields to be preserved, got title %q", got.Title)
+	}
+	if input.URL != "HTTPS://Example.com/Page" || input.InternalLinks[0] != "https://EXAMPLE.com/a" {
+		t.Errorf("Expected input to be left untouched, got %+v", input)
+	}
+}
+
+// Verifies that an invalid primary URL is an error while an invalid canonical URL is kept.
+func TestNormalizeInvalidURLs(t *testing.T) {
+	if _, err := Normalize(models.PageData{URL: "relative/page"}); err == nil {
+		t.Error("Expected error for invalid primary URL, got nil")
+	}
+
+	got, err := Normalize(models.PageData{URL: "https://example.com", CanonicalURL: "relative"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if got.CanonicalURL != "relative" {
+		t.Errorf("Expected invalid canonical URL to be kept, got %q", got.CanonicalURL)
+	}
+}
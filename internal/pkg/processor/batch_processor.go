@@ -7,6 +7,7 @@ import (
     "errors"
     "fmt"
     "net/http"
+    "net/url"
     "sync"
     "time"
     "go.uber.org/zap"
@@ -22,18 +23,21 @@ type BatchProcessor struct {
     circuitBreaker *circuitbreaker.CircuitBreaker
     batchSize      int
     batchTimeout   time.Duration
+    maxWaitTime    time.Duration // hard upper bound on an item's wait, zero for none
+    numWorkers     int           // batches sent to the NLP service concurrently
     
-    // Rate limiter for controlling API request rate
+    // Rate limiter for controlling API request rate, safe for concurrent use
     rateLimiter    *rate.Limiter
-    limiterMu      sync.Mutex
     
     // Batch state
     mu             sync.Mutex
     currentBatch   []batchItem
     processingChan chan struct{}
+    batches        chan []batchItem // full batches waiting for an NLP worker
     
     // For graceful shutdown
     done           chan struct{}
+    stopOnce       sync.Once
 }
 
 // Represents a document in the batch
@@ -44,6 +48,13 @@ type batchItem struct {
     timestamp    time.Time
 }
 
+// Sends the result to the waiting caller, recording how long the item
+// spent in the batch including the NLP request.
+func (item batchItem) dispatch(result nlpResult) {
+    metrics.NlpItemLatency.Observe(time.Since(item.timestamp).Seconds())
+    item.resultCh <- result
+}
+
 // Holds the NLP processing results
 type nlpResult struct {
     entities   []entity
@@ -57,8 +68,38 @@ type entity struct {
     Label string `json:"label"`
 }
 
+// Configures optional BatchProcessor behaviour.
+type BatchOption func(*BatchProcessor)
+
+// Flushes the batch as soon as any item has waited longer than maxWaitTime,
+// even if the batch timeout tick is late or the batch is not full.
+func WithMaxWaitTime(maxWaitTime time.Duration) BatchOption {
+    return func(bp *BatchProcessor) {
+        bp.maxWaitTime = maxWaitTime
+    }
+}
+
+// Sends up to numWorkers batches to the NLP service at the same time, so
+// one slow request doesn't hold up the batches behind it.
+func WithNumWorkers(numWorkers int) BatchOption {
+    return func(bp *BatchProcessor) {
+        bp.numWorkers = numWorkers
+    }
+}
+
+// Posts a notification to webhookURL whenever the NLP circuit breaker opens.
+// An empty URL sends none.
+func WithCircuitBreakerWebhook(webhookURL string) BatchOption {
+    return func(bp *BatchProcessor) {
+        bp.circuitBreaker.WebhookURL = webhookURL
+    }
+}
+
+// Returned for items still waiting in a batch when the processor stops
+var errBatchProcessorStopped = errors.New("batch processor stopped")
+
 // Creates a new NLP batch processor
-func NewBatchProcessor(nlpServiceURL string, batchSize int, batchTimeout time.Duration) *BatchProcessor {
+func NewBatchProcessor(nlpServiceURL string, batchSize int, batchTimeout time.Duration, opts ...BatchOption) *BatchProcessor {
     bp := &BatchProcessor{
         nlpServiceURL:  nlpServiceURL,
         circuitBreaker: circuitbreaker.NewCircuitBreaker("nlp-service", 5, 30*time.Second),
@@ -68,30 +109,81 @@ func NewBatchProcessor(nlpServiceURL string, batchSize int, batchTimeout time.Du
         rateLimiter:    rate.NewLimiter(rate.Limit(5), 10),
         currentBatch:   make([]batchItem, 0, batchSize),
         processingChan: make(chan struct{}, 1),
+        batches:        make(chan []batchItem),
+        numWorkers:     1,
         done:           make(chan struct{}),
     }
+    for _, opt := range opts {
+        opt(bp)
+    }
+    bp.numWorkers = max(bp.numWorkers, 1)
     
-    // Start batch processing goroutine
+    // Start the batching goroutine and the workers sending batches
     go bp.processBatches()
+    for i := 0; i < bp.numWorkers; i++ {
+        go bp.runNLPWorker()
+    }
     
     return bp
 }
 
-// Gracefully shuts down the batch processor
+// Updates the NLP request rate limit at runtime
+func (bp *BatchProcessor) SetRateLimit(rps float64, burst int) {
+    bp.rateLimiter.SetLimit(rate.Limit(rps))
+    bp.rateLimiter.SetBurst(burst)
+    logger.Log.Info("NLP rate limit updated", zap.Float64("rps", rps), zap.Int("burst", burst))
+}
+
+// Checks that the NLP service answers GET /health on its host. Returns an
+// error if the service is unreachable or reports itself unhealthy.
+func (bp *BatchProcessor) HealthCheck(ctx context.Context) error {
+    healthURL, err := url.Parse(bp.nlpServiceURL)
+    if err != nil {
+        return fmt.Errorf("invalid NLP service URL: %w", err)
+    }
+    healthURL.Path = "/health"
+    healthURL.RawQuery = ""
+
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, healthURL.String(), nil)
+    if err != nil {
+        return fmt.Errorf("failed to create health check request: %w", err)
+    }
+
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        return fmt.Errorf("NLP service unreachable: %w", err)
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode != http.StatusOK {
+        return fmt.Errorf("NLP service unhealthy, status %d", resp.StatusCode)
+    }
+    return nil
+}
+
+// Gracefully shuts down the batch processor. Safe to call more than once.
 func (bp *BatchProcessor) Stop() {
-    close(bp.done)
+    bp.stopOnce.Do(func() { close(bp.done) })
 }
 
 // Submits text for NLP processing and returns results
 func (bp *BatchProcessor) Process(ctx context.Context, text string) ([]entity, []string, error) {
+    entities, keyphrases, _, err := bp.ProcessWithSummary(ctx, text, false)
+    return entities, keyphrases, err
+}
+
+// Submits text for NLP processing, also asking for a summary when
+// needsSummary is set, and returns results
+func (bp *BatchProcessor) ProcessWithSummary(ctx context.Context, text string, needsSummary bool) ([]entity, []string, string, error) {
     
 	if text == "" {
-        return nil, nil, nil
+        return nil, nil, "", nil
     }
     
     resultCh := make(chan nlpResult, 1)
     item := batchItem{
         text:         text,
+        needsSummary: needsSummary,
         resultCh:     resultCh,
         timestamp:    time.Now(),
     }
@@ -115,9 +207,9 @@ func (bp *BatchProcessor) Process(ctx context.Context, text string) ([]entity, [
     select {
     case result := <-resultCh:
         if result.err != nil {
-            return nil, nil, result.err
+            return nil, nil, "", result.err
         }
-        return result.entities, result.keyphrases, nil
+        return result.entities, result.keyphrases, result.summary, nil
     case <-ctx.Done(): // Remove the item from batch when context is canceled
         bp.mu.Lock()
         for i, batchItem := range bp.currentBatch {
@@ -129,7 +221,7 @@ func (bp *BatchProcessor) Process(ctx context.Context, text string) ([]entity, [
             }
         }
         bp.mu.Unlock()
-        return nil, nil, ctx.Err()
+        return nil, nil, "", ctx.Err()
     }
 }
 
@@ -137,6 +229,14 @@ func (bp *BatchProcessor) Process(ctx context.Context, text string) ([]entity, [
 func (bp *BatchProcessor) processBatches() {
     ticker := time.NewTicker(bp.batchTimeout)
     defer ticker.Stop()
+
+    // Checked twice per max wait, so no item waits much beyond it
+    var overdueCheck <-chan time.Time
+    if bp.maxWaitTime > 0 {
+        overdueTicker := time.NewTicker(max(bp.maxWaitTime/2, time.Millisecond))
+        defer overdueTicker.Stop()
+        overdueCheck = overdueTicker.C
+    }
     
     for {
         select {
@@ -146,11 +246,28 @@ func (bp *BatchProcessor) processBatches() {
             bp.processBatch()
         case <-ticker.C:
             bp.processBatch()
+        case <-overdueCheck:
+            if bp.hasOverdueItems() {
+                bp.processBatch()
+            }
+        }
+    }
+}
+
+// Reports whether any item in the current batch has waited longer than maxWaitTime.
+func (bp *BatchProcessor) hasOverdueItems() bool {
+    bp.mu.Lock()
+    defer bp.mu.Unlock()
+    for _, item := range bp.currentBatch {
+        if time.Since(item.timestamp) >= bp.maxWaitTime {
+            return true
         }
     }
+    return false
 }
 
-// Handles processing of the current batch
+// Hands the current batch to the next free NLP worker, waiting for one
+// if they are all busy
 func (bp *BatchProcessor) processBatch() {
     bp.mu.Lock()
     if len(bp.currentBatch) == 0 {
@@ -162,10 +279,42 @@ func (bp *BatchProcessor) processBatch() {
     batch := bp.currentBatch
     bp.currentBatch = make([]batchItem, 0, bp.batchSize)
     bp.mu.Unlock()
-    
+
+    select {
+    case bp.batches <- batch:
+    case <-bp.done:
+        for _, item := range batch {
+            item.dispatch(nlpResult{err: errBatchProcessorStopped})
+        }
+    }
+}
+
+// Sends batches to the NLP service until the processor stops
+func (bp *BatchProcessor) runNLPWorker() {
+    for {
+        select {
+        case <-bp.done:
+            return
+        case batch := <-bp.batches:
+            metrics.NlpConcurrentWorkers.Inc()
+            bp.sendBatch(batch)
+            metrics.NlpConcurrentWorkers.Dec()
+        }
+    }
+}
+
+// Sends a batch to the NLP service and dispatches the results to its items
+func (bp *BatchProcessor) sendBatch(batch []batchItem) {
     // Track metrics
     metrics.NlpBatchCount.Inc()
     metrics.NlpBatchSize.Observe(float64(len(batch)))
+    if bp.maxWaitTime > 0 {
+        for _, item := range batch {
+            if time.Since(item.timestamp) >= bp.maxWaitTime {
+                metrics.NlpBatchOverdueItems.Inc()
+            }
+        }
+    }
     
     // Check circuit breaker state
     if bp.circuitBreaker.State() == "open" {
@@ -173,27 +322,25 @@ func (bp *BatchProcessor) processBatch() {
         
         // Return circuit open error to all items
         for _, item := range batch {
-            item.resultCh <- nlpResult{
+            item.dispatch(nlpResult{
                 err: circuitbreaker.ErrCircuitOpen,
-            }
+            })
         }
         return
     }
     
     // Apply rate limiting before sending the batch
-    bp.limiterMu.Lock()
     ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
     err := bp.rateLimiter.Wait(ctx)
     cancel()
-    bp.limiterMu.Unlock()
     
     if err != nil {
         logger.Log.Warn("Rate limit exceeded for NLP batch", zap.Error(err))
         // Return rate limit error to all items
         for _, item := range batch {
-            item.resultCh <- nlpResult{
+            item.dispatch(nlpResult{
                 err: fmt.Errorf("rate limit exceeded: %w", err),
-            }
+            })
         }
         return
     }
@@ -215,7 +362,7 @@ func (bp *BatchProcessor) processBatch() {
     if err != nil {
         logger.Log.Error("Failed to marshal NLP batch request", zap.Error(err))
         for _, item := range batch {
-            item.resultCh <- nlpResult{err: err}
+            item.dispatch(nlpResult{err: err})
         }
         return
     }
@@ -255,7 +402,7 @@ func (bp *BatchProcessor) processBatch() {
     // Handle circuit breaker error
     if errors.Is(err, circuitbreaker.ErrCircuitOpen) {
         for _, item := range batch {
-            item.resultCh <- nlpResult{err: err}
+            item.dispatch(nlpResult{err: err})
         }
         return
     }
@@ -264,7 +411,7 @@ func (bp *BatchProcessor) processBatch() {
     if err != nil {
         logger.Log.Error("NLP batch request failed", zap.Error(err))
         for _, item := range batch {
-            item.resultCh <- nlpResult{err: err}
+            item.dispatch(nlpResult{err: err})
         }
         return
     }
@@ -275,7 +422,7 @@ func (bp *BatchProcessor) processBatch() {
         err := fmt.Errorf("invalid response format or mismatch in result count")
         logger.Log.Error("NLP batch response error", zap.Error(err))
         for _, item := range batch {
-            item.resultCh <- nlpResult{err: err}
+            item.dispatch(nlpResult{err: err})
         }
         return
     }
@@ -288,7 +435,7 @@ func (bp *BatchProcessor) processBatch() {
         
         result, ok := rawResult.(map[string]interface{})
         if !ok {
-            batch[i].resultCh <- nlpResult{err: fmt.Errorf("invalid result format")}
+            batch[i].dispatch(nlpResult{err: fmt.Errorf("invalid result format")})
             continue
         }
         
@@ -325,10 +472,10 @@ func (bp *BatchProcessor) processBatch() {
         summary, _ := result["summary"].(string)
         
         // Send result back
-        batch[i].resultCh <- nlpResult{
+        batch[i].dispatch(nlpResult{
             entities:   entities,
             keyphrases: keyphrases,
             summary:    summary,
-        }
+        })
     }
 }
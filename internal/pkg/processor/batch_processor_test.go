@@ -0,0 +1,231 @@
+package processor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	dto "github.com/prometheus/client_model/go"
+	"golang.org/x/time/rate"
+	"indexer/internal/pkg/metrics"
+)
+
+// Verifies that SetRateLimit updates the limiter in place.
+func TestBatchProcessorSetRateLimit(t *testing.T) {
+	bp := NewBatchProcessor("http://localhost:0/nlp/", 10, 200*time.Millisecond)
+	defer bp.Stop()
+
+	bp.SetRateLimit(2.5, 4)
+
+	if bp.rateLimiter.Limit() != rate.Limit(2.5) {
+		t.Errorf("Expected limit 2.5, got %v", bp.rateLimiter.Limit())
+	}
+	if bp.rateLimiter.Burst() != 4 {
+		t.Errorf("Expected burst 4, got %d", bp.rateLimiter.Burst())
+	}
+}
+
+// Verifies that WithCircuitBreakerWebhook sets the NLP circuit breaker's webhook.
+func TestBatchProcessorCircuitBreakerWebhook(t *testing.T) {
+	bp := NewBatchProcessor("http://localhost:0/nlp/", 10, 200*time.Millisecond, WithCircuitBreakerWebhook("http://alerts.example.com/hook"))
+	defer bp.Stop()
+
+	if bp.circuitBreaker.WebhookURL != "http://alerts.example.com/hook" {
+		t.Errorf("Expected webhook URL to be set, got %q", bp.circuitBreaker.WebhookURL)
+	}
+}
+
+// Verifies that HealthCheck queries /health on the NLP service host.
+func TestBatchProcessorHealthCheck(t *testing.T) {
+	healthy := true
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		if !healthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	bp := NewBatchProcessor(server.URL+"/nlp/", 10, 200*time.Millisecond)
+	defer bp.Stop()
+
+	if err := bp.HealthCheck(context.Background()); err != nil {
+		t.Errorf("Expected healthy service, got %v", err)
+	}
+	if path != "/health" {
+		t.Errorf("Expected request to /health, got %s", path)
+	}
+
+	healthy = false
+	if err := bp.HealthCheck(context.Background()); err == nil {
+		t.Error("Expected error for unhealthy service, got nil")
+	}
+
+	server.Close()
+	if err := bp.HealthCheck(context.Background()); err == nil {
+		t.Error("Expected error for unreachable service, got nil")
+	}
+}
+
+// Returns the number of observations recorded by the per-item NLP latency histogram.
+func nlpItemLatencyCount(t *testing.T) uint64 {
+	t.Helper()
+	var m dto.Metric
+	if err := metrics.NlpItemLatency.Write(&m); err != nil {
+		t.Fatalf("Failed to read histogram: %v", err)
+	}
+	return m.GetHistogram().GetSampleCount()
+}
+
+// Verifies that a latency sample is recorded for every item in a batch,
+// whether it succeeds or fails.
+func TestBatchProcessorItemLatency(t *testing.T) {
+	var fail atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`{"results": [{"entities": [], "keyphrases": ["go"]}]}`))
+	}))
+	defer server.Close()
+
+	bp := NewBatchProcessor(server.URL+"/nlp/", 1, 10*time.Millisecond)
+	defer bp.Stop()
+
+	before := nlpItemLatencyCount(t)
+	if _, _, err := bp.Process(context.Background(), "some text"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	fail.Store(true)
+	if _, _, err := bp.Process(context.Background(), "more text"); err == nil {
+		t.Fatal("Expected error from failing NLP service")
+	}
+
+	if got := nlpItemLatencyCount(t) - before; got != 2 {
+		t.Errorf("Expected 2 item latency samples, got %d", got)
+	}
+}
+
+// Verifies that an item is flushed once it exceeds the max wait time, even
+// though the batch is not full and the batch timeout is far off.
+func TestBatchProcessorMaxWaitTime(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"results": [{"entities": [], "keyphrases": ["go"]}]}`))
+	}))
+	defer server.Close()
+
+	bp := NewBatchProcessor(server.URL+"/nlp/", 10, time.Hour, WithMaxWaitTime(50*time.Millisecond))
+	defer bp.Stop()
+
+	var before dto.Metric
+	metrics.NlpBatchOverdueItems.Write(&before)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if _, _, err := bp.Process(ctx, "some text"); err != nil {
+		t.Fatalf("Expected the overdue item to be processed, got %v", err)
+	}
+
+	var after dto.Metric
+	metrics.NlpBatchOverdueItems.Write(&after)
+	if got := after.GetCounter().GetValue() - before.GetCounter().GetValue(); got != 1 {
+		t.Errorf("Expected 1 overdue item, got %v", got)
+	}
+}
+
+// Verifies that batches are sent to the NLP service concurrently by
+// several workers.
+func TestBatchProcessorNumWorkers(t *testing.T) {
+	var inFlight, maxInFlight atomic.Int32
+	bothArrived := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		if current > maxInFlight.Load() {
+			maxInFlight.Store(current)
+		}
+		if current == 2 {
+			close(bothArrived)
+		}
+		// Hold the request until the other batch is in flight too
+		select {
+		case <-bothArrived:
+		case <-time.After(2 * time.Second):
+		}
+		w.Write([]byte(`{"results": [{"entities": [], "keyphrases": []}]}`))
+	}))
+	defer server.Close()
+
+	bp := NewBatchProcessor(server.URL+"/nlp/", 1, 10*time.Millisecond, WithNumWorkers(2))
+	defer bp.Stop()
+
+	errs := make(chan error, 2)
+	for _, text := range []string{"first text", "second text"} {
+		go func(text string) {
+			_, _, err := bp.Process(context.Background(), text)
+			errs <- err
+		}(text)
+	}
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	}
+
+	if got := maxInFlight.Load(); got != 2 {
+		t.Errorf("Expected 2 concurrent NLP requests, got %d", got)
+	}
+}
+
+// Verifies that items submitted concurrently by many goroutines each get
+// their own result while several workers share the rate limiter. Run with
+// -race to catch unsynchronized access.
+func TestBatchProcessorConcurrentSubmit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Documents []struct {
+				Text string `json:"text"`
+			} `json:"documents"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		// Echo each text back as its only keyphrase
+		results := make([]map[string]interface{}, len(request.Documents))
+		for i, document := range request.Documents {
+			results[i] = map[string]interface{}{"entities": []interface{}{}, "keyphrases": []string{document.Text}}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"results": results})
+	}))
+	defer server.Close()
+
+	bp := NewBatchProcessor(server.URL+"/nlp/", 5, 10*time.Millisecond, WithNumWorkers(4))
+	defer bp.Stop()
+	bp.SetRateLimit(1000, 100)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			text := fmt.Sprintf("text %d", i)
+			_, keyphrases, err := bp.Process(context.Background(), text)
+			if err != nil {
+				t.Errorf("Expected no error for %q, got %v", text, err)
+				return
+			}
+			if len(keyphrases) != 1 || keyphrases[0] != text {
+				t.Errorf("Expected keyphrases [%s], got %v", text, keyphrases)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,64 @@
+package processor
+
+import (
+    "fmt"
+    "os"
+    "strings"
+    "gopkg.in/yaml.v3"
+    "indexer/internal/pkg/models"
+)
+
+// Assigns categories to a document based on its keywords.
+type CategoryEnricher struct {
+    categories map[string][]string // lowercase keyword -> categories
+}
+
+// Creates a new CategoryEnricher from a keyword to categories mapping.
+// Keywords are matched case-insensitively.
+func NewCategoryEnricher(categories map[string][]string) *CategoryEnricher {
+    normalized := make(map[string][]string, len(categories))
+    for keyword, cats := range categories {
+        key := strings.ToLower(strings.TrimSpace(keyword))
+        normalized[key] = append(normalized[key], cats...)
+    }
+    return &CategoryEnricher{categories: normalized}
+}
+
+// Parses a keyword to categories mapping from YAML (or JSON) data.
+func ParseCategoryMap(data []byte) (map[string][]string, error) {
+    var categories map[string][]string
+    if err := yaml.Unmarshal(data, &categories); err != nil {
+        return nil, fmt.Errorf("failed to parse category map: %w", err)
+    }
+    return categories, nil
+}
+
+// Reads a keyword to categories mapping from a YAML file.
+func LoadCategoryMapFile(path string) (map[string][]string, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, fmt.Errorf("failed to read category map: %w", err)
+    }
+    return ParseCategoryMap(data)
+}
+
+// Holds no background resources, so there is nothing to stop.
+func (enricher *CategoryEnricher) Stop() {}
+
+// Adds the categories of every matching keyword to the document, without duplicates.
+func (enricher *CategoryEnricher) Enrich(pageData *models.PageData, doc *models.Document) error {
+    seen := make(map[string]bool, len(doc.Categories))
+    for _, category := range doc.Categories {
+        seen[category] = true
+    }
+
+    for _, keyword := range doc.Keywords {
+        for _, category := range enricher.categories[strings.ToLower(keyword)] {
+            if !seen[category] {
+                seen[category] = true
+                doc.Categories = append(doc.Categories, category)
+            }
+        }
+    }
+    return nil
+}
@@ -0,0 +1,63 @@
+package processor
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"indexer/internal/pkg/models"
+)
+
+const categoryFixture = `
+golang: [programming, technology]
+Kubernetes: [technology, devops]
+recipe: [food]
+`
+
+// Verifies that matching keywords add their categories once each.
+func TestCategoryEnricher(t *testing.T) {
+	categories, err := ParseCategoryMap([]byte(categoryFixture))
+	if err != nil {
+		t.Fatalf("Failed to parse fixture: %v", err)
+	}
+	enricher := NewCategoryEnricher(categories)
+
+	doc := &models.Document{Keywords: []string{"golang", "kubernetes", "unrelated"}}
+	if err := enricher.Enrich(&models.PageData{}, doc); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := []string{"programming", "technology", "devops"}
+	if !reflect.DeepEqual(doc.Categories, expected) {
+		t.Errorf("Expected categories %v, got %v", expected, doc.Categories)
+	}
+
+	doc = &models.Document{Keywords: []string{"weather"}}
+	enricher.Enrich(&models.PageData{}, doc)
+	if len(doc.Categories) != 0 {
+		t.Errorf("Expected no categories without matching keywords, got %v", doc.Categories)
+	}
+}
+
+// Verifies loading the mapping from a YAML file.
+func TestLoadCategoryMapFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "categories.yaml")
+	if err := os.WriteFile(path, []byte(categoryFixture), 0600); err != nil {
+		t.Fatalf("Failed to write fixture: %v", err)
+	}
+
+	categories, err := LoadCategoryMapFile(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !reflect.DeepEqual(categories["recipe"], []string{"food"}) {
+		t.Errorf("Expected recipe to map to [food], got %v", categories["recipe"])
+	}
+
+	if _, err := LoadCategoryMapFile(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("Expected error for missing file, got nil")
+	}
+	if _, err := ParseCategoryMap([]byte("golang: programming: [bad")); err == nil {
+		t.Error("Expected error for invalid YAML, got nil")
+	}
+}
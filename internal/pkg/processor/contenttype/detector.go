@@ -0,0 +1,33 @@
+package contenttype
+
+import (
+    "unicode"
+    "unicode/utf8"
+)
+
+// Share of characters that must be printable for text to count as text
+const MinPrintableRatio = 0.9
+
+// Reports whether text looks like human-readable text rather than binary
+// data, i.e. at least MinPrintableRatio of its characters are printable
+// Unicode or whitespace. Invalid UTF-8 bytes count as unprintable. Empty
+// text is considered text.
+func IsTextContent(text string) bool {
+    total, printable := 0, 0
+    for index, r := range text {
+        total++
+        if r == utf8.RuneError {
+            // A literal U+FFFD is printable, an invalid byte is not
+            if _, size := utf8.DecodeRuneInString(text[index:]); size == 1 {
+                continue
+            }
+        }
+        if unicode.IsPrint(r) || unicode.IsSpace(r) {
+            printable++
+        }
+    }
+    if total == 0 {
+        return true
+    }
+    return float64(printable) >= MinPrintableRatio*float64(total)
+}
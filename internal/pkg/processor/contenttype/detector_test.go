@@ -0,0 +1,31 @@
+package contenttype
+
+import (
+	"strings"
+	"testing"
+)
+
+// Verifies that readable text passes and binary data is rejected.
+func TestIsTextContent(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected bool
+	}{
+		{"empty", "", true},
+		{"plain text", "The committee met on Tuesday.", true},
+		{"whitespace", "Line one\n\tLine two\r\n", true},
+		{"unicode", "Café 中文 عربي — “quoted” 🙂", true},
+		{"replacement character", "Broken � character", true},
+		{"few control characters", strings.Repeat("a", 95) + strings.Repeat("\x00", 5), true},
+		{"many control characters", strings.Repeat("a", 85) + strings.Repeat("\x00", 15), false},
+		{"pdf bytes", "%PDF-1.4\x00\x01\x02\x03\x04\x05\x06\x07\x08\x0e\x0f", false},
+		{"invalid utf-8", "ab\xff\xfe\xfd\xfc\xfb\xfa\xf9\xf8", false},
+	}
+
+	for _, tc := range tests {
+		if got := IsTextContent(tc.text); got != tc.expected {
+			t.Errorf("%s: IsTextContent(%q) = %v, expected %v", tc.name, tc.text, got, tc.expected)
+		}
+	}
+}
@@ -2,9 +2,14 @@ package processor
 
 import (
     "context"
+    "errors"
     "fmt"
+    "net/url"
+    "strings"
     "time"
+    "unicode/utf8"
     "go.uber.org/zap"
+    "indexer/internal/pkg/circuitbreaker"
     "indexer/internal/pkg/logger"
     "indexer/internal/pkg/metrics"
     "indexer/internal/pkg/models"
@@ -13,30 +18,166 @@ import (
 // Defines the interface for adding additional metadata to a document.
 type Enricher interface {
     Enrich(pageData *models.PageData, doc *models.Document) error
+
+    // Releases any background resources, the enricher must not be used after
+    Stop()
+}
+
+// Applies a sequence of enrichers to a document, in the order given.
+type ChainedEnricher struct {
+    enrichers       []Enricher
+    continueOnError bool
+}
+
+// Creates a new ChainedEnricher. When continueOnError is false the chain stops
+// at the first failing enricher, otherwise every enricher runs and the errors
+// are joined together.
+func NewChainedEnricher(continueOnError bool, enrichers ...Enricher) *ChainedEnricher {
+    return &ChainedEnricher{
+        enrichers:       enrichers,
+        continueOnError: continueOnError,
+    }
+}
+
+// Runs each enricher in sequence against the same document.
+func (chain *ChainedEnricher) Enrich(pageData *models.PageData, doc *models.Document) error {
+    var errs []error
+    for _, enricher := range chain.enrichers {
+        if err := enricher.Enrich(pageData, doc); err != nil {
+            if !chain.continueOnError {
+                return err
+            }
+            errs = append(errs, err)
+        }
+    }
+    return errors.Join(errs...)
+}
+
+// Stops every enricher in the chain.
+func (chain *ChainedEnricher) Stop() {
+    for _, enricher := range chain.enrichers {
+        enricher.Stop()
+    }
 }
 
 // Implementation of Enricher.
 type nlpEnricher struct {
     batchProcessor *BatchProcessor
+    fallback       *LocalFallbackEnricher // used while the circuit is open, may be nil
+
+    // Summaries are only requested for pages with at least this many words
+    summarizeMinWords int
+
+    // Text sent to the NLP service is truncated to this many bytes, zero for no limit
+    maxTextBytes int
 }
 
+// Default batch settings for now
+const (
+    defaultNLPBatchSize    = 10 // Process 10 documents at a time
+    defaultNLPBatchTimeout = 200 * time.Millisecond
+)
+
 // Creates a new instance of an NLP-based Enricher.
 func NewNLPEnricher(nlpServiceURL string) Enricher {
-    // Default batch settings for now
-    batchSize := 10  // Process 10 documents at a time
-    batchTimeout := 200 * time.Millisecond
+    return NewNLPEnricherWithBatchProcessor(
+        NewBatchProcessor(nlpServiceURL, defaultNLPBatchSize, defaultNLPBatchTimeout),
+    )
+}
+
+// Creates a new NLP-based Enricher around an existing batch processor.
+func NewNLPEnricherWithBatchProcessor(batchProcessor *BatchProcessor) Enricher {
+    return NewNLPEnricherWithFallback(batchProcessor, nil)
+}
+
+// Creates a new NLP-based Enricher that extracts keywords with fallback
+// whenever the NLP service circuit breaker is open. A nil fallback skips
+// enrichment instead.
+func NewNLPEnricherWithFallback(batchProcessor *BatchProcessor, fallback *LocalFallbackEnricher) Enricher {
     return &nlpEnricher{
-        batchProcessor: NewBatchProcessor(nlpServiceURL, batchSize, batchTimeout),
+        batchProcessor: batchProcessor,
+        fallback:       fallback,
     }
 }
 
-// Augments the document with entities and keywords using batch processing.
+// Stops the batch processor feeding the enricher.
+func (enricher *nlpEnricher) Stop() {
+    enricher.batchProcessor.Stop()
+}
+
+// Copies the page onto the document and augments it with entities and
+// keywords using batch processing. The page's own fields are kept even when
+// the NLP service fails.
 func (enricher *nlpEnricher) Enrich(pageData *models.PageData, doc *models.Document) error {
-    // Skip if no text
-    if pageData.VisibleText == "" {
-        return nil
+    // Copy basic fields from PageData to Document. URL, CanonicalURL,
+    // VisibleText and the links were already normalized by cleanAndNormalize.
+    doc.URL = pageData.URL
+    doc.CanonicalURL = pageData.CanonicalURL
+    doc.Title = pageData.Title
+    doc.MetaDescription = pageData.MetaDescription
+    doc.Language = pageData.Language
+    doc.VisibleText = pageData.VisibleText
+    doc.WordCount = len(strings.Fields(pageData.VisibleText))
+    doc.InternalLinks = pageData.InternalLinks
+    doc.ExternalLinks = pageData.ExternalLinks
+    doc.AnchorTexts = pageData.AnchorTexts
+    doc.InternalLinkCount = len(doc.InternalLinks)
+    doc.OutboundLinkCount = len(doc.ExternalLinks)
+    doc.H1Count = len(pageData.Headings["h1"])
+    doc.H2Count = len(pageData.Headings["h2"])
+    doc.H3Count = len(pageData.Headings["h3"])
+    doc.AltTextCount = len(pageData.AltTexts)
+    doc.HasAltTextsCoverage = doc.AltTextCount > 0
+    // Unset dates are left nil rather than indexed as the zero time
+    if !pageData.DatePublished.IsZero() {
+        published := pageData.DatePublished
+        doc.DatePublished = &published
+    }
+    if !pageData.DateModified.IsZero() {
+        modified := pageData.DateModified
+        doc.DateModified = &modified
+    }
+    if !pageData.CrawledAt.IsZero() {
+        crawled := pageData.CrawledAt
+        doc.CrawledAt = &crawled
+    }
+    doc.SocialLinks = pageData.SocialLinks
+    doc.StructuredData = parseStructuredData(pageData)
+    doc.OpenGraph = models.OpenGraph{
+        OGTitle:       pageData.OpenGraph["og:title"],
+        OGDescription: pageData.OpenGraph["og:description"],
+        OGImage:       pageData.OpenGraph["og:image"],
+    }
+    doc.Tags = parseTags(pageData)
+    doc.IsSecure = pageData.IsSecure
+    doc.FetchError = pageData.FetchError
+    // The author's meta keywords are kept apart from the NLP keywords, since
+    // they are often stuffed for SEO and would skew the quality score and
+    // categories derived from them.
+    doc.MetaKeywords = pageData.MetaKeywords
+    
+    if loadTime := loadTimeMillis(pageData); loadTime > 0 {
+        doc.LoadTime = loadTime
+    }
+
+    metrics.DocumentWordCount.Observe(float64(doc.WordCount))
+
+    // Record when the page was processed, the crawl time comes from the crawler
+    doc.IndexedAt = time.Now()
+
+    // Skip NLP if no text
+    if pageData.VisibleText != "" {
+        enricher.addNLPFields(pageData, doc)
     }
+
+    doc.QualityScore = enricher.calculateQualityScore(doc)
     
+    return nil
+}
+
+// Sets the entities, keywords and summary the NLP service finds in the
+// page text. They are left unset if the service fails.
+func (enricher *nlpEnricher) addNLPFields(pageData *models.PageData, doc *models.Document) {
     // Create context with timeout for processing
     ctx, cancel := context.WithTimeout(context.Background(), 10 * time.Second)
     defer cancel()
@@ -44,18 +185,29 @@ func (enricher *nlpEnricher) Enrich(pageData *models.PageData, doc *models.Docum
     // Record timing for metrics
     startTime := time.Now()
     
+    // Summaries are expensive, so only ask for them on longer pages
+    needsSummary := pageData.NeedsSummary && doc.WordCount >= enricher.summarizeMinWords
+
     // Process through batch processor
-    entities, keyphrases, err := enricher.batchProcessor.Process(ctx, pageData.VisibleText)
+    nlpText := truncateUTF8(pageData.VisibleText, enricher.maxTextBytes)
+    entities, keyphrases, summary, err := enricher.batchProcessor.ProcessWithSummary(ctx, nlpText, needsSummary)
     
     // Update metrics
     metrics.NlpRequests.Inc()
     metrics.NlpLatency.Observe(time.Since(startTime).Seconds())
     
+    if errors.Is(err, circuitbreaker.ErrCircuitOpen) && enricher.fallback != nil {
+        // Keep quality scores meaningful during NLP outages
+        logger.Log.Debug("NLP service unavailable, using local keyword extraction", zap.String("url", pageData.URL))
+        metrics.NlpFallbacks.Inc()
+        entities, keyphrases, err = nil, enricher.fallback.ExtractKeywords(pageData.VisibleText), nil
+    }
+
     if err != nil {
         logger.Log.Warn("NLP enrichment failed", zap.Error(err), zap.String("url", pageData.URL))
         metrics.NlpErrors.Inc()
         // Continue without NLP enrichment
-        return nil
+        return
     }
     
     // Map entities to doc.Entities
@@ -64,36 +216,89 @@ func (enricher *nlpEnricher) Enrich(pageData *models.PageData, doc *models.Docum
         docEntities = append(docEntities, fmt.Sprintf("%s: %s", ent.Label, ent.Text))
     }
     doc.Entities = docEntities
-    
-    // Store keywords
     doc.Keywords = keyphrases
-    
-    // Copy basic fields from PageData to Document
-    doc.URL = pageData.URL
-    doc.CanonicalURL = pageData.CanonicalURL
-    doc.Title = pageData.Title
-    doc.MetaDescription = pageData.MetaDescription
-    doc.Language = pageData.Language
-    doc.VisibleText = pageData.VisibleText
-    doc.InternalLinks = pageData.InternalLinks
-    doc.ExternalLinks = pageData.ExternalLinks
-    doc.DatePublished = pageData.DatePublished
-    doc.DateModified = pageData.DateModified
-    doc.SocialLinks = pageData.SocialLinks
-    doc.IsSecure = pageData.IsSecure
-    
-    if pageData.LoadTime > 0 {
-        doc.LoadTime = int64(pageData.LoadTime / time.Millisecond)
+    doc.Summary = summary
+}
+
+// Parses every JSON-LD block on the page, keeping one entry per node so
+// arrays and @graph documents contribute all of theirs. Blocks that are not
+// valid JSON objects or arrays of them are skipped.
+func parseStructuredData(pageData *models.PageData) []models.StructuredData {
+    var blocks []models.StructuredData
+    for _, raw := range pageData.StructuredData {
+        nodes, err := jsonLDNodes([]byte(raw))
+        if err != nil {
+            logger.Log.Debug("Skipping invalid structured data block", zap.Error(err), zap.String("url", pageData.URL))
+            continue
+        }
+        for _, node := range nodes {
+            blocks = append(blocks, models.StructuredData{
+                Context: jsonLDContext(node.Context),
+                Type:    jsonLDStrings(node.Type),
+            })
+        }
     }
+    return blocks
+}
 
-    doc.QualityScore = enricher.calculateQualityScore(doc)
-    
-    // Set last crawled time
-    doc.LastCrawled = time.Now()
-    
-    return nil
+// Collects the page's tags from its OpenGraph article:tag and the keywords
+// of its JSON-LD blocks. Keywords may be an array or a comma-separated
+// string. Tags are trimmed and deduplicated ignoring case, keeping the
+// first spelling seen.
+func parseTags(pageData *models.PageData) []string {
+    var tags []string
+    seen := make(map[string]struct{})
+    add := func(values ...string) {
+        for _, value := range values {
+            for _, tag := range strings.Split(value, ",") {
+                tag = strings.TrimSpace(tag)
+                key := strings.ToLower(tag)
+                if _, ok := seen[key]; ok || tag == "" {
+                    continue
+                }
+                seen[key] = struct{}{}
+                tags = append(tags, tag)
+            }
+        }
+    }
+
+    add(pageData.OpenGraph["article:tag"])
+    for _, raw := range pageData.StructuredData {
+        nodes, err := jsonLDNodes([]byte(raw))
+        if err != nil {
+            continue
+        }
+        for _, node := range nodes {
+            add(jsonLDStrings(node.Keywords)...)
+        }
+    }
+    return tags
+}
+
+// Cuts text to at most maxBytes bytes without splitting a character.
+// A maxBytes of zero or less leaves the text unchanged.
+func truncateUTF8(text string, maxBytes int) string {
+    if maxBytes <= 0 || len(text) <= maxBytes {
+        return text
+    }
+    for maxBytes > 0 && !utf8.RuneStart(text[maxBytes]) {
+        maxBytes--
+    }
+    return text[:maxBytes]
+}
+
+// Returns the page load time in milliseconds, preferring the crawler's
+// FetchDurationMs over the LoadTime duration.
+func loadTimeMillis(pageData *models.PageData) int64 {
+    if pageData.FetchDurationMs > 0 {
+        return pageData.FetchDurationMs
+    }
+    return pageData.LoadTime.Milliseconds()
 }
 
+// Path fragments commonly used by ad networks and sponsored content
+var adURLPatterns = []string{"/sponsored/", "/ad/", "/ads/", "/adserver/", "/affiliate/"}
+
 // Quality scoring for prioritization
 func (enricher *nlpEnricher) calculateQualityScore(doc *models.Document) int {
     score := 0
@@ -108,6 +313,25 @@ func (enricher *nlpEnricher) calculateQualityScore(doc *models.Document) int {
     if len(doc.MetaDescription) > 50 {
         score += 5
     }
+
+    // Long-form content is favoured, thin or bloated pages are penalized
+    if doc.WordCount >= 300 && doc.WordCount <= 2000 {
+        score += 10
+    } else if doc.WordCount < 100 || doc.WordCount > 10000 {
+        score -= 5
+    }
+    
+    // A single H1 is best practice, a page without one is poorly structured
+    if doc.H1Count == 1 {
+        score += 5
+    } else if doc.H1Count == 0 {
+        score -= 5
+    }
+    
+    // Images described with alt text are accessibility-friendly
+    if doc.HasAltTextsCoverage {
+        score += 5
+    }
     
     // Content signals
     if len(doc.Entities) >= 1 {
@@ -117,13 +341,9 @@ func (enricher *nlpEnricher) calculateQualityScore(doc *models.Document) int {
         score += 10
     }
     
-    // Link signals
-    if len(doc.InternalLinks) > 0 {
-        score += 5
-    }
-    if len(doc.ExternalLinks) > 0 {
-        score += 5
-    }
+    // Link signals, one point per link up to five each
+    score += min(doc.InternalLinkCount, 5)
+    score += min(doc.OutboundLinkCount, 5)
 
     if doc.Language == "en" {
         score += 10
@@ -142,10 +362,45 @@ func (enricher *nlpEnricher) calculateQualityScore(doc *models.Document) int {
         score += 2
     }
     
-    // Cap at 100
+    score -= urlPenalty(doc.URL)
+
+    // Clamp to 0-100
     if score > 100 {
         score = 100
+    } else if score < 0 {
+        score = 0
     }
     
     return score
-}
\ No newline at end of file
+}
+
+// Penalizes deep pages, heavily parameterized URLs and ad-network paths
+func urlPenalty(rawURL string) int {
+    parsed, err := url.Parse(rawURL)
+    if err != nil {
+        return 0
+    }
+
+    penalty := 0
+    segments := 0
+    for _, segment := range strings.Split(parsed.Path, "/") {
+        if segment != "" {
+            segments++
+        }
+    }
+    if segments > 5 {
+        penalty += 5
+    }
+    if len(parsed.Query()) > 3 {
+        penalty += 5
+    }
+
+    path := strings.ToLower(parsed.Path) + "/"
+    for _, pattern := range adURLPatterns {
+        if strings.Contains(path, pattern) {
+            penalty += 10
+            break
+        }
+    }
+    return penalty
+}
@@ -0,0 +1,660 @@
+package processor
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"go.uber.org/zap"
+	"indexer/internal/pkg/circuitbreaker"
+	"indexer/internal/pkg/logger"
+	"indexer/internal/pkg/models"
+)
+
+func init() {
+	// Ensure that the logger is not nil during tests.
+	logger.Log = zap.NewNop()
+}
+
+// mockEnricher records its name in a shared slice every time it is invoked.
+type mockEnricher struct {
+	name  string
+	calls *[]string
+	err   error
+}
+
+func (me *mockEnricher) Enrich(pageData *models.PageData, doc *models.Document) error {
+	*me.calls = append(*me.calls, me.name)
+	return me.err
+}
+
+func (me *mockEnricher) Stop() {
+	*me.calls = append(*me.calls, me.name+" stopped")
+}
+
+// Returns an NLP enricher backed by a stub NLP service that finds no
+// entities or keyphrases, flushing every item on its own.
+func newTestNLPEnricher(t *testing.T) Enricher {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"results": [{"entities": [], "keyphrases": []}]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	bp := NewBatchProcessor(server.URL+"/nlp/", 1, 10*time.Millisecond)
+	t.Cleanup(bp.Stop)
+	return NewNLPEnricherWithBatchProcessor(bp)
+}
+
+// Verifies that a ChainedEnricher invokes its enrichers in order.
+func TestChainedEnricherOrder(t *testing.T) {
+	var calls []string
+	chain := NewChainedEnricher(false,
+		&mockEnricher{name: "first", calls: &calls},
+		&mockEnricher{name: "second", calls: &calls},
+	)
+
+	if err := chain.Enrich(&models.PageData{}, &models.Document{}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := []string{"first", "second"}
+	if !reflect.DeepEqual(calls, expected) {
+		t.Errorf("Expected call order %v, got %v", expected, calls)
+	}
+}
+
+// Verifies that stopping a ChainedEnricher stops every enricher, and that
+// the NLP enricher's batch processor can be stopped more than once.
+func TestChainedEnricherStop(t *testing.T) {
+	var calls []string
+	bp := NewBatchProcessor("http://localhost:0/nlp/", 1, 10*time.Millisecond)
+	chain := NewChainedEnricher(false,
+		&mockEnricher{name: "first", calls: &calls},
+		NewNLPEnricherWithBatchProcessor(bp),
+		&mockEnricher{name: "second", calls: &calls},
+	)
+
+	chain.Stop()
+	bp.Stop()
+
+	expected := []string{"first stopped", "second stopped"}
+	if !reflect.DeepEqual(calls, expected) {
+		t.Errorf("Expected stop calls %v, got %v", expected, calls)
+	}
+	select {
+	case <-bp.done:
+	default:
+		t.Error("Expected the batch processor to be stopped")
+	}
+}
+
+// Verifies that the chain stops at the first error unless configured to continue.
+func TestChainedEnricherError(t *testing.T) {
+	failure := errors.New("enrichment failed")
+
+	var calls []string
+	chain := NewChainedEnricher(false,
+		&mockEnricher{name: "first", calls: &calls, err: failure},
+		&mockEnricher{name: "second", calls: &calls},
+	)
+	if err := chain.Enrich(&models.PageData{}, &models.Document{}); !errors.Is(err, failure) {
+		t.Errorf("Expected %v, got %v", failure, err)
+	}
+	if !reflect.DeepEqual(calls, []string{"first"}) {
+		t.Errorf("Expected chain to stop after first enricher, got %v", calls)
+	}
+
+	calls = nil
+	chain = NewChainedEnricher(true,
+		&mockEnricher{name: "first", calls: &calls, err: failure},
+		&mockEnricher{name: "second", calls: &calls},
+	)
+	if err := chain.Enrich(&models.PageData{}, &models.Document{}); !errors.Is(err, failure) {
+		t.Errorf("Expected %v, got %v", failure, err)
+	}
+	if !reflect.DeepEqual(calls, []string{"first", "second"}) {
+		t.Errorf("Expected both enrichers to run, got %v", calls)
+	}
+}
+
+// Verifies the word count contribution to the quality score.
+func TestCalculateQualityScoreWordCount(t *testing.T) {
+	enricher := &nlpEnricher{}
+	newDoc := func(wordCount int) *models.Document {
+		return &models.Document{Title: "A reasonable title", IsSecure: true, LoadTime: 5000, WordCount: wordCount}
+	}
+	base := enricher.calculateQualityScore(newDoc(150))
+
+	tests := []struct {
+		name      string
+		wordCount int
+		delta     int
+	}{
+		{"long-form", 1000, 10},
+		{"lower bound", 300, 10},
+		{"upper bound", 2000, 10},
+		{"medium", 150, 0},
+		{"very long", 20000, -5},
+		{"very short", 20, -5},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			score := enricher.calculateQualityScore(newDoc(tc.wordCount))
+			if score-base != tc.delta {
+				t.Errorf("Expected score delta %d for %d words, got %d", tc.delta, tc.wordCount, score-base)
+			}
+		})
+	}
+}
+
+// Verifies that link counts raise the quality score gradually up to a cap.
+func TestCalculateQualityScoreLinkCounts(t *testing.T) {
+	enricher := &nlpEnricher{}
+	// A single H1 keeps the base score clear of the clamp at zero
+	newDoc := func(internal, outbound int) *models.Document {
+		return &models.Document{LoadTime: 5000, WordCount: 150, H1Count: 1, InternalLinkCount: internal, OutboundLinkCount: outbound}
+	}
+	base := enricher.calculateQualityScore(newDoc(0, 0))
+
+	tests := []struct {
+		name     string
+		internal int
+		outbound int
+		delta    int
+	}{
+		{"single internal", 1, 0, 1},
+		{"several internal", 3, 0, 3},
+		{"internal capped", 50, 0, 5},
+		{"single outbound", 0, 1, 1},
+		{"both capped", 10, 10, 10},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			score := enricher.calculateQualityScore(newDoc(tc.internal, tc.outbound))
+			if score-base != tc.delta {
+				t.Errorf("Expected score delta %d, got %d", tc.delta, score-base)
+			}
+		})
+	}
+}
+
+// Verifies that Open Graph tags on the page are mapped onto the document.
+func TestNLPEnricherOpenGraph(t *testing.T) {
+	enricher := newTestNLPEnricher(t)
+
+	pageData := &models.PageData{
+		URL:         "https://example.com",
+		VisibleText: "Some visible text",
+		OpenGraph:   map[string]string{"og:title": "Test", "og:image": "https://example.com/image.png"},
+	}
+	doc := &models.Document{}
+	if err := enricher.Enrich(pageData, doc); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if doc.OpenGraph.OGTitle != "Test" {
+		t.Errorf("Expected OGTitle %q, got %q", "Test", doc.OpenGraph.OGTitle)
+	}
+	if doc.OpenGraph.OGImage != "https://example.com/image.png" {
+		t.Errorf("Expected OGImage to be mapped, got %q", doc.OpenGraph.OGImage)
+	}
+	if doc.OpenGraph.OGDescription != "" {
+		t.Errorf("Expected empty OGDescription, got %q", doc.OpenGraph.OGDescription)
+	}
+}
+
+// Verifies that page fields without enrichment of their own are carried over
+// onto the document.
+func TestNLPEnricherCopiesPageFields(t *testing.T) {
+	enricher := newTestNLPEnricher(t)
+
+	tests := []struct {
+		name     string
+		pageData models.PageData
+		check    func(doc *models.Document) bool
+	}{
+		{
+			"headings",
+			models.PageData{Headings: map[string][]string{"h1": {"Main"}, "h2": {"One", "Two"}}},
+			func(doc *models.Document) bool { return doc.H1Count == 1 && doc.H2Count == 2 && doc.H3Count == 0 },
+		},
+		{
+			"alt texts",
+			models.PageData{AltTexts: []string{"A diagram", "A photo"}},
+			func(doc *models.Document) bool { return doc.AltTextCount == 2 && doc.HasAltTextsCoverage },
+		},
+		{
+			"anchor texts",
+			models.PageData{AnchorTexts: []string{"Home", "Contact us"}},
+			func(doc *models.Document) bool { return reflect.DeepEqual(doc.AnchorTexts, []string{"Home", "Contact us"}) },
+		},
+		{
+			"fetch error",
+			models.PageData{FetchError: "unexpected EOF"},
+			func(doc *models.Document) bool { return doc.FetchError == "unexpected EOF" },
+		},
+		{
+			"meta keywords",
+			models.PageData{MetaKeywords: "tests, examples"},
+			func(doc *models.Document) bool { return doc.MetaKeywords == "tests, examples" && len(doc.Keywords) == 0 },
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			pageData := tc.pageData
+			pageData.URL = "https://example.com"
+			pageData.VisibleText = "Some visible text"
+			doc := &models.Document{}
+			if err := enricher.Enrich(&pageData, doc); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if !tc.check(doc) {
+				t.Errorf("Expected %s to be copied from %+v, got %+v", tc.name, tc.pageData, doc)
+			}
+		})
+	}
+}
+
+// Verifies that every valid JSON-LD block on the page is kept on the document.
+func TestNLPEnricherStructuredData(t *testing.T) {
+	enricher := newTestNLPEnricher(t)
+
+	pageData := &models.PageData{
+		URL:         "https://example.com",
+		VisibleText: "Some visible text",
+		StructuredData: []string{
+			`{"@context": "https://schema.org", "@type": "Article"}`,
+			`not json`,
+			`{"@context": "https://schema.org", "@type": "BreadcrumbList"}`,
+		},
+	}
+	doc := &models.Document{}
+	if err := enricher.Enrich(pageData, doc); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := []models.StructuredData{
+		{Context: "https://schema.org", Type: []string{"Article"}},
+		{Context: "https://schema.org", Type: []string{"BreadcrumbList"}},
+	}
+	if !reflect.DeepEqual(doc.StructuredData, expected) {
+		t.Errorf("Expected structured data %v, got %v", expected, doc.StructuredData)
+	}
+}
+
+// Verifies that the JSON-LD shapes common on real pages are all kept.
+func TestParseStructuredData(t *testing.T) {
+	tests := []struct {
+		name     string
+		block    string
+		expected []models.StructuredData
+	}{
+		{
+			"single object",
+			`{"@context": "https://schema.org", "@type": "Article"}`,
+			[]models.StructuredData{{Context: "https://schema.org", Type: []string{"Article"}}},
+		},
+		{
+			"top-level array",
+			`[{"@context": "https://schema.org", "@type": "Organization"}, {"@context": "https://schema.org", "@type": "WebSite"}]`,
+			[]models.StructuredData{
+				{Context: "https://schema.org", Type: []string{"Organization"}},
+				{Context: "https://schema.org", Type: []string{"WebSite"}},
+			},
+		},
+		{
+			"graph inherits context",
+			`{"@context": "https://schema.org", "@graph": [{"@type": "WebPage"}, {"@context": "https://example.org", "@type": "Person"}]}`,
+			[]models.StructuredData{
+				{Context: "https://schema.org", Type: []string{"WebPage"}},
+				{Context: "https://example.org", Type: []string{"Person"}},
+			},
+		},
+		{
+			"type array",
+			`{"@context": "https://schema.org", "@type": ["Article", "NewsArticle"]}`,
+			[]models.StructuredData{{Context: "https://schema.org", Type: []string{"Article", "NewsArticle"}}},
+		},
+		{
+			"object context",
+			`{"@context": {"@vocab": "https://schema.org/", "name": "headline"}, "@type": "Article"}`,
+			[]models.StructuredData{{Context: "https://schema.org/", Type: []string{"Article"}}},
+		},
+		{
+			"array context",
+			`{"@context": ["https://schema.org", {"@language": "en"}], "@type": "Article"}`,
+			[]models.StructuredData{{Context: "https://schema.org", Type: []string{"Article"}}},
+		},
+		{"invalid", `not json`, nil},
+		{"array of non-objects", `[1, 2]`, nil},
+	}
+
+	for _, tc := range tests {
+		pageData := &models.PageData{StructuredData: []string{tc.block}}
+		if blocks := parseStructuredData(pageData); !reflect.DeepEqual(blocks, tc.expected) {
+			t.Errorf("%s: expected structured data %v, got %v", tc.name, tc.expected, blocks)
+		}
+	}
+}
+
+// Verifies that tags are collected from OpenGraph and JSON-LD keywords.
+func TestParseTags(t *testing.T) {
+	tests := []struct {
+		name     string
+		pageData models.PageData
+		expected []string
+	}{
+		{"none", models.PageData{}, nil},
+		{
+			"open graph",
+			models.PageData{OpenGraph: map[string]string{"article:tag": "Go, Search ,,"}},
+			[]string{"Go", "Search"},
+		},
+		{
+			"keywords array",
+			models.PageData{StructuredData: []string{`{"@type": "Article", "keywords": ["Go", "Indexing"]}`}},
+			[]string{"Go", "Indexing"},
+		},
+		{
+			"keywords string",
+			models.PageData{StructuredData: []string{`{"@type": "Article", "keywords": "Go, Indexing"}`}},
+			[]string{"Go", "Indexing"},
+		},
+		{
+			"graph keywords",
+			models.PageData{StructuredData: []string{`{"@context": "https://schema.org", "@graph": [{"@type": "Article", "keywords": ["Go"]}]}`}},
+			[]string{"Go"},
+		},
+		{
+			"deduplicated across sources",
+			models.PageData{
+				OpenGraph: map[string]string{"article:tag": "Go"},
+				StructuredData: []string{
+					`not json`,
+					`{"keywords": 42}`,
+					`{"keywords": ["go", "Search"]}`,
+				},
+			},
+			[]string{"Go", "Search"},
+		},
+	}
+
+	for _, tc := range tests {
+		if tags := parseTags(&tc.pageData); !reflect.DeepEqual(tags, tc.expected) {
+			t.Errorf("%s: expected tags %v, got %v", tc.name, tc.expected, tags)
+		}
+	}
+}
+
+// Verifies that text is truncated to the byte limit on a character boundary.
+func TestTruncateUTF8(t *testing.T) {
+	tests := []struct {
+		text     string
+		maxBytes int
+		expected string
+	}{
+		{"hello world", 0, "hello world"},
+		{"hello world", 20, "hello world"},
+		{"hello world", 5, "hello"},
+		{"café au lait", 4, "caf"},
+		{"café au lait", 5, "café"},
+		{"日本語", 4, "日"},
+	}
+
+	for _, tc := range tests {
+		if got := truncateUTF8(tc.text, tc.maxBytes); got != tc.expected {
+			t.Errorf("truncateUTF8(%q, %d) = %q, expected %q", tc.text, tc.maxBytes, got, tc.expected)
+		}
+	}
+}
+
+// Verifies that only dates set on the page are copied and serialized.
+func TestNLPEnricherDates(t *testing.T) {
+	enricher := newTestNLPEnricher(t)
+
+	published := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	pageData := &models.PageData{URL: "https://example.com", VisibleText: "Some visible text", DatePublished: published}
+	doc := &models.Document{}
+	if err := enricher.Enrich(pageData, doc); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if doc.DatePublished == nil || !doc.DatePublished.Equal(published) {
+		t.Errorf("Expected DatePublished %v, got %v", published, doc.DatePublished)
+	}
+	if doc.DateModified != nil {
+		t.Errorf("Expected DateModified to be nil, got %v", doc.DateModified)
+	}
+
+	encoded, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatalf("Failed to marshal document: %v", err)
+	}
+	if !strings.Contains(string(encoded), `"date_published":"2024-03-01T12:00:00Z"`) {
+		t.Errorf("Expected date_published in JSON, got %s", encoded)
+	}
+	if strings.Contains(string(encoded), "date_modified") {
+		t.Errorf("Expected date_modified to be omitted, got %s", encoded)
+	}
+}
+
+// Verifies that a failed NLP call only leaves the NLP fields unset, while
+// the fields taken from the page and the index time are still set.
+func TestNLPEnricherFailureKeepsPageFields(t *testing.T) {
+	bp := NewBatchProcessor("http://127.0.0.1:0/nlp/", 1, 10*time.Millisecond)
+	defer bp.Stop()
+	bp.circuitBreaker = circuitbreaker.NewCircuitBreaker("test", 1, time.Minute)
+	bp.circuitBreaker.Execute(func() error { return errors.New("unavailable") })
+
+	crawled := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	pageData := &models.PageData{
+		URL:         "https://example.com",
+		Title:       "A reasonable title",
+		VisibleText: "Some visible text",
+		Headings:    map[string][]string{"h1": {"Main"}},
+		CrawledAt:   crawled,
+		FetchError:  "unexpected EOF",
+	}
+	before := time.Now()
+	doc := &models.Document{}
+	if err := NewNLPEnricherWithBatchProcessor(bp).Enrich(pageData, doc); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if doc.Entities != nil || doc.Keywords != nil || doc.Summary != "" {
+		t.Errorf("Expected no NLP fields, got %v, %v and %q", doc.Entities, doc.Keywords, doc.Summary)
+	}
+	if doc.IndexedAt.Before(before) {
+		t.Errorf("Expected IndexedAt to be the processing time, got %v", doc.IndexedAt)
+	}
+	if doc.Title != pageData.Title || doc.WordCount != 3 || doc.H1Count != 1 || doc.FetchError != pageData.FetchError {
+		t.Errorf("Expected the page fields to be copied, got %+v", doc)
+	}
+	if doc.CrawledAt == nil || !doc.CrawledAt.Equal(crawled) {
+		t.Errorf("Expected CrawledAt %v, got %v", crawled, doc.CrawledAt)
+	}
+	if doc.QualityScore == 0 {
+		t.Error("Expected a quality score from the page fields")
+	}
+}
+
+// Verifies that the crawl time comes from the crawler while the index time
+// records when the page was processed.
+func TestNLPEnricherCrawledAt(t *testing.T) {
+	enricher := newTestNLPEnricher(t)
+
+	crawled := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	before := time.Now()
+	doc := &models.Document{}
+	if err := enricher.Enrich(&models.PageData{URL: "https://example.com", VisibleText: "Some visible text", CrawledAt: crawled}, doc); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if doc.CrawledAt == nil || !doc.CrawledAt.Equal(crawled) {
+		t.Errorf("Expected CrawledAt %v, got %v", crawled, doc.CrawledAt)
+	}
+	if doc.IndexedAt.Before(before) {
+		t.Errorf("Expected IndexedAt to be the processing time, got %v", doc.IndexedAt)
+	}
+
+	uncrawled := &models.Document{}
+	if err := enricher.Enrich(&models.PageData{URL: "https://example.com", VisibleText: "Some visible text"}, uncrawled); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if uncrawled.CrawledAt != nil {
+		t.Errorf("Expected CrawledAt to be nil when not reported, got %v", uncrawled.CrawledAt)
+	}
+}
+
+// Verifies that summaries are only requested for long enough pages that ask for one.
+func TestNLPEnricherSummary(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Documents []struct {
+				NeedsSummary bool `json:"needs_summary"`
+			} `json:"documents"`
+		}
+		json.NewDecoder(r.Body).Decode(&request)
+		summary := ""
+		if len(request.Documents) == 1 && request.Documents[0].NeedsSummary {
+			summary = "A short summary"
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"results": []map[string]interface{}{{"entities": []string{}, "keyphrases": []string{}, "summary": summary}},
+		})
+	}))
+	defer server.Close()
+
+	bp := NewBatchProcessor(server.URL+"/nlp/", 1, 10*time.Millisecond)
+	defer bp.Stop()
+	enricher := &nlpEnricher{batchProcessor: bp, summarizeMinWords: 4}
+
+	tests := []struct {
+		name         string
+		text         string
+		needsSummary bool
+		expected     string
+	}{
+		{"long page", "one two three four five", true, "A short summary"},
+		{"short page", "one two three", true, ""},
+		{"not requested", "one two three four five", false, ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			doc := &models.Document{}
+			pageData := &models.PageData{URL: "https://example.com", VisibleText: tc.text, NeedsSummary: tc.needsSummary}
+			if err := enricher.Enrich(pageData, doc); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if doc.Summary != tc.expected {
+				t.Errorf("Expected summary %q, got %q", tc.expected, doc.Summary)
+			}
+		})
+	}
+}
+
+// Verifies that FetchDurationMs takes precedence over LoadTime.
+func TestLoadTimeMillis(t *testing.T) {
+	tests := []struct {
+		name     string
+		pageData models.PageData
+		expected int64
+	}{
+		{"fetch duration", models.PageData{FetchDurationMs: 1500, LoadTime: 3 * time.Second}, 1500},
+		{"load time fallback", models.PageData{LoadTime: 2500 * time.Millisecond}, 2500},
+		{"neither set", models.PageData{}, 0},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := loadTimeMillis(&tc.pageData); got != tc.expected {
+				t.Errorf("Expected %dms, got %dms", tc.expected, got)
+			}
+		})
+	}
+}
+
+// Verifies the URL-based penalties applied to the quality score.
+func TestCalculateQualityScoreURLPenalties(t *testing.T) {
+	enricher := &nlpEnricher{}
+	newDoc := func(url string) *models.Document {
+		return &models.Document{Title: "A reasonable title", IsSecure: true, LoadTime: 5000, WordCount: 150, URL: url}
+	}
+	base := enricher.calculateQualityScore(newDoc("https://example.com/articles/post"))
+
+	tests := []struct {
+		name  string
+		url   string
+		delta int
+	}{
+		{"shallow path", "https://example.com/a/b/c/d/e", 0},
+		{"deep path", "https://example.com/a/b/c/d/e/f", -5},
+		{"few query params", "https://example.com/search?q=go&page=2&sort=asc", 0},
+		{"many query params", "https://example.com/search?q=go&page=2&sort=asc&ref=home", -5},
+		{"sponsored path", "https://example.com/sponsored/product", -10},
+		{"ad path", "https://example.com/ad/banner", -10},
+		{"ad trailing segment", "https://example.com/news/ad", -10},
+		{"ad-like word", "https://example.com/adventure/trip", 0},
+		{"all penalties", "https://example.com/ads/a/b/c/d/e?a=1&b=2&c=3&d=4", -20},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			score := enricher.calculateQualityScore(newDoc(tc.url))
+			if score-base != tc.delta {
+				t.Errorf("Expected score delta %d for %s, got %d", tc.delta, tc.url, score-base)
+			}
+		})
+	}
+}
+
+// Verifies the H1 contribution to the quality score.
+func TestCalculateQualityScoreHeadings(t *testing.T) {
+	enricher := &nlpEnricher{}
+	newDoc := func(h1Count int) *models.Document {
+		return &models.Document{Title: "A reasonable title", IsSecure: true, LoadTime: 5000, WordCount: 150, H1Count: h1Count, H2Count: 4}
+	}
+	base := enricher.calculateQualityScore(newDoc(2))
+
+	tests := []struct {
+		name    string
+		h1Count int
+		delta   int
+	}{
+		{"single H1", 1, 5},
+		{"no H1", 0, -5},
+		{"several H1", 3, 0},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			score := enricher.calculateQualityScore(newDoc(tc.h1Count))
+			if score-base != tc.delta {
+				t.Errorf("Expected score delta %d for %d H1 tags, got %d", tc.delta, tc.h1Count, score-base)
+			}
+		})
+	}
+}
+
+// Verifies the alt text bonus in the quality score.
+func TestCalculateQualityScoreAltTexts(t *testing.T) {
+	enricher := &nlpEnricher{}
+	doc := &models.Document{Title: "A reasonable title", IsSecure: true, LoadTime: 5000, WordCount: 150}
+	base := enricher.calculateQualityScore(doc)
+
+	doc.AltTextCount = 3
+	doc.HasAltTextsCoverage = true
+	if delta := enricher.calculateQualityScore(doc) - base; delta != 5 {
+		t.Errorf("Expected alt text coverage to add 5 points, got %d", delta)
+	}
+}
@@ -0,0 +1,30 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Returned when a page has no visible text left after cleanup.
+var ErrEmptyContent = errors.New("page has no visible content")
+
+// Returned when the page's robots meta tag asks not to be indexed.
+var ErrRobotsNoIndex = errors.New("page is marked noindex by robots meta tag")
+
+// Returned when the page's visible text looks like binary data rather than text.
+var ErrBinaryContent = errors.New("page content is binary, not text")
+
+// Returned when the page's robots.txt disallows indexing it.
+var ErrRobotsTxtDisallowed = errors.New("page is disallowed by robots.txt")
+
+// Returned when the page belongs to a domain configured to never be indexed.
+var ErrSkippedDomain = errors.New("page belongs to a skipped domain")
+
+// Returned when a crawled page responded with a status code that should not be indexed.
+type ErrNonIndexableStatus struct {
+	Code int
+}
+
+func (err ErrNonIndexableStatus) Error() string {
+	return fmt.Sprintf("non-indexable HTTP status code: %d", err.Code)
+}
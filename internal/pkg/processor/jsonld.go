@@ -0,0 +1,92 @@
+package processor
+
+import (
+    "bytes"
+    "encoding/json"
+)
+
+// A JSON-LD node as found on real pages. @context may be a string, an array
+// or an object, and @type a string or an array, so both are kept raw.
+type jsonLDNode struct {
+    Context  json.RawMessage   `json:"@context"`
+    Type     json.RawMessage   `json:"@type"`
+    Graph    []json.RawMessage `json:"@graph"`
+    Keywords json.RawMessage   `json:"keywords"`
+}
+
+// Flattens a JSON-LD block into its nodes. Top-level arrays and @graph
+// documents yield one node per element, and graph nodes without a @context
+// of their own inherit the document's. Returns an error if the block or
+// any of its elements is not a JSON object.
+func jsonLDNodes(data []byte) ([]jsonLDNode, error) {
+    data = bytes.TrimSpace(data)
+    if len(data) > 0 && data[0] == '[' {
+        var elements []json.RawMessage
+        if err := json.Unmarshal(data, &elements); err != nil {
+            return nil, err
+        }
+        return jsonLDElementNodes(elements, nil)
+    }
+
+    var node jsonLDNode
+    if err := json.Unmarshal(data, &node); err != nil {
+        return nil, err
+    }
+    if len(node.Graph) == 0 {
+        return []jsonLDNode{node}, nil
+    }
+    return jsonLDElementNodes(node.Graph, node.Context)
+}
+
+// Flattens each element into its nodes, giving context to those without one.
+func jsonLDElementNodes(elements []json.RawMessage, context json.RawMessage) ([]jsonLDNode, error) {
+    var nodes []jsonLDNode
+    for _, element := range elements {
+        elementNodes, err := jsonLDNodes(element)
+        if err != nil {
+            return nil, err
+        }
+        for i := range elementNodes {
+            if len(elementNodes[i].Context) == 0 {
+                elementNodes[i].Context = context
+            }
+        }
+        nodes = append(nodes, elementNodes...)
+    }
+    return nodes, nil
+}
+
+// Returns the strings of a value that is either a string or an array,
+// skipping array elements that are not strings.
+func jsonLDStrings(raw json.RawMessage) []string {
+    var value string
+    if err := json.Unmarshal(raw, &value); err == nil {
+        return []string{value}
+    }
+    var elements []json.RawMessage
+    if err := json.Unmarshal(raw, &elements); err != nil {
+        return nil
+    }
+    var values []string
+    for _, element := range elements {
+        if err := json.Unmarshal(element, &value); err == nil {
+            values = append(values, value)
+        }
+    }
+    return values
+}
+
+// Returns the vocabulary a @context points at: the context itself if it is
+// a string, its first string if an array, or its @vocab if an object.
+func jsonLDContext(raw json.RawMessage) string {
+    var context struct {
+        Vocab string `json:"@vocab"`
+    }
+    if err := json.Unmarshal(raw, &context); err == nil {
+        return context.Vocab
+    }
+    if values := jsonLDStrings(raw); len(values) > 0 {
+        return values[0]
+    }
+    return ""
+}
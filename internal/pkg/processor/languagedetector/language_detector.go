@@ -2,6 +2,7 @@ package languagedetector
 
 import (
 	"errors"
+	"strings"
 	"github.com/pemistahl/lingua-go"
 	"go.uber.org/zap"
 	"indexer/internal/pkg/logger"
@@ -9,11 +10,14 @@ import (
 )
 
 // Detects the language of a given text and returns the ISO 639-1 code.
-func DetectLanguage(languageDetector lingua.LanguageDetector, text string) (string, error) {
+// Hints, such as the page's meta keywords, are assumed to share the text's
+// language and are classified along with it to bias the result.
+func DetectLanguage(languageDetector lingua.LanguageDetector, text string, hints ...string) (string, error) {
     const minTextLength = 20
     if len(text) < minTextLength {
         return "unknown", nil
     }
+    text = withHints(text, hints)
 
     // Detect language and calculate confidence values
     detectedLang, exists := languageDetector.DetectLanguageOf(text)
@@ -47,4 +51,17 @@ func DetectLanguage(languageDetector lingua.LanguageDetector, text string) (stri
     // If not English or low confidence, skip this document
     metrics.NonEnglishPagesSkipped.Inc()
     return detectedLang.IsoCode639_1().String(), errors.New("not an English page, skipping")
-}
\ No newline at end of file
+}
+
+// Appends the non-empty hints to the text, one per line.
+func withHints(text string, hints []string) string {
+    var builder strings.Builder
+    builder.WriteString(text)
+    for _, hint := range hints {
+        if hint = strings.TrimSpace(hint); hint != "" {
+            builder.WriteString("\n")
+            builder.WriteString(hint)
+        }
+    }
+    return builder.String()
+}
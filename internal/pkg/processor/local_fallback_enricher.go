@@ -0,0 +1,140 @@
+package processor
+
+import (
+    "math"
+    "sort"
+    "strings"
+    "sync"
+    "unicode"
+    "indexer/internal/pkg/models"
+)
+
+// Number of keywords extracted per page by the local fallback
+const defaultFallbackKeywordCount = 10
+
+// Terms whose document frequency is tracked before the counts decay, so the
+// vocabulary can't grow for as long as the NLP service is down
+const defaultFallbackMaxVocabulary = 100000
+
+// Common English words that carry no meaning as keywords
+var fallbackStopWords = map[string]bool{
+    "the": true, "and": true, "for": true, "are": true, "but": true, "not": true,
+    "you": true, "all": true, "any": true, "can": true, "had": true, "her": true,
+    "was": true, "one": true, "our": true, "out": true, "has": true, "his": true,
+    "how": true, "its": true, "may": true, "new": true, "now": true, "see": true,
+    "who": true, "did": true, "get": true, "him": true, "she": true, "too": true,
+    "use": true, "that": true, "with": true, "have": true, "this": true, "will": true,
+    "your": true, "from": true, "they": true, "been": true, "were": true, "said": true,
+    "each": true, "which": true, "their": true, "there": true, "what": true, "about": true,
+    "would": true, "these": true, "other": true, "into": true, "more": true, "some": true,
+    "than": true, "then": true, "them": true, "also": true, "only": true, "when": true,
+    "where": true, "while": true, "after": true, "before": true, "such": true, "very": true,
+    "just": true, "over": true, "most": true, "could": true, "should": true, "because": true,
+}
+
+// Extracts keywords with TF-IDF, without calling any external service. The
+// document frequencies are learned from the pages it sees, so keywords
+// improve as more pages pass through. Once more than maxVocabulary terms are
+// tracked, every count is halved and terms dropping to zero are forgotten,
+// which keeps memory bounded and favours recent pages.
+type LocalFallbackEnricher struct {
+    mu            sync.Mutex
+    documentCount int
+    docFrequency  map[string]int
+    maxKeywords   int
+    maxVocabulary int
+}
+
+// Creates a new LocalFallbackEnricher.
+func NewLocalFallbackEnricher() *LocalFallbackEnricher {
+    return &LocalFallbackEnricher{
+        docFrequency:  make(map[string]int),
+        maxKeywords:   defaultFallbackKeywordCount,
+        maxVocabulary: defaultFallbackMaxVocabulary,
+    }
+}
+
+// Sets the document keywords from the page's visible text.
+func (enricher *LocalFallbackEnricher) Enrich(pageData *models.PageData, doc *models.Document) error {
+    doc.Keywords = enricher.ExtractKeywords(pageData.VisibleText)
+    return nil
+}
+
+// Holds no background resources, so there is nothing to stop.
+func (enricher *LocalFallbackEnricher) Stop() {}
+
+// Returns the highest scoring terms in text, most relevant first.
+func (enricher *LocalFallbackEnricher) ExtractKeywords(text string) []string {
+    termCounts := make(map[string]int)
+    total := 0
+    for _, term := range keywordTerms(text) {
+        termCounts[term]++
+        total++
+    }
+    if total == 0 {
+        return nil
+    }
+
+    enricher.mu.Lock()
+    enricher.documentCount++
+    for term := range termCounts {
+        enricher.docFrequency[term]++
+    }
+
+    type scoredTerm struct {
+        term  string
+        score float64
+    }
+    scored := make([]scoredTerm, 0, len(termCounts))
+    for term, count := range termCounts {
+        tf := float64(count) / float64(total)
+        idf := math.Log(float64(1+enricher.documentCount)/float64(1+enricher.docFrequency[term])) + 1
+        scored = append(scored, scoredTerm{term: term, score: tf * idf})
+    }
+    if len(enricher.docFrequency) > enricher.maxVocabulary {
+        enricher.decay()
+    }
+    enricher.mu.Unlock()
+
+    sort.Slice(scored, func(i, j int) bool {
+        if scored[i].score != scored[j].score {
+            return scored[i].score > scored[j].score
+        }
+        return scored[i].term < scored[j].term
+    })
+
+    keywords := make([]string, 0, min(len(scored), enricher.maxKeywords))
+    for i := 0; i < len(scored) && i < enricher.maxKeywords; i++ {
+        keywords = append(keywords, scored[i].term)
+    }
+    return keywords
+}
+
+// Halves the document count and every document frequency, forgetting terms
+// that drop to zero. Callers must hold mu.
+func (enricher *LocalFallbackEnricher) decay() {
+    enricher.documentCount /= 2
+    for term, frequency := range enricher.docFrequency {
+        if frequency /= 2; frequency == 0 {
+            delete(enricher.docFrequency, term)
+        } else {
+            enricher.docFrequency[term] = frequency
+        }
+    }
+}
+
+// Splits text into lowercase words, dropping short words, numbers and stop words.
+func keywordTerms(text string) []string {
+    words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+        return !unicode.IsLetter(r)
+    })
+
+    terms := words[:0]
+    for _, word := range words {
+        if len(word) < 3 || fallbackStopWords[word] {
+            continue
+        }
+        terms = append(terms, word)
+    }
+    return terms
+}
@@ -0,0 +1,85 @@
+package processor
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+	"indexer/internal/pkg/circuitbreaker"
+	"indexer/internal/pkg/models"
+)
+
+// Verifies that frequent terms rank first and stop words are ignored.
+func TestLocalFallbackEnricherKeywords(t *testing.T) {
+	enricher := NewLocalFallbackEnricher()
+	doc := &models.Document{}
+	pageData := &models.PageData{VisibleText: "Golang golang GOLANG compiler compiler and the of runtime 2024"}
+	if err := enricher.Enrich(pageData, doc); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := []string{"golang", "compiler", "runtime"}
+	if !reflect.DeepEqual(doc.Keywords, expected) {
+		t.Errorf("Expected keywords %v, got %v", expected, doc.Keywords)
+	}
+	if keywords := enricher.ExtractKeywords("a an the"); keywords != nil {
+		t.Errorf("Expected no keywords for stop words only, got %v", keywords)
+	}
+}
+
+// Verifies that terms seen on every page are ranked below distinctive ones.
+func TestLocalFallbackEnricherInverseDocumentFrequency(t *testing.T) {
+	enricher := NewLocalFallbackEnricher()
+	enricher.ExtractKeywords("website navigation")
+	enricher.ExtractKeywords("website footer")
+
+	keywords := enricher.ExtractKeywords("website kubernetes")
+	if len(keywords) != 2 || keywords[0] != "kubernetes" {
+		t.Errorf("Expected distinctive term to rank first, got %v", keywords)
+	}
+}
+
+// Verifies that the tracked vocabulary decays instead of growing without bound.
+func TestLocalFallbackEnricherVocabularyCap(t *testing.T) {
+	enricher := NewLocalFallbackEnricher()
+	enricher.maxVocabulary = 4
+	enricher.ExtractKeywords("website alpha")
+	enricher.ExtractKeywords("website bravo")
+	enricher.ExtractKeywords("website charlie delta")
+
+	if len(enricher.docFrequency) != 1 || enricher.docFrequency["website"] != 1 {
+		t.Errorf("Expected only the common term to survive decay, got %v", enricher.docFrequency)
+	}
+	if enricher.documentCount != 1 {
+		t.Errorf("Expected the document count to be halved, got %d", enricher.documentCount)
+	}
+}
+
+// Verifies that the NLP enricher extracts keywords locally while the circuit is open.
+func TestNLPEnricherLocalFallback(t *testing.T) {
+	bp := NewBatchProcessor("http://127.0.0.1:0/nlp/", 1, 10*time.Millisecond)
+	defer bp.Stop()
+	bp.circuitBreaker = circuitbreaker.NewCircuitBreaker("test", 1, time.Minute)
+	bp.circuitBreaker.Execute(func() error { return errors.New("unavailable") })
+
+	pageData := &models.PageData{URL: "https://example.com", VisibleText: "Kubernetes clusters schedule kubernetes pods"}
+
+	doc := &models.Document{}
+	if err := NewNLPEnricherWithBatchProcessor(bp).Enrich(pageData, doc); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if doc.Keywords != nil || doc.URL != pageData.URL {
+		t.Errorf("Expected only NLP enrichment to be skipped without a fallback, got %+v", doc)
+	}
+
+	doc = &models.Document{}
+	if err := NewNLPEnricherWithFallback(bp, NewLocalFallbackEnricher()).Enrich(pageData, doc); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(doc.Keywords) == 0 || doc.Keywords[0] != "kubernetes" {
+		t.Errorf("Expected fallback keywords, got %v", doc.Keywords)
+	}
+	if doc.URL != pageData.URL || doc.QualityScore == 0 {
+		t.Errorf("Expected the document to be enriched, got %+v", doc)
+	}
+}
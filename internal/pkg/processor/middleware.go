@@ -0,0 +1,104 @@
+package processor
+
+import (
+    "context"
+    "errors"
+    "time"
+    "go.uber.org/zap"
+    "indexer/internal/pkg/logger"
+    "indexer/internal/pkg/metrics"
+    "indexer/internal/pkg/models"
+)
+
+// Wraps a Processor with additional behaviour.
+type ProcessorMiddleware func(Processor) Processor
+
+// Adapts an ordinary function to the Processor interface.
+type ProcessorFunc func(pageData *models.PageData, doc *models.Document) error
+
+func (fn ProcessorFunc) Process(pageData *models.PageData, doc *models.Document) error {
+    return fn(pageData, doc)
+}
+
+// Runs Process through a middleware chain while forwarding the optional
+// interfaces to the processor it wraps, which a bare ProcessorFunc would hide.
+type middlewareProcessor struct {
+    Processor
+    inner *processor
+}
+
+func (wrapped *middlewareProcessor) SetNLPRateLimit(rps float64, burst int) {
+    wrapped.inner.SetNLPRateLimit(rps, burst)
+}
+
+func (wrapped *middlewareProcessor) CheckNLPHealth(ctx context.Context) error {
+    return wrapped.inner.CheckNLPHealth(ctx)
+}
+
+func (wrapped *middlewareProcessor) Stop() {
+    wrapped.inner.Stop()
+}
+
+// Returned by the WithTimeout middleware when processing takes too long.
+var ErrProcessingTimeout = errors.New("page processing timed out")
+
+// Applies the middlewares so that the first one is the outermost.
+func chainMiddleware(processor Processor, middlewares ...ProcessorMiddleware) Processor {
+    for i := len(middlewares) - 1; i >= 0; i-- {
+        processor = middlewares[i](processor)
+    }
+    return processor
+}
+
+// Logs the outcome and duration of every call at debug level.
+func WithLogging() ProcessorMiddleware {
+    return func(next Processor) Processor {
+        return ProcessorFunc(func(pageData *models.PageData, doc *models.Document) error {
+            start := time.Now()
+            err := next.Process(pageData, doc)
+            logger.Log.Debug("Processed page data",
+                zap.String("url", pageData.URL),
+                zap.Duration("duration", time.Since(start)),
+                zap.Error(err))
+            return err
+        })
+    }
+}
+
+// Records the latency and error count of every call.
+func WithMetrics() ProcessorMiddleware {
+    return func(next Processor) Processor {
+        return ProcessorFunc(func(pageData *models.PageData, doc *models.Document) error {
+            start := time.Now()
+            err := next.Process(pageData, doc)
+            metrics.ProcessLatency.Observe(time.Since(start).Seconds())
+            if err != nil {
+                metrics.ProcessErrors.Inc()
+            }
+            return err
+        })
+    }
+}
+
+// Returns ErrProcessingTimeout if the wrapped processor does not finish within d.
+// The underlying call is not interrupted and may keep writing to its arguments,
+// so callers should discard them after a timeout.
+func WithTimeout(d time.Duration) ProcessorMiddleware {
+    return func(next Processor) Processor {
+        return ProcessorFunc(func(pageData *models.PageData, doc *models.Document) error {
+            done := make(chan error, 1)
+            go func() {
+                done <- next.Process(pageData, doc)
+            }()
+
+            timer := time.NewTimer(d)
+            defer timer.Stop()
+            select {
+            case err := <-done:
+                return err
+            case <-timer.C:
+                return ErrProcessingTimeout
+            }
+        })
+    }
+}
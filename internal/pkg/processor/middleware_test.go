@@ -0,0 +1,104 @@
+package processor
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+	"indexer/internal/pkg/models"
+)
+
+// Returns a middleware that records its name before and after the call.
+func recordingMiddleware(name string, calls *[]string) ProcessorMiddleware {
+	return func(next Processor) Processor {
+		return ProcessorFunc(func(pageData *models.PageData, doc *models.Document) error {
+			*calls = append(*calls, name+" before")
+			err := next.Process(pageData, doc)
+			*calls = append(*calls, name+" after")
+			return err
+		})
+	}
+}
+
+// Verifies that the first middleware wraps all the others.
+func TestChainMiddlewareOrder(t *testing.T) {
+	var calls []string
+	inner := ProcessorFunc(func(pageData *models.PageData, doc *models.Document) error {
+		calls = append(calls, "processor")
+		return nil
+	})
+
+	proc := chainMiddleware(inner, recordingMiddleware("outer", &calls), recordingMiddleware("inner", &calls))
+	if err := proc.Process(&models.PageData{}, &models.Document{}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := []string{"outer before", "inner before", "processor", "inner after", "outer after"}
+	if !reflect.DeepEqual(calls, expected) {
+		t.Errorf("Expected call order %v, got %v", expected, calls)
+	}
+}
+
+// Verifies that WithTimeout gives up on slow processors but passes fast results through.
+func TestWithTimeout(t *testing.T) {
+	failure := errors.New("rejected")
+	fast := WithTimeout(time.Second)(ProcessorFunc(func(pageData *models.PageData, doc *models.Document) error {
+		return failure
+	}))
+	if err := fast.Process(&models.PageData{}, &models.Document{}); !errors.Is(err, failure) {
+		t.Errorf("Expected %v, got %v", failure, err)
+	}
+
+	release := make(chan struct{})
+	defer close(release)
+	slow := WithTimeout(20 * time.Millisecond)(ProcessorFunc(func(pageData *models.PageData, doc *models.Document) error {
+		<-release
+		return nil
+	}))
+	if err := slow.Process(&models.PageData{}, &models.Document{}); !errors.Is(err, ErrProcessingTimeout) {
+		t.Errorf("Expected ErrProcessingTimeout, got %v", err)
+	}
+}
+
+// Verifies that the logging and metrics middlewares return the wrapped result unchanged.
+func TestLoggingAndMetricsPassThrough(t *testing.T) {
+	failure := errors.New("rejected")
+	proc := chainMiddleware(ProcessorFunc(func(pageData *models.PageData, doc *models.Document) error {
+		doc.Title = "processed"
+		return failure
+	}), WithLogging(), WithMetrics())
+
+	doc := &models.Document{}
+	if err := proc.Process(&models.PageData{URL: "https://example.com"}, doc); !errors.Is(err, failure) {
+		t.Errorf("Expected %v, got %v", failure, err)
+	}
+	if doc.Title != "processed" {
+		t.Errorf("Expected wrapped processor to run, got title %q", doc.Title)
+	}
+}
+
+// Verifies that a processor built with middlewares still exposes the
+// optional interfaces of the processor it wraps.
+func TestNewProcessorWithMiddlewareForwardsInterfaces(t *testing.T) {
+	var calls []string
+	proc := NewProcessor(&failingDeduper{}, ProcessorConfig{NLPServiceURL: "http://127.0.0.1:0"}, recordingMiddleware("outer", &calls))
+
+	limiter, ok := proc.(NLPRateLimiter)
+	if !ok {
+		t.Fatal("Expected processor with middleware to implement NLPRateLimiter")
+	}
+	limiter.SetNLPRateLimit(5, 1)
+	if _, ok := proc.(NLPHealthChecker); !ok {
+		t.Error("Expected processor with middleware to implement NLPHealthChecker")
+	}
+	stopper, ok := proc.(Stopper)
+	if !ok {
+		t.Fatal("Expected processor with middleware to implement Stopper")
+	}
+	defer stopper.Stop()
+
+	proc.Process(&models.PageData{URL: "not a url"}, &models.Document{})
+	if len(calls) != 2 {
+		t.Errorf("Expected Process to run through the middleware, got calls %v", calls)
+	}
+}
@@ -0,0 +1,90 @@
+package processor
+
+import (
+    "errors"
+    "fmt"
+    "runtime/debug"
+    "sync"
+    "go.uber.org/zap"
+    "indexer/internal/pkg/logger"
+    "indexer/internal/pkg/models"
+)
+
+// Default number of pages of a batch processed at the same time
+const DefaultBatchConcurrency = 4
+
+// Outcome of processing a single page of a batch.
+type ProcessResult struct {
+    Document models.Document
+    Err      error
+}
+
+// Implemented by processors that can process several pages in one call.
+type BatchingProcessor interface {
+    Processor
+    ProcessBatch(items []*models.PageData) ([]ProcessResult, error)
+}
+
+// Processes the pages of a batch in parallel with a wrapped Processor.
+type concurrentProcessor struct {
+    Processor
+    concurrency int
+}
+
+// Wraps proc so that batches are processed with at most concurrency pages
+// in flight. A concurrency below 1 uses DefaultBatchConcurrency.
+func NewConcurrentProcessor(proc Processor, concurrency int) BatchingProcessor {
+    if concurrency < 1 {
+        concurrency = DefaultBatchConcurrency
+    }
+    return &concurrentProcessor{Processor: proc, concurrency: concurrency}
+}
+
+// Processes every item, returning one result per item in the same order.
+// The error joins the per-item errors and is nil when every item succeeded.
+func (cp *concurrentProcessor) ProcessBatch(items []*models.PageData) ([]ProcessResult, error) {
+    results := make([]ProcessResult, len(items))
+    semaphore := make(chan struct{}, cp.concurrency)
+
+    var wg sync.WaitGroup
+    for i, item := range items {
+        wg.Add(1)
+        semaphore <- struct{}{}
+        go func() {
+            defer wg.Done()
+            defer func() { <-semaphore }()
+            // A panic here cannot be recovered by the caller, so fail the item instead
+            defer func() {
+                if r := recover(); r != nil {
+                    logger.Log.Error("Processor panicked",
+                        zap.String("url", item.URL),
+                        zap.Any("panic", r),
+                        zap.ByteString("stack", debug.Stack()))
+                    results[i].Err = fmt.Errorf("processor panicked: %v", r)
+                }
+            }()
+            results[i].Err = cp.Process(item, &results[i].Document)
+        }()
+    }
+    wg.Wait()
+
+    // Aggregate spam detection across the batch
+    var errs []error
+    spamPages, maxSpamScore := 0, 0
+    for _, result := range results {
+        if result.Err != nil {
+            errs = append(errs, result.Err)
+        }
+        if result.Document.SpamScore > 0 {
+            spamPages++
+            maxSpamScore = max(maxSpamScore, result.Document.SpamScore)
+        }
+    }
+    logger.Log.Debug("Processed batch",
+        zap.Int("size", len(items)),
+        zap.Int("failed", len(errs)),
+        zap.Int("spam_pages", spamPages),
+        zap.Int("max_spam_score", maxSpamScore))
+
+    return results, errors.Join(errs...)
+}
@@ -0,0 +1,88 @@
+package processor
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+	"indexer/internal/pkg/models"
+)
+
+// Verifies that results line up with their items and failures are joined.
+func TestConcurrentProcessorResults(t *testing.T) {
+	failure := errors.New("rejected")
+	proc := NewConcurrentProcessor(ProcessorFunc(func(pageData *models.PageData, doc *models.Document) error {
+		if pageData.URL == "bad" {
+			return failure
+		}
+		doc.URL = pageData.URL
+		return nil
+	}), 2)
+
+	items := []*models.PageData{{URL: "a"}, {URL: "bad"}, {URL: "c"}}
+	results, err := proc.ProcessBatch(items)
+	if !errors.Is(err, failure) {
+		t.Errorf("Expected joined error to contain %v, got %v", failure, err)
+	}
+	if len(results) != len(items) {
+		t.Fatalf("Expected %d results, got %d", len(items), len(results))
+	}
+	if results[0].Document.URL != "a" || results[0].Err != nil {
+		t.Errorf("Unexpected first result: %+v", results[0])
+	}
+	if !errors.Is(results[1].Err, failure) {
+		t.Errorf("Expected second item to fail, got %v", results[1].Err)
+	}
+	if results[2].Document.URL != "c" || results[2].Err != nil {
+		t.Errorf("Unexpected third result: %+v", results[2])
+	}
+
+	if _, err := proc.ProcessBatch([]*models.PageData{{URL: "a"}}); err != nil {
+		t.Errorf("Expected no error when every item succeeds, got %v", err)
+	}
+}
+
+// Verifies that no more than the configured number of items run at once.
+func TestConcurrentProcessorLimit(t *testing.T) {
+	var inFlight, peak int32
+	proc := NewConcurrentProcessor(ProcessorFunc(func(pageData *models.PageData, doc *models.Document) error {
+		current := atomic.AddInt32(&inFlight, 1)
+		for {
+			seen := atomic.LoadInt32(&peak)
+			if current <= seen || atomic.CompareAndSwapInt32(&peak, seen, current) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&inFlight, -1)
+		return nil
+	}), 2)
+
+	items := make([]*models.PageData, 8)
+	for i := range items {
+		items[i] = &models.PageData{}
+	}
+	proc.ProcessBatch(items)
+
+	if peak != 2 {
+		t.Errorf("Expected at most 2 items in flight, got %d", peak)
+	}
+}
+
+// Verifies that a panicking item fails on its own instead of crashing the batch.
+func TestConcurrentProcessorPanic(t *testing.T) {
+	proc := NewConcurrentProcessor(ProcessorFunc(func(pageData *models.PageData, doc *models.Document) error {
+		if pageData.URL == "crash" {
+			panic("processor crashed")
+		}
+		return nil
+	}), 0)
+
+	results, err := proc.ProcessBatch([]*models.PageData{{URL: "crash"}, {URL: "ok"}})
+	if err == nil || results[0].Err == nil {
+		t.Errorf("Expected the panicking item to fail, got %v", results[0].Err)
+	}
+	if results[1].Err != nil {
+		t.Errorf("Expected the other item to succeed, got %v", results[1].Err)
+	}
+}
@@ -1,15 +1,20 @@
 package processor
 
 import (
+    "context"
     "errors"
-    "net/url"
+    "net/http"
+    "strconv"
     "strings"
     "log"
+    "sync"
 	"time"
 	"go.uber.org/zap"
 	"github.com/pemistahl/lingua-go"
 	"indexer/internal/pkg/logger"
 	"indexer/internal/pkg/deduplicator"
+	"indexer/internal/pkg/normalize"
+	"indexer/internal/pkg/processor/contenttype"
 	"indexer/internal/pkg/processor/languagedetector"
 	"indexer/internal/pkg/processor/spamdetector"
     "indexer/internal/pkg/models"
@@ -23,56 +28,197 @@ type Processor interface {
 	Process(pageData *models.PageData, doc *models.Document) error
 }
 
+// Implemented by processors whose NLP request rate can be tuned at runtime.
+type NLPRateLimiter interface {
+	SetNLPRateLimit(rps float64, burst int)
+}
+
+// Implemented by processors that depend on the NLP service being reachable.
+type NLPHealthChecker interface {
+	CheckNLPHealth(ctx context.Context) error
+}
+
+// Implemented by processors holding background resources that must be
+// released on shutdown.
+type Stopper interface {
+	Stop()
+}
+
 // The default implementation of Processor.
 type processor struct {
 	deduper  deduper.Deduper
 	enricher Enricher
 	spamDetector *spamdetector.SpamDetector
+	spamEvents spamdetector.SpamEventWriter
+	batchProcessor *BatchProcessor
+	skipDomains map[string]struct{}
+	robots RobotsChecker // nil skips robots.txt checks
+
+	// Built on first use unless injected, see detector
+	languageDetector lingua.LanguageDetector
+	detectorOnce     sync.Once
+}
+
+// Reports whether robots.txt allows a page to be indexed.
+type RobotsChecker interface {
+    Allowed(pageURL string) bool
+}
+
+// Settings for NewProcessor. Zero values fall back to the defaults noted
+// on each field.
+type ProcessorConfig struct {
+    NLPServiceURL string
+    SpamThreshold int
+    BatchSize     int           // NLP batch size, defaultNLPBatchSize if zero
+    BatchTimeout  time.Duration // NLP batch timeout, defaultNLPBatchTimeout if zero
+    MaxBatchWait  time.Duration // longest a page waits in an NLP batch, no bound if zero
+    MaxTextBytes  int           // visible text sent to the NLP service is cut to this size, zero for no limit
+
+    // Spam rejections are recorded here, NoopSpamEventWriter if nil
+    SpamEvents spamdetector.SpamEventWriter
+
+    // Keyword to categories mapping, categorization is skipped if empty
+    Categories map[string][]string
+
+    // Extract keywords locally while the NLP service is unavailable
+    LocalNLPFallback bool
+
+    // Summaries are only requested for pages with at least this many words
+    SummarizeMinWords int
+
+    // Pages from these domains, or their subdomains, are always rejected
+    SkipDomains []string
+
+    // NLP batches sent concurrently, one if zero
+    NLPWorkers int
+
+    // Pages disallowed by their host's robots.txt are rejected, unchecked if nil
+    Robots RobotsChecker
+
+    // Notified whenever the NLP circuit breaker opens, none if empty
+    CircuitBreakerWebhookURL string
 }
 
 // Creates a new Processor instance and wires in the sub‑components.
-func NewProcessor(deduper deduper.Deduper, nlpServiceURL string, spamThreshold int) Processor {
-    return &processor{
+// Any middlewares are applied around the processor, the first being outermost.
+func NewProcessor(deduper deduper.Deduper, cfg ProcessorConfig, middlewares ...ProcessorMiddleware) Processor {
+    spamEvents := cfg.SpamEvents
+    if spamEvents == nil {
+        spamEvents = spamdetector.NoopSpamEventWriter{}
+    }
+    batchSize := cfg.BatchSize
+    if batchSize <= 0 {
+        batchSize = defaultNLPBatchSize
+    }
+    batchTimeout := cfg.BatchTimeout
+    if batchTimeout <= 0 {
+        batchTimeout = defaultNLPBatchTimeout
+    }
+    batchProcessor := NewBatchProcessor(cfg.NLPServiceURL, batchSize, batchTimeout,
+        WithNumWorkers(cfg.NLPWorkers),
+        WithMaxWaitTime(cfg.MaxBatchWait),
+        WithCircuitBreakerWebhook(cfg.CircuitBreakerWebhookURL))
+
+    // Categories are derived from keywords, so run after the NLP enricher
+    var fallback *LocalFallbackEnricher
+    if cfg.LocalNLPFallback {
+        fallback = NewLocalFallbackEnricher()
+    }
+    enrichers := []Enricher{&nlpEnricher{
+        batchProcessor:    batchProcessor,
+        fallback:          fallback,
+        summarizeMinWords: cfg.SummarizeMinWords,
+        maxTextBytes:      cfg.MaxTextBytes,
+    }}
+    if len(cfg.Categories) > 0 {
+        enrichers = append(enrichers, NewCategoryEnricher(cfg.Categories))
+    }
+
+    proc := &processor{
         deduper:  deduper,
-        enricher: NewNLPEnricher(nlpServiceURL),
-		spamDetector: spamdetector.NewSpamDetector(spamThreshold),
+        enricher: NewChainedEnricher(false, enrichers...),
+		spamDetector: spamdetector.NewSpamDetector(spamdetector.DefaultSpamPhrases(), cfg.SpamThreshold),
+		spamEvents: spamEvents,
+		batchProcessor: batchProcessor,
+		skipDomains: newDomainSet(cfg.SkipDomains),
+		robots: cfg.Robots,
+    }
+    if len(middlewares) == 0 {
+        return proc
     }
+    return &middlewareProcessor{Processor: chainMiddleware(proc, middlewares...), inner: proc}
+}
+
+// Updates the rate limit applied to NLP batch requests.
+func (processor *processor) SetNLPRateLimit(rps float64, burst int) {
+	processor.batchProcessor.SetRateLimit(rps, burst)
 }
 
-// Global language detector singleton to avoid repeated initialization
-var languageDetector lingua.LanguageDetector
+// Verifies the NLP service is up before any work is submitted to it.
+func (processor *processor) CheckNLPHealth(ctx context.Context) error {
+	return processor.batchProcessor.HealthCheck(ctx)
+}
 
-// Initializes the language detector once
-func init() {
-	// Build the detector with preloaded models for better performance
-	languageDetector = lingua.NewLanguageDetectorBuilder().
-	FromAllLanguages().
-	WithPreloadedLanguageModels().
-	Build()
+// Stops the enrichers, shutting down the NLP batch processor, and sends
+// any spam events still queued.
+func (processor *processor) Stop() {
+	processor.enricher.Stop()
+	if stopper, ok := processor.spamEvents.(Stopper); ok {
+		stopper.Stop()
+	}
+}
+
+// Returns the processor's language detector, building it on first use.
+// Language models are cached by lingua, so only the first build is slow.
+func (processor *processor) detector() lingua.LanguageDetector {
+	processor.detectorOnce.Do(func() {
+		if processor.languageDetector == nil {
+			// Build the detector with preloaded models for better performance
+			processor.languageDetector = lingua.NewLanguageDetectorBuilder().
+			FromAllLanguages().
+			WithPreloadedLanguageModels().
+			Build()
+		}
+	})
+	return processor.languageDetector
 }
 
 // Runs the data processing pipeline:
 // cleaning/normalization, deduplication, and enrichment.
+//
+// Each step owns the fields it writes, and later steps only read them:
+//   - cleanAndNormalize owns URL, CanonicalURL, VisibleText and the links,
+//     writing the normalized values back to pageData so every later step
+//     sees the same form
+//   - detectLanguage owns pageData.Language
+//   - detectSpam owns doc.SpamScore
+//   - the enrichers copy the normalized pageData fields onto doc and own
+//     the NLP and scoring fields
 func (processor *processor) Process(pageData *models.PageData, doc *models.Document) error {
     
 	// Clean & normalize
-    if err := cleanAndNormalize(pageData, doc); err != nil {
+    if err := cleanAndNormalize(pageData, doc, processor.skipDomains, processor.robots); err != nil {
         return err
     }
 
-	// Dedup check
-	signature := deduper.GenerateSignature(pageData.VisibleText)
-	if processor.deduper.IsDuplicate(signature) {
-		return errors.New("duplicate page detected")
-	}
+	// Dedup check, skipped for pages too short to have a signature
+	if signature := deduper.GenerateSignature(pageData.VisibleText); signature != "" {
+		if processor.deduper.IsDuplicate(signature) {
+			return errors.New("duplicate page detected")
+		}
 
-	// Store signature
-	processor.deduper.StoreSignature(signature)
+		// Store signature. A failed store only means the page may be
+		// processed again later, so carry on.
+		if err := processor.deduper.StoreSignature(signature); err != nil {
+			logger.Log.Warn("Failed to store page signature", zap.String("url", pageData.URL), zap.Error(err))
+		}
+	}
 
 	// Language detection
-	if err := detectLanguage(pageData); err != nil {
+	if err := detectLanguage(processor.detector(), pageData); err != nil {
 		return err
 	}
+	metrics.LanguageBreakdown.WithLabelValues(pageData.Language).Inc()
 	
 	// Spam detection
 	if err := processor.detectSpam(pageData, doc); err != nil {
@@ -105,83 +251,91 @@ func (processor *processor) Process(pageData *models.PageData, doc *models.Docum
 
 // Applies cleaning, URL normalization, language detection,
 // and spam filtering. It updates the PageData and Document in place.
-func cleanAndNormalize(pageData *models.PageData, doc *models.Document) error {
-	// Basic HTML cleanup.
-	doc.VisibleText = basicHTMLCleanup(pageData.VisibleText)
+// Pages from any of skipDomains are rejected, as are pages robots
+// disallows when robots is non-nil.
+func cleanAndNormalize(pageData *models.PageData, doc *models.Document, skipDomains map[string]struct{}, robots RobotsChecker) error {
+	// Only successful responses are indexed. A zero code means the crawler didn't report one.
+	if pageData.HTTPStatusCode != 0 && pageData.HTTPStatusCode != http.StatusOK {
+		metrics.NonIndexableStatusCodes.WithLabelValues(strconv.Itoa(pageData.HTTPStatusCode)).Inc()
+		logger.Log.Info("Skipping page with non-indexable status code",
+			zap.String("url", pageData.URL),
+			zap.Int("status_code", pageData.HTTPStatusCode))
+		return ErrNonIndexableStatus{Code: pageData.HTTPStatusCode}
+	}
+
+	// Respect the page's robots meta tag.
+	if hasNoIndex(pageData.Robots) {
+		return ErrRobotsNoIndex
+	}
 
-	// Normalize primary URL.
-	var err error
-	doc.URL, err = normalizeURL(pageData.URL)
+	// Partially fetched pages are still indexed, keeping the error for diagnostics.
+	if pageData.FetchError != "" {
+		logger.Log.Info("Indexing page with fetch error",
+			zap.String("url", pageData.URL),
+			zap.String("fetch_error", pageData.FetchError))
+	}
+
+	// Reject skipped domains before walking the page text.
+	if domain, skipped := skippedDomain(strings.TrimSpace(pageData.URL), skipDomains); skipped {
+		recordSkippedDomain(domain)
+		logger.Log.Info("Skipping page from skipped domain",
+			zap.String("url", pageData.URL),
+			zap.String("domain", domain))
+		return ErrSkippedDomain
+	}
+
+	// Basic HTML cleanup and URL normalization.
+	normalized, err := normalize.Normalize(*pageData)
 	if err != nil {
 		log.Printf("invalid URL %q: %v", pageData.URL, err)
 		return err
 	}
 
-	// Normalize canonical URL if valid.
-	if canonical, err := normalizeURL(pageData.CanonicalURL); err == nil {
-		pageData.CanonicalURL = canonical
+	// Later steps read these from pageData, so keep it in the normalized form.
+	pageData.URL = normalized.URL
+	pageData.VisibleText = normalized.VisibleText
+	doc.VisibleText = normalized.VisibleText
+	if doc.VisibleText == "" {
+		return ErrEmptyContent
 	}
+	if !contenttype.IsTextContent(doc.VisibleText) {
+		metrics.BinaryContentSkipped.Inc()
+		logger.Log.Info("Skipping page with binary content", zap.String("url", pageData.URL))
+		return ErrBinaryContent
+	}
+	doc.URL = normalized.URL
 
-	// Normalize internal and external links.
-	pageData.InternalLinks = normalizeURLs(pageData.InternalLinks)
-	pageData.ExternalLinks = normalizeURLs(pageData.ExternalLinks)
-
-	return nil
-}
+	if robots != nil && !robots.Allowed(doc.URL) {
+		metrics.RobotsTxtDisallowed.Inc()
+		logger.Log.Info("Skipping page disallowed by robots.txt", zap.String("url", doc.URL))
+		return ErrRobotsTxtDisallowed
+	}
 
-// Removes extra whitespace and newlines.
-func basicHTMLCleanup(input string) string {
-	return strings.Join(strings.Fields(strings.TrimSpace(input)), " ")
-}
+	pageData.CanonicalURL = normalized.CanonicalURL
+	pageData.InternalLinks = normalized.InternalLinks
+	pageData.ExternalLinks = normalized.ExternalLinks
 
-// Trims, parses, and normalizes a URL.
-func normalizeURL(rawURL string) (string, error) {
-    rawURL = strings.TrimSpace(rawURL)
-    if rawURL == "" {
-        return "", errors.New("empty URL")
-    }
-    
-    // Handle relative URLs
-    if !strings.Contains(rawURL, "://") && !strings.HasPrefix(rawURL, "//") {
-        return "", errors.New("relative URL without base")
-    }
-    
-    // Handle scheme-relative URLs (starting with //)
-    if strings.HasPrefix(rawURL, "//") {
-        rawURL = "https:" + rawURL
-    }
-    
-    parsedURL, err := url.Parse(rawURL)
-    if err != nil {
-        return "", err
-    }
-    
-    // Ensure scheme is set
-    if parsedURL.Scheme == "" {
-        parsedURL.Scheme = "https"
-    }
-    
-    parsedURL.Scheme = strings.ToLower(parsedURL.Scheme)
-    parsedURL.Host = strings.ToLower(parsedURL.Host)
-    return parsedURL.String(), nil
+	return nil
 }
 
-// Processes a slice of URLs and returns only those that are valid.
-func normalizeURLs(urls []string) []string {
-	var result []string
-	for _, link := range urls {
-		if normalized, err := normalizeURL(link); err == nil {
-			result = append(result, normalized)
+// Reports whether a robots meta tag value forbids indexing.
+func hasNoIndex(robots string) bool {
+	for _, directive := range strings.Split(robots, ",") {
+		switch strings.ToLower(strings.TrimSpace(directive)) {
+		case "noindex", "none":
+			return true
 		}
 	}
-	return result
+	return false
 }
 
 // Detects the language of the visible text and updates the PageData.
-func detectLanguage(pageData *models.PageData) error {
+func detectLanguage(detector lingua.LanguageDetector, pageData *models.PageData) error {
     start := time.Now()
 
-	lang, err := languagedetector.DetectLanguage(languageDetector, pageData.VisibleText)
+	// Meta keywords are usually written in the page's language, which helps
+	// on pages with little visible text
+	lang, err := languagedetector.DetectLanguage(detector, pageData.VisibleText, pageData.MetaKeywords)
 
     metrics.LanguageDetectionLatency.Observe(time.Since(start).Seconds())
     
@@ -211,6 +365,7 @@ func (processor *processor) detectSpam(pageData *models.PageData, doc *models.Do
 	
 	// Store spam score and matched phrases in the document
 	doc.SpamScore = spamResult.Score
+	doc.SpamSignals = spamResult.Phrases
 	
 	logger.Log.Debug("Spam detection result", 
 		zap.String("url", pageData.URL),
@@ -223,6 +378,16 @@ func (processor *processor) detectSpam(pageData *models.PageData, doc *models.Do
 		logger.Log.Info("Skipping high spam content", 
 			zap.String("url", pageData.URL), 
 			zap.Int("spam_score", spamResult.Score))
+
+		event := spamdetector.SpamEvent{
+			URL:       pageData.URL,
+			Score:     spamResult.Score,
+			Phrases:   spamResult.Phrases,
+			Timestamp: time.Now(),
+		}
+		if err := processor.spamEvents.Write(event); err != nil {
+			logger.Log.Warn("Failed to record spam event", zap.String("url", pageData.URL), zap.Error(err))
+		}
 		return errors.New("high spam content detected, skipping")
 	}
 
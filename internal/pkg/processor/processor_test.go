@@ -0,0 +1,389 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"github.com/pemistahl/lingua-go"
+	"indexer/internal/pkg/metrics"
+	"indexer/internal/pkg/models"
+	"indexer/internal/pkg/processor/spamdetector"
+)
+
+// Verifies that only pages with a 200 (or unreported) status code pass cleanup.
+func TestCleanAndNormalizeStatusCode(t *testing.T) {
+	tests := []struct {
+		code    int
+		allowed bool
+	}{
+		{0, true},
+		{200, true},
+		{301, false},
+		{404, false},
+		{500, false},
+	}
+
+	for _, tc := range tests {
+		pageData := &models.PageData{URL: "https://example.com/page", VisibleText: "Some content", HTTPStatusCode: tc.code}
+		err := cleanAndNormalize(pageData, &models.Document{}, nil, nil)
+
+		if tc.allowed {
+			if err != nil {
+				t.Errorf("Expected status %d to be allowed, got %v", tc.code, err)
+			}
+			continue
+		}
+
+		var statusErr ErrNonIndexableStatus
+		if !errors.As(err, &statusErr) {
+			t.Errorf("Expected ErrNonIndexableStatus for status %d, got %v", tc.code, err)
+		} else if statusErr.Code != tc.code {
+			t.Errorf("Expected error code %d, got %d", tc.code, statusErr.Code)
+		}
+	}
+}
+
+// Verifies that pages with nothing left after cleanup are rejected.
+func TestCleanAndNormalizeEmptyContent(t *testing.T) {
+	for _, text := range []string{"", "   ", "\n\t \n"} {
+		pageData := &models.PageData{URL: "https://example.com/blank", VisibleText: text}
+		if err := cleanAndNormalize(pageData, &models.Document{}, nil, nil); !errors.Is(err, ErrEmptyContent) {
+			t.Errorf("Expected ErrEmptyContent for %q, got %v", text, err)
+		}
+	}
+}
+
+// Verifies that pages whose visible text is binary data are rejected.
+func TestCleanAndNormalizeBinaryContent(t *testing.T) {
+	pageData := &models.PageData{URL: "https://example.com/file.pdf", VisibleText: "%PDF-1.4\x00\x01\x02\x03\x04\x05\x06\x07\x08\x0e\x0f"}
+	if err := cleanAndNormalize(pageData, &models.Document{}, nil, nil); !errors.Is(err, ErrBinaryContent) {
+		t.Errorf("Expected ErrBinaryContent, got %v", err)
+	}
+}
+
+// Verifies that pages with a fetch error are still let through.
+func TestCleanAndNormalizeFetchError(t *testing.T) {
+	pageData := &models.PageData{URL: "https://example.com/partial", VisibleText: "Some content", FetchError: "unexpected EOF"}
+	if err := cleanAndNormalize(pageData, &models.Document{}, nil, nil); err != nil {
+		t.Errorf("Expected partially fetched page to pass, got %v", err)
+	}
+}
+
+// Verifies that pages with a noindex robots directive are rejected.
+func TestCleanAndNormalizeRobotsNoIndex(t *testing.T) {
+	tests := []struct {
+		robots  string
+		allowed bool
+	}{
+		{"", true},
+		{"index,follow", true},
+		{"nofollow", true},
+		{"noindex", false},
+		{"noindex,nofollow", false},
+		{"NOINDEX, follow", false},
+		{"none", false},
+	}
+
+	for _, tc := range tests {
+		pageData := &models.PageData{URL: "https://example.com/page", VisibleText: "Some content", Robots: tc.robots}
+		err := cleanAndNormalize(pageData, &models.Document{}, nil, nil)
+		if tc.allowed && err != nil {
+			t.Errorf("Expected robots %q to be allowed, got %v", tc.robots, err)
+		}
+		if !tc.allowed && !errors.Is(err, ErrRobotsNoIndex) {
+			t.Errorf("Expected ErrRobotsNoIndex for robots %q, got %v", tc.robots, err)
+		}
+	}
+}
+
+// recordingSpamEventWriter keeps every event it is given.
+type recordingSpamEventWriter struct {
+	events []spamdetector.SpamEvent
+}
+
+func (w *recordingSpamEventWriter) Write(event spamdetector.SpamEvent) error {
+	w.events = append(w.events, event)
+	return nil
+}
+
+// Verifies that rejected spam pages are reported to the event writer.
+func TestDetectSpamWritesEvent(t *testing.T) {
+	writer := &recordingSpamEventWriter{}
+	proc := &processor{spamDetector: spamdetector.NewSpamDetector(spamdetector.DefaultSpamPhrases(), 1), spamEvents: writer}
+
+	pageData := &models.PageData{URL: "https://example.com/offer", VisibleText: "Act now and get rich quick!"}
+	if err := proc.detectSpam(pageData, &models.Document{}); err == nil {
+		t.Fatal("Expected page to be rejected as spam")
+	}
+
+	if len(writer.events) != 1 {
+		t.Fatalf("Expected 1 spam event, got %d", len(writer.events))
+	}
+	event := writer.events[0]
+	if event.URL != pageData.URL {
+		t.Errorf("Expected event URL %q, got %q", pageData.URL, event.URL)
+	}
+	if event.Score <= 0 || len(event.Phrases) == 0 {
+		t.Errorf("Expected event to carry score and phrases, got %+v", event)
+	}
+
+	writer.events = nil
+	clean := &models.PageData{URL: "https://example.com/report", VisibleText: "The committee met on Tuesday."}
+	if err := proc.detectSpam(clean, &models.Document{}); err != nil {
+		t.Fatalf("Expected clean page to pass, got %v", err)
+	}
+	if len(writer.events) != 0 {
+		t.Errorf("Expected no spam events for a clean page, got %d", len(writer.events))
+	}
+}
+
+// Verifies that matched spam phrases are kept on pages below the threshold.
+func TestDetectSpamStoresSignals(t *testing.T) {
+	proc := &processor{spamDetector: spamdetector.NewSpamDetector(spamdetector.DefaultSpamPhrases(), 100), spamEvents: spamdetector.NoopSpamEventWriter{}}
+
+	doc := &models.Document{}
+	pageData := &models.PageData{URL: "https://example.com/offer", VisibleText: "Act now and get rich quick!"}
+	if err := proc.detectSpam(pageData, doc); err != nil {
+		t.Fatalf("Expected page below the threshold to pass, got %v", err)
+	}
+	if doc.SpamScore <= 0 || len(doc.SpamSignals) == 0 {
+		t.Errorf("Expected document to carry spam score and signals, got score %d and signals %v", doc.SpamScore, doc.SpamSignals)
+	}
+
+	clean := &models.Document{}
+	if err := proc.detectSpam(&models.PageData{VisibleText: "The committee met on Tuesday."}, clean); err != nil {
+		t.Fatalf("Expected clean page to pass, got %v", err)
+	}
+	if len(clean.SpamSignals) != 0 {
+		t.Errorf("Expected no spam signals for a clean page, got %v", clean.SpamSignals)
+	}
+}
+
+// Verifies that pages from skipped domains and their subdomains are rejected.
+func TestCleanAndNormalizeSkippedDomain(t *testing.T) {
+	skipDomains := newDomainSet([]string{" Spam.example ", "admin.internal.test", ""})
+	tests := []struct {
+		url     string
+		allowed bool
+	}{
+		{"https://example.com/page", true},
+		{"https://spam.example/offer", false},
+		{"https://WWW.SPAM.EXAMPLE/offer", false},
+		{"https://notspam.example/page", true},
+		{"https://admin.internal.test/users", false},
+		{"https://internal.test/page", true},
+		{"  https://spam.example/offer \t", false},
+	}
+
+	for _, tc := range tests {
+		pageData := &models.PageData{URL: tc.url, VisibleText: "Some content"}
+		err := cleanAndNormalize(pageData, &models.Document{}, skipDomains, nil)
+		if tc.allowed && err != nil {
+			t.Errorf("Expected %s to be allowed, got %v", tc.url, err)
+		}
+		if !tc.allowed && !errors.Is(err, ErrSkippedDomain) {
+			t.Errorf("Expected ErrSkippedDomain for %s, got %v", tc.url, err)
+		}
+	}
+
+	// Rejected before the content is inspected
+	pageData := &models.PageData{URL: "https://spam.example/file.pdf", VisibleText: "%PDF-1.4\x00\x01\x02\x03\x04\x05\x06\x07\x08\x0e\x0f"}
+	if err := cleanAndNormalize(pageData, &models.Document{}, skipDomains, nil); !errors.Is(err, ErrSkippedDomain) {
+		t.Errorf("Expected ErrSkippedDomain before the binary content check, got %v", err)
+	}
+}
+
+// Answers robots.txt checks from a fixed set of disallowed URLs.
+type stubRobotsChecker map[string]bool
+
+func (disallowed stubRobotsChecker) Allowed(pageURL string) bool {
+	return !disallowed[pageURL]
+}
+
+// Verifies that pages disallowed by robots.txt are rejected.
+func TestCleanAndNormalizeRobotsTxt(t *testing.T) {
+	robots := stubRobotsChecker{"https://example.com/private": true}
+
+	pageData := &models.PageData{URL: "https://example.com/private", VisibleText: "Some content"}
+	if err := cleanAndNormalize(pageData, &models.Document{}, nil, robots); !errors.Is(err, ErrRobotsTxtDisallowed) {
+		t.Errorf("Expected ErrRobotsTxtDisallowed, got %v", err)
+	}
+
+	pageData = &models.PageData{URL: "https://example.com/public", VisibleText: "Some content"}
+	if err := cleanAndNormalize(pageData, &models.Document{}, nil, robots); err != nil {
+		t.Errorf("Expected allowed page to pass, got %v", err)
+	}
+}
+
+// Verifies that only a bounded number of domains get their own metric label.
+func TestRecordSkippedDomainLabels(t *testing.T) {
+	for i := 0; i < maxSkippedDomainLabels+5; i++ {
+		recordSkippedDomain(fmt.Sprintf("domain%d.example", i))
+	}
+	if len(skippedDomainLabels) != maxSkippedDomainLabels {
+		t.Errorf("Expected %d labelled domains, got %d", maxSkippedDomainLabels, len(skippedDomainLabels))
+	}
+	if got := counterValue(metrics.SkippedDomains.WithLabelValues("other")); got < 5 {
+		t.Errorf("Expected overflow domains to be counted as other, got %v", got)
+	}
+}
+
+// failingDeduper never finds duplicates and fails to store signatures.
+type failingDeduper struct {
+	stored int
+}
+
+func (fd *failingDeduper) IsDuplicate(signature string) bool {
+	return false
+}
+
+func (fd *failingDeduper) StoreSignature(signature string) error {
+	fd.stored++
+	return errors.New("redis unavailable")
+}
+
+func (fd *failingDeduper) Clear() error {
+	return errors.New("redis unavailable")
+}
+
+func (fd *failingDeduper) Close() error {
+	return nil
+}
+
+// Verifies that a failed signature store does not stop processing.
+func TestProcessContinuesAfterStoreFailure(t *testing.T) {
+	dedup := &failingDeduper{}
+	proc := &processor{deduper: dedup}
+
+	// A non-English page stops at language detection, just after the store.
+	pageData := &models.PageData{
+		URL:         "https://example.com/fr",
+		VisibleText: "Bonjour à tous, ceci est une page entièrement rédigée en français pour le test.",
+	}
+	err := proc.Process(pageData, &models.Document{})
+
+	if dedup.stored != 1 {
+		t.Errorf("Expected signature store to be attempted once, got %d", dedup.stored)
+	}
+	if err == nil || strings.Contains(err.Error(), "redis unavailable") {
+		t.Errorf("Expected processing to continue to language detection, got %v", err)
+	}
+}
+
+// Verifies that pages too short for a signature bypass the deduper.
+func TestProcessSkipsDedupForShortText(t *testing.T) {
+	dedup := &failingDeduper{}
+	// A zero spam threshold rejects the page before it reaches enrichment
+	proc := &processor{
+		deduper:      dedup,
+		spamDetector: spamdetector.NewSpamDetector(spamdetector.DefaultSpamPhrases(), 0),
+		spamEvents:   spamdetector.NoopSpamEventWriter{},
+	}
+
+	proc.Process(&models.PageData{URL: "https://example.com/short", VisibleText: "Hello world"}, &models.Document{})
+
+	if dedup.stored != 0 {
+		t.Errorf("Expected no signature to be stored for short text, got %d", dedup.stored)
+	}
+}
+
+// Verifies that pages passing language detection are counted by language.
+func TestProcessCountsLanguage(t *testing.T) {
+	counter := metrics.LanguageBreakdown.WithLabelValues("en")
+	before := counterValue(counter)
+
+	// A zero spam threshold rejects the page before it reaches enrichment
+	proc := &processor{
+		deduper:      &failingDeduper{},
+		spamDetector: spamdetector.NewSpamDetector(spamdetector.DefaultSpamPhrases(), 0),
+		spamEvents:   spamdetector.NoopSpamEventWriter{},
+	}
+	pageData := &models.PageData{
+		URL:         "https://example.com/en",
+		VisibleText: "The committee reviewed the quarterly report and agreed on the agenda for next week.",
+	}
+	proc.Process(pageData, &models.Document{})
+
+	if got := counterValue(counter) - before; got != 1 {
+		t.Errorf("Expected one English document to be counted, got %v", got)
+	}
+}
+
+// Verifies that the indexed document keeps the normalized URL and text
+// through enrichment.
+func TestProcessKeepsNormalizedURL(t *testing.T) {
+	proc := &processor{
+		deduper:      &failingDeduper{},
+		enricher:     newTestNLPEnricher(t),
+		spamDetector: spamdetector.NewSpamDetector(spamdetector.DefaultSpamPhrases(), 100),
+		spamEvents:   spamdetector.NoopSpamEventWriter{},
+	}
+
+	pageData := &models.PageData{
+		URL:         "  HTTPS://Example.COM/page \t",
+		VisibleText: "  The committee reviewed the quarterly report\n and agreed on the agenda for next week.  ",
+	}
+	doc := &models.Document{}
+	if err := proc.Process(pageData, doc); err != nil {
+		t.Fatalf("Expected page to be processed, got %v", err)
+	}
+
+	if doc.URL != "https://example.com/page" {
+		t.Errorf("Expected normalized URL, got %q", doc.URL)
+	}
+	if doc.URL != pageData.URL {
+		t.Errorf("Expected page data URL %q to match document URL %q", pageData.URL, doc.URL)
+	}
+	expectedText := "The committee reviewed the quarterly report and agreed on the agenda for next week."
+	if doc.VisibleText != expectedText {
+		t.Errorf("Expected cleaned visible text, got %q", doc.VisibleText)
+	}
+}
+
+// Verifies that concurrent Process calls share the lazily built language
+// detector safely. Run with -race to catch unsynchronized access.
+func TestProcessParallel(t *testing.T) {
+	// Texts are too short for a signature, so the deduper is never used,
+	// and a zero spam threshold rejects every page before enrichment
+	proc := &processor{
+		spamDetector: spamdetector.NewSpamDetector(spamdetector.DefaultSpamPhrases(), 0),
+		spamEvents:   spamdetector.NoopSpamEventWriter{},
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			pageData := &models.PageData{URL: fmt.Sprintf("https://example.com/%d", i), VisibleText: "Good morning all"}
+			if err := proc.Process(pageData, &models.Document{}); err == nil {
+				t.Errorf("Expected page %d to be rejected as spam", i)
+			}
+			if pageData.Language == "" {
+				t.Errorf("Expected a language to be detected for page %d", i)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+// Verifies that meta keywords bias language detection towards their language.
+func TestDetectLanguageMetaKeywords(t *testing.T) {
+	detector := lingua.NewLanguageDetectorBuilder().FromLanguages(lingua.English, lingua.German).Build()
+
+	pageData := &models.PageData{URL: "https://example.com", VisibleText: "Hallo and welcome to the Kindergarten"}
+	if err := detectLanguage(detector, pageData); err != nil || pageData.Language != "en" {
+		t.Fatalf("Expected English without keywords, got %q, %v", pageData.Language, err)
+	}
+
+	pageData = &models.PageData{
+		URL:          "https://example.com",
+		VisibleText:  "Hallo and welcome to the Kindergarten",
+		MetaKeywords: "Nachrichten, Wetter, Deutschland, Bundesliga, Zeitung",
+	}
+	if err := detectLanguage(detector, pageData); err == nil {
+		t.Errorf("Expected German keywords to mark the page as non-English, got %q", pageData.Language)
+	}
+}
@@ -0,0 +1,69 @@
+package processor
+
+import (
+    "net/url"
+    "strings"
+    "sync"
+    "indexer/internal/pkg/metrics"
+)
+
+// Number of skipped domains that get their own metric label
+const maxSkippedDomainLabels = 20
+
+// Domains already labelled in metrics.SkippedDomains
+var (
+    skippedDomainLabelsMu sync.Mutex
+    skippedDomainLabels   = make(map[string]struct{})
+)
+
+// Builds a lookup set from a list of domains, ignoring case and blanks.
+func newDomainSet(domains []string) map[string]struct{} {
+    set := make(map[string]struct{}, len(domains))
+    for _, domain := range domains {
+        domain = strings.ToLower(strings.TrimSpace(domain))
+        if domain != "" {
+            set[domain] = struct{}{}
+        }
+    }
+    return set
+}
+
+// Reports whether the host of rawURL, or one of its parent domains, is in
+// skipDomains. Returns the matching entry.
+func skippedDomain(rawURL string, skipDomains map[string]struct{}) (string, bool) {
+    if len(skipDomains) == 0 {
+        return "", false
+    }
+    parsed, err := url.Parse(rawURL)
+    if err != nil {
+        return "", false
+    }
+
+    host := strings.ToLower(parsed.Hostname())
+    for host != "" {
+        if _, ok := skipDomains[host]; ok {
+            return host, true
+        }
+        _, parent, found := strings.Cut(host, ".")
+        if !found {
+            break
+        }
+        host = parent
+    }
+    return "", false
+}
+
+// Counts a skipped page, keeping the label cardinality bounded.
+func recordSkippedDomain(domain string) {
+    skippedDomainLabelsMu.Lock()
+    if _, ok := skippedDomainLabels[domain]; !ok {
+        if len(skippedDomainLabels) < maxSkippedDomainLabels {
+            skippedDomainLabels[domain] = struct{}{}
+        } else {
+            domain = "other"
+        }
+    }
+    skippedDomainLabelsMu.Unlock()
+
+    metrics.SkippedDomains.WithLabelValues(domain).Inc()
+}
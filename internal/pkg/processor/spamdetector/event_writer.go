@@ -0,0 +1,175 @@
+package spamdetector
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "sync"
+    "time"
+    "go.uber.org/zap"
+    "indexer/internal/pkg/esclient"
+    "indexer/internal/pkg/logger"
+    "indexer/internal/pkg/metrics"
+)
+
+// Records why a page was rejected as spam
+type SpamEvent struct {
+    URL       string    `json:"url"`
+    Score     int       `json:"score"`
+    Phrases   []string  `json:"phrases"`
+    Timestamp time.Time `json:"timestamp"`
+}
+
+// Persists spam events for later analysis
+type SpamEventWriter interface {
+    Write(event SpamEvent) error
+}
+
+// Discards every event
+type NoopSpamEventWriter struct{}
+
+func (NoopSpamEventWriter) Write(event SpamEvent) error {
+    return nil
+}
+
+// Events waiting to be written before further ones are dropped
+const spamEventBacklog = 1024
+
+// Events sent in one bulk request
+const spamEventBatchSize = 100
+
+// Longest an event waits before a partial batch is sent
+var spamEventFlushInterval = 5 * time.Second
+
+// Writes spam events as documents to an Elasticsearch index. Events are
+// queued and sent in bulk batches from a background goroutine, so callers
+// never wait on Elasticsearch. Call Stop to send the remaining events.
+type ElasticsearchSpamEventWriter struct {
+    client   *esclient.Client
+    bulkPath string
+    timeout  time.Duration
+
+    events   chan SpamEvent
+    stop     chan struct{}
+    stopOnce sync.Once
+    done     chan struct{} // closed once the background writer has exited
+}
+
+// Creates a new ElasticsearchSpamEventWriter that indexes events into
+// indexName through client, and starts its background writer.
+func NewElasticsearchSpamEventWriter(client *esclient.Client, indexName string) *ElasticsearchSpamEventWriter {
+    writer := &ElasticsearchSpamEventWriter{
+        client:   client,
+        bulkPath: "/" + indexName + "/_bulk",
+        timeout:  5 * time.Second,
+        events:   make(chan SpamEvent, spamEventBacklog),
+        stop:     make(chan struct{}),
+        done:     make(chan struct{}),
+    }
+    go writer.run()
+    return writer
+}
+
+// Queues an event to be indexed. Returns an error without blocking if the
+// backlog is full or the writer has stopped, in which case the event is dropped.
+func (writer *ElasticsearchSpamEventWriter) Write(event SpamEvent) error {
+    select {
+    case <-writer.stop:
+        metrics.SpamEventsDropped.Inc()
+        return errors.New("spam event writer is stopped")
+    default:
+    }
+
+    select {
+    case writer.events <- event:
+        return nil
+    default:
+        metrics.SpamEventsDropped.Inc()
+        return errors.New("spam event backlog is full")
+    }
+}
+
+// Stops the background writer once the queued events have been sent.
+func (writer *ElasticsearchSpamEventWriter) Stop() {
+    writer.stopOnce.Do(func() { close(writer.stop) })
+    <-writer.done
+}
+
+// Collects queued events into batches and sends them until stopped.
+func (writer *ElasticsearchSpamEventWriter) run() {
+    defer close(writer.done)
+    ticker := time.NewTicker(spamEventFlushInterval)
+    defer ticker.Stop()
+
+    batch := make([]SpamEvent, 0, spamEventBatchSize)
+    add := func(event SpamEvent) {
+        batch = append(batch, event)
+        if len(batch) >= spamEventBatchSize {
+            writer.send(batch)
+            batch = batch[:0]
+        }
+    }
+
+    for {
+        select {
+        case event := <-writer.events:
+            add(event)
+        case <-ticker.C:
+            writer.send(batch)
+            batch = batch[:0]
+        case <-writer.stop:
+            for {
+                select {
+                case event := <-writer.events:
+                    add(event)
+                default:
+                    writer.send(batch)
+                    return
+                }
+            }
+        }
+    }
+}
+
+// Indexes a batch of events in one bulk request. Failed batches are logged
+// and dropped.
+func (writer *ElasticsearchSpamEventWriter) send(batch []SpamEvent) {
+    if len(batch) == 0 {
+        return
+    }
+    if err := writer.sendBulk(batch); err != nil {
+        logger.Log.Warn("Failed to write spam events", zap.Int("count", len(batch)), zap.Error(err))
+        metrics.SpamEventsDropped.Add(float64(len(batch)))
+    }
+}
+
+// POSTs the events as NDJSON index actions to the index's bulk endpoint.
+func (writer *ElasticsearchSpamEventWriter) sendBulk(batch []SpamEvent) error {
+    var payload bytes.Buffer
+    for _, event := range batch {
+        line, err := json.Marshal(event)
+        if err != nil {
+            return fmt.Errorf("failed to marshal spam event: %w", err)
+        }
+        payload.WriteString(`{"index":{}}` + "\n")
+        payload.Write(line)
+        payload.WriteByte('\n')
+    }
+
+    ctx, cancel := context.WithTimeout(context.Background(), writer.timeout)
+    defer cancel()
+
+    resp, err := writer.client.Do(ctx, http.MethodPost, writer.bulkPath, "application/x-ndjson", payload.Bytes())
+    if err != nil {
+        return fmt.Errorf("failed to write spam events: %w", err)
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode >= 300 {
+        return fmt.Errorf("spam event write failed with status %d", resp.StatusCode)
+    }
+    return nil
+}
@@ -0,0 +1,156 @@
+package spamdetector
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+	dto "github.com/prometheus/client_model/go"
+	"indexer/internal/pkg/esclient"
+	"indexer/internal/pkg/metrics"
+)
+
+// Returns the current value of the dropped spam events counter.
+func droppedSpamEvents(t *testing.T) float64 {
+	t.Helper()
+	var metric dto.Metric
+	if err := metrics.SpamEventsDropped.Write(&metric); err != nil {
+		t.Fatalf("Failed to read metric: %v", err)
+	}
+	return metric.GetCounter().GetValue()
+}
+
+// Verifies that queued events are indexed in one bulk request into the
+// configured index once the writer stops.
+func TestElasticsearchSpamEventWriter(t *testing.T) {
+	var mu sync.Mutex
+	var paths []string
+	var received []SpamEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		paths = append(paths, r.URL.Path)
+		scanner := bufio.NewScanner(bytes.NewReader(body))
+		for line := 0; scanner.Scan(); line++ {
+			if line%2 == 0 {
+				continue // action line
+			}
+			var event SpamEvent
+			if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+				t.Errorf("Failed to decode spam event: %v", err)
+			}
+			received = append(received, event)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	writer := NewElasticsearchSpamEventWriter(esclient.New(server.URL+"/_bulk"), "spam_events")
+	events := []SpamEvent{
+		{URL: "https://example.com/a", Score: 20, Phrases: []string{"act now"}, Timestamp: time.Now()},
+		{URL: "https://example.com/b", Score: 30, Phrases: []string{"free money"}, Timestamp: time.Now()},
+	}
+	for _, event := range events {
+		if err := writer.Write(event); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	writer.Stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(paths) != 1 || paths[0] != "/spam_events/_bulk" {
+		t.Errorf("Expected one request to /spam_events/_bulk, got %v", paths)
+	}
+	if len(received) != len(events) {
+		t.Fatalf("Expected %d events, got %d", len(events), len(received))
+	}
+	for i, event := range events {
+		if received[i].URL != event.URL || received[i].Score != event.Score || len(received[i].Phrases) != 1 {
+			t.Errorf("Expected %+v, got %+v", event, received[i])
+		}
+	}
+}
+
+// Verifies that a rejected batch is counted as dropped.
+func TestElasticsearchSpamEventWriterFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	before := droppedSpamEvents(t)
+	writer := NewElasticsearchSpamEventWriter(esclient.New(server.URL), "spam_events")
+	if err := writer.Write(SpamEvent{URL: "https://example.com"}); err != nil {
+		t.Fatalf("Expected the event to be queued, got %v", err)
+	}
+	writer.Stop()
+
+	if dropped := droppedSpamEvents(t) - before; dropped != 1 {
+		t.Errorf("Expected 1 dropped event, got %v", dropped)
+	}
+}
+
+// Verifies that events are written with the client's basic auth credentials.
+func TestElasticsearchSpamEventWriterBasicAuth(t *testing.T) {
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, _ := r.BasicAuth()
+		auth = username + ":" + password
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	writer := NewElasticsearchSpamEventWriter(esclient.New(server.URL, esclient.WithBasicAuth("elastic", "secret")), "spam_events")
+	if err := writer.Write(SpamEvent{URL: "https://example.com"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	writer.Stop()
+
+	if auth != "elastic:secret" {
+		t.Errorf("Expected basic auth elastic:secret, got %q", auth)
+	}
+}
+
+// Verifies that Write never blocks: events beyond the backlog, or written
+// after Stop, are dropped with an error.
+func TestElasticsearchSpamEventWriterDrops(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	before := droppedSpamEvents(t)
+	writer := NewElasticsearchSpamEventWriter(esclient.New(server.URL), "spam_events")
+
+	// The first batch blocks the writer in the server, the rest fill the backlog
+	var rejected int
+	for i := 0; i < spamEventBatchSize+spamEventBacklog+10; i++ {
+		if err := writer.Write(SpamEvent{URL: "https://example.com"}); err != nil {
+			rejected++
+		}
+		if i == spamEventBatchSize-1 {
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+	if rejected == 0 {
+		t.Error("Expected writes beyond the backlog to be rejected")
+	}
+
+	close(release)
+	writer.Stop()
+	if err := writer.Write(SpamEvent{URL: "https://example.com"}); err == nil {
+		t.Error("Expected an error writing to a stopped writer")
+	}
+	if dropped := droppedSpamEvents(t) - before; dropped != float64(rejected+1) {
+		t.Errorf("Expected %d dropped events, got %v", rejected+1, dropped)
+	}
+}
@@ -0,0 +1,38 @@
+package spamdetector
+
+import (
+    "strings"
+    "unicode"
+    "golang.org/x/text/unicode/norm"
+)
+
+// Lowercase Cyrillic and Greek letters that look like Latin ones and are
+// commonly swapped in to dodge phrase matching
+var homoglyphs = map[rune]rune{
+    'а': 'a', 'в': 'b', 'е': 'e', 'к': 'k', 'м': 'm', 'н': 'h', 'о': 'o',
+    'р': 'p', 'с': 'c', 'т': 't', 'у': 'y', 'х': 'x', 'і': 'i', 'ј': 'j',
+    'ѕ': 's', 'ԁ': 'd', 'ԛ': 'q', 'ԝ': 'w',
+    'α': 'a', 'β': 'b', 'ε': 'e', 'ι': 'i', 'κ': 'k', 'ν': 'v', 'ο': 'o',
+    'ρ': 'p', 'τ': 't', 'υ': 'u', 'χ': 'x',
+}
+
+// Folds text to lowercase ASCII so obfuscated phrases still match. Known
+// homoglyphs are mapped to their Latin look-alikes, then the text is
+// decomposed with NFKD and anything left outside ASCII is dropped.
+func normalizeText(text string) string {
+    lower := strings.Map(func(r rune) rune {
+        if latin, ok := homoglyphs[r]; ok {
+            return latin
+        }
+        return r
+    }, strings.ToLower(text))
+
+    var builder strings.Builder
+    builder.Grow(len(lower))
+    for _, r := range norm.NFKD.String(lower) {
+        if r <= unicode.MaxASCII {
+            builder.WriteRune(unicode.ToLower(r))
+        }
+    }
+    return builder.String()
+}
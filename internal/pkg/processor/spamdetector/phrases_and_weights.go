@@ -114,4 +114,13 @@ var weights = map[string]int{
 	"you have won": 3,
 	"you win": 3,
 	"ozempic": 2,
+}
+
+// Returns the built-in spam phrases with their weights.
+func DefaultSpamPhrases() []SpamPhrase {
+    phrases := make([]SpamPhrase, len(spamPhrases))
+    for i, text := range spamPhrases {
+        phrases[i] = SpamPhrase{Text: text, Weight: weights[text]}
+    }
+    return phrases
 }
\ No newline at end of file
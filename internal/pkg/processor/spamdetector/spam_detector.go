@@ -1,12 +1,21 @@
 package spamdetector
 
 import (
+    "fmt"
+    "os"
     "strings"
     "github.com/cloudflare/ahocorasick"  // Efficient Aho-Corasick implementation
     "go.uber.org/zap"
+    "gopkg.in/yaml.v3"
     "indexer/internal/pkg/logger"
 )
 
+// A phrase that indicates spam and the score it adds when found
+type SpamPhrase struct {
+    Text   string `yaml:"text" json:"text"`
+    Weight int    `yaml:"weight" json:"weight"` // Defaults to 1 when unset
+}
+
 // Detects spam content using Aho-Corasick algorithm
 type SpamDetector struct {
     matcher       *ahocorasick.Matcher
@@ -19,38 +28,73 @@ type SpamDetector struct {
 type SpamResult struct {
     Score       int            // Overall spam score
     IsHighSpam  bool           // Whether content exceeds block threshold
+    Phrases     []string       // Spam phrases found in the text
 }
 
 // Creates a new detector with the given spam phrases
-func NewSpamDetector(blockThreshold int) *SpamDetector {
-    // Convert phrases to byte slices for the Aho-Corasick matcher
-    patterns := make([][]byte, len(spamPhrases))
-    for i, phrase := range spamPhrases {
-        patterns[i] = []byte(strings.ToLower(phrase))
-    }
-    
-    // Set default weights for phrases without explicit weights
+func NewSpamDetector(phrases []SpamPhrase, blockThreshold int) *SpamDetector {
+    // Convert phrases to byte slices for the Aho-Corasick matcher, folded
+    // like the text DetectSpam matches them against
+    patterns := make([][]byte, 0, len(phrases))
+    texts := make([]string, 0, len(phrases))
     phraseScores := make(map[string]int)
-    for _, phrase := range spamPhrases {
-        if weight, exists := weights[phrase]; exists {
-            phraseScores[phrase] = weight
+    for _, phrase := range phrases {
+        pattern := normalizeText(phrase.Text)
+        if strings.TrimSpace(pattern) == "" {
+            // Would match everywhere, or nowhere once the text is folded
+            logger.Log.Warn("Ignoring spam phrase without ASCII letters", zap.String("phrase", phrase.Text))
+            continue
+        }
+        patterns = append(patterns, []byte(pattern))
+        texts = append(texts, phrase.Text)
+        
+        // Set default weights for phrases without explicit weights
+        if phrase.Weight > 0 {
+            phraseScores[phrase.Text] = phrase.Weight
         } else {
-            phraseScores[phrase] = 1 // Default weight
+            phraseScores[phrase.Text] = 1 // Default weight
         }
     }
     
     logger.Log.Info("Initializing spam detector", 
-        zap.Int("phrase_count", len(spamPhrases)), 
+        zap.Int("phrase_count", len(phrases)), 
         zap.Int("block_threshold", blockThreshold))
     
     return &SpamDetector{
         matcher:       	ahocorasick.NewMatcher(patterns),
-        spamPhrases:   	spamPhrases,
+        spamPhrases:   	texts,
         phraseScores:  	phraseScores,
         blockThreshold: blockThreshold,
     }
 }
 
+// Parses a list of spam phrases from YAML (or JSON) data.
+func ParseSpamPhrases(data []byte) ([]SpamPhrase, error) {
+    var phrases []SpamPhrase
+    if err := yaml.Unmarshal(data, &phrases); err != nil {
+        return nil, fmt.Errorf("failed to parse spam phrases: %w", err)
+    }
+    for i, phrase := range phrases {
+        if strings.TrimSpace(phrase.Text) == "" {
+            return nil, fmt.Errorf("spam phrase %d has no text", i)
+        }
+    }
+    return phrases, nil
+}
+
+// Creates a new detector with the spam phrases listed in a YAML file.
+func NewSpamDetectorFromFile(path string, blockThreshold int) (*SpamDetector, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, fmt.Errorf("failed to read spam phrases: %w", err)
+    }
+    phrases, err := ParseSpamPhrases(data)
+    if err != nil {
+        return nil, err
+    }
+    return NewSpamDetector(phrases, blockThreshold), nil
+}
+
 // Analyzes text for spam content
 func (sd *SpamDetector) DetectSpam(text string) SpamResult {
     if text == "" {
@@ -60,22 +104,24 @@ func (sd *SpamDetector) DetectSpam(text string) SpamResult {
         }
     }
     
-    // Convert to lowercase for case-insensitive matching
-    lowerText := strings.ToLower(text)
-    textBytes := []byte(lowerText)
+    // Fold to lowercase ASCII for case-insensitive, homoglyph-resistant matching
+    textBytes := []byte(normalizeText(text))
     
     // Calculate text length for density calculations
     textLength := len([]rune(text))
     
-    // Find all matches using Aho-Corasick
-    hits := sd.matcher.Match(textBytes)
+    // Find all matches using Aho-Corasick. Workers share the detector, so
+    // use the variant that keeps its match state per call
+    hits := sd.matcher.MatchThreadSafe(textBytes)
     
     // Calculate spam score and match counts
     totalScore := 0
 	
 	// Calculate spam score based on matched phrases
+    phrases := make([]string, 0, len(hits))
     for _, hit := range hits {
         totalScore += sd.phraseScores[sd.spamPhrases[hit]]
+        phrases = append(phrases, sd.spamPhrases[hit])
     }
     
     // Adjust score based on text length (longer legitimate content dilutes spam)
@@ -92,5 +138,6 @@ func (sd *SpamDetector) DetectSpam(text string) SpamResult {
     return SpamResult{
         Score:      totalScore,
         IsHighSpam: isHighSpam,
+        Phrases:    phrases,
     }
 }
\ No newline at end of file
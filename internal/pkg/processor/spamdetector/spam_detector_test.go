@@ -0,0 +1,159 @@
+package spamdetector
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"go.uber.org/zap"
+	"indexer/internal/pkg/logger"
+)
+
+func init() {
+	// Ensure that the logger is not nil during tests.
+	logger.Log = zap.NewNop()
+}
+
+const (
+	spammySentence = "Act now and buy now to get rich quick with this limited time offer. "
+	cleanSentence  = "The committee reviewed the quarterly report and agreed on the agenda. "
+)
+
+// Repeats the sentence until the text is exactly size bytes long.
+func buildText(sentence string, size int) string {
+	text := strings.Repeat(sentence, size/len(sentence)+1)
+	return text[:size]
+}
+
+func benchmarkDetectSpam(b *testing.B, text string) {
+	detector := NewSpamDetector(DefaultSpamPhrases(), 15)
+	b.SetBytes(int64(len(text)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		detector.DetectSpam(text)
+	}
+}
+
+func BenchmarkDetectSpam_Short(b *testing.B) {
+	benchmarkDetectSpam(b, buildText(spammySentence, 100))
+}
+
+func BenchmarkDetectSpam_Long(b *testing.B) {
+	benchmarkDetectSpam(b, buildText(spammySentence, 10000))
+}
+
+func BenchmarkDetectSpam_NoHits(b *testing.B) {
+	benchmarkDetectSpam(b, buildText(cleanSentence, 100000))
+}
+
+func BenchmarkNewSpamDetector(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		NewSpamDetector(DefaultSpamPhrases(), 15)
+	}
+}
+
+// Verifies that homoglyphs, accents and full-width letters are folded before matching.
+func TestNormalizeText(t *testing.T) {
+	tests := map[string]string{
+		"Act Now":         "act now",
+		"аct nоw":         "act now", // Cyrillic а and о
+		"fréé money":      "free money",
+		"ＦＲＥＥ ｍｏｎｅｙ": "free money", // full-width
+		"日本 buy now":      " buy now",
+	}
+	for input, expected := range tests {
+		if got := normalizeText(input); got != expected {
+			t.Errorf("Expected normalizeText(%q) to be %q, got %q", input, expected, got)
+		}
+	}
+}
+
+// Verifies that a spam phrase disguised with a homoglyph is still detected.
+func TestDetectSpamHomoglyph(t *testing.T) {
+	detector := NewSpamDetector(DefaultSpamPhrases(), 15)
+	plain := detector.DetectSpam("Act now and get rich quick")
+	disguised := detector.DetectSpam("Аct nоw and get rіch quick") // Cyrillic А, о and і
+
+	if plain.Score == 0 {
+		t.Fatal("Expected plain spam text to score above 0")
+	}
+	if disguised.Score != plain.Score {
+		t.Errorf("Expected disguised text to score %d, got %d (phrases %v)", plain.Score, disguised.Score, disguised.Phrases)
+	}
+}
+
+// Verifies that custom phrases are matched with their own weights.
+func TestNewSpamDetectorCustomPhrases(t *testing.T) {
+	detector := NewSpamDetector([]SpamPhrase{{Text: "Limited Stock", Weight: 4}, {Text: "order today"}}, 5)
+
+	result := detector.DetectSpam("Limited stock, so order today")
+	if result.Score != 5 || !result.IsHighSpam {
+		t.Errorf("Expected a blocking score of 5, got %+v", result)
+	}
+	if result := detector.DetectSpam("Act now and buy now"); result.Score != 0 {
+		t.Errorf("Expected default phrases to be ignored, got %+v", result)
+	}
+}
+
+// Verifies that phrases are loaded from a YAML file.
+func TestNewSpamDetectorFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "phrases.yaml")
+	data := "- text: limited stock\n  weight: 3\n- text: order today\n"
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatalf("Failed to write phrases: %v", err)
+	}
+
+	detector, err := NewSpamDetectorFromFile(path, 10)
+	if err != nil {
+		t.Fatalf("Failed to load phrases: %v", err)
+	}
+	if result := detector.DetectSpam("limited stock, order today"); result.Score != 4 || result.IsHighSpam {
+		t.Errorf("Expected a score of 4, got %+v", result)
+	}
+
+	// Non-ASCII phrases are folded like the text they are matched against
+	path = filepath.Join(t.TempDir(), "accented.yaml")
+	data = "- text: Crème brûlée\n  weight: 2\n- text: ＦＲＥＥ ＧＩＦＴ\n  weight: 3\n- text: €€€\n  weight: 5\n"
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatalf("Failed to write phrases: %v", err)
+	}
+	detector, err = NewSpamDetectorFromFile(path, 10)
+	if err != nil {
+		t.Fatalf("Failed to load phrases: %v", err)
+	}
+	if result := detector.DetectSpam("Order crème brûlée for a free gift"); result.Score != 5 {
+		t.Errorf("Expected both non-ASCII phrases to match for a score of 5, got %+v", result)
+	}
+
+	if _, err := NewSpamDetectorFromFile(filepath.Join(t.TempDir(), "missing.yaml"), 10); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+	if _, err := ParseSpamPhrases([]byte("- weight: 2\n")); err == nil {
+		t.Error("Expected an error for a phrase without text")
+	}
+}
+
+// Verifies that workers sharing one detector get consistent results. Run
+// with -race: the matcher keeps per-call state, which Match would share.
+func TestDetectSpamConcurrent(t *testing.T) {
+	detector := NewSpamDetector(DefaultSpamPhrases(), 15)
+	spammy := buildText(spammySentence, 2000)
+	expected := detector.DetectSpam(spammy).Score
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if score := detector.DetectSpam(spammy).Score; score != expected {
+					t.Errorf("Expected score %d, got %d", expected, score)
+					return
+				}
+				detector.DetectSpam(cleanSentence)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,78 @@
+package processor
+
+import (
+    "sync"
+    "github.com/prometheus/client_golang/prometheus"
+    dto "github.com/prometheus/client_model/go"
+    "indexer/internal/pkg/metrics"
+)
+
+// Counters backing each skip category, read directly so that skips are
+// only ever tracked once.
+var skipCounters = map[string]prometheus.Collector{
+    "duplicate":           metrics.DuplicatesDetected,
+    "non_english":         metrics.NonEnglishPagesSkipped,
+    "high_spam":           metrics.HighSpamPagesSkipped,
+    "empty_content":       metrics.EmptyContentSkipped,
+    "binary_content":      metrics.BinaryContentSkipped,
+    "robots_noindex":      metrics.RobotsNoIndexSkipped,
+    "non_indexable_status": metrics.NonIndexableStatusCodes,
+    "skipped_domain":      metrics.SkippedDomains,
+    "robots_txt_disallowed": metrics.RobotsTxtDisallowed,
+    "url_filter":          metrics.URLFilterRejections,
+}
+
+// Reports how many pages were skipped at each processing stage since the
+// stats were created or last reset. Safe for concurrent use.
+type ProcessorStats struct {
+    mu       sync.Mutex
+    baseline map[string]float64
+}
+
+// Creates a new ProcessorStats counting from now.
+func NewProcessorStats() *ProcessorStats {
+    stats := &ProcessorStats{}
+    stats.Reset()
+    return stats
+}
+
+// Returns the skip count of every category.
+func (stats *ProcessorStats) Skipped() map[string]int64 {
+    stats.mu.Lock()
+    defer stats.mu.Unlock()
+
+    skipped := make(map[string]int64, len(skipCounters))
+    for category, counter := range skipCounters {
+        skipped[category] = int64(counterValue(counter) - stats.baseline[category])
+    }
+    return skipped
+}
+
+// Starts counting again from zero.
+func (stats *ProcessorStats) Reset() {
+    stats.mu.Lock()
+    defer stats.mu.Unlock()
+
+    stats.baseline = make(map[string]float64, len(skipCounters))
+    for category, counter := range skipCounters {
+        stats.baseline[category] = counterValue(counter)
+    }
+}
+
+// Sums the values of a counter or every series of a counter vector.
+func counterValue(collector prometheus.Collector) float64 {
+    ch := make(chan prometheus.Metric)
+    go func() {
+        collector.Collect(ch)
+        close(ch)
+    }()
+
+    total := 0.0
+    for metric := range ch {
+        var m dto.Metric
+        if err := metric.Write(&m); err == nil && m.Counter != nil {
+            total += m.Counter.GetValue()
+        }
+    }
+    return total
+}
@@ -0,0 +1,45 @@
+package processor
+
+import (
+	"testing"
+	"indexer/internal/pkg/metrics"
+)
+
+// Verifies that skip counts are read from the metric counters and can be reset.
+func TestProcessorStats(t *testing.T) {
+	stats := NewProcessorStats()
+	for category, count := range stats.Skipped() {
+		if count != 0 {
+			t.Errorf("Expected %s to start at 0, got %d", category, count)
+		}
+	}
+
+	metrics.EmptyContentSkipped.Inc()
+	metrics.EmptyContentSkipped.Inc()
+	metrics.NonIndexableStatusCodes.WithLabelValues("404").Inc()
+	metrics.NonIndexableStatusCodes.WithLabelValues("500").Inc()
+	metrics.RobotsTxtDisallowed.Inc()
+	metrics.URLFilterRejections.Inc()
+
+	skipped := stats.Skipped()
+	if skipped["empty_content"] != 2 {
+		t.Errorf("Expected 2 empty content skips, got %d", skipped["empty_content"])
+	}
+	if skipped["non_indexable_status"] != 2 {
+		t.Errorf("Expected status codes to be summed to 2, got %d", skipped["non_indexable_status"])
+	}
+	if skipped["robots_txt_disallowed"] != 1 {
+		t.Errorf("Expected 1 robots.txt skip, got %d", skipped["robots_txt_disallowed"])
+	}
+	if skipped["url_filter"] != 1 {
+		t.Errorf("Expected 1 URL filter rejection, got %d", skipped["url_filter"])
+	}
+	if skipped["duplicate"] != 0 {
+		t.Errorf("Expected no duplicate skips, got %d", skipped["duplicate"])
+	}
+
+	stats.Reset()
+	if skipped := stats.Skipped(); skipped["empty_content"] != 0 || skipped["non_indexable_status"] != 0 {
+		t.Errorf("Expected counts to be zero after reset, got %v", skipped)
+	}
+}
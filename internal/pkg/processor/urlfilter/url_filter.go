@@ -0,0 +1,124 @@
+package urlfilter
+
+import (
+    "bufio"
+    "bytes"
+    "fmt"
+    "net/url"
+    "os"
+    "strings"
+)
+
+// Rejects URLs matching known bad patterns before they reach the
+// processing pipeline. A pattern starting with "/" is a path prefix
+// matched on segment boundaries, e.g. "/login" matches "/login" and
+// "/login/reset" but not "/loginpage". Any other pattern is a host,
+// matching that host and its subdomains. Matching is case-insensitive
+// and costs one set lookup per host label and path segment.
+//
+// Host patterns overlap with the processor's skip domains, but act earlier
+// and more cheaply: the filter sees the raw URL in the worker and drops the
+// page without any processing, counting it in one unlabelled metric. Skip
+// domains are checked against the normalized URL, reject the page with an
+// error and count it per domain.
+type URLFilter struct {
+    hosts map[string]struct{}
+    paths map[string]struct{}
+}
+
+// Creates a new URLFilter from host and path patterns. Blank patterns
+// are ignored.
+func New(patterns []string) *URLFilter {
+    filter := &URLFilter{
+        hosts: make(map[string]struct{}),
+        paths: make(map[string]struct{}),
+    }
+    for _, pattern := range patterns {
+        pattern = strings.ToLower(strings.TrimSpace(pattern))
+        switch {
+        case pattern == "":
+        case strings.HasPrefix(pattern, "/"):
+            if trimmed := strings.TrimRight(pattern, "/"); trimmed != "" {
+                filter.paths[trimmed] = struct{}{}
+            }
+        default:
+            filter.hosts[pattern] = struct{}{}
+        }
+    }
+    return filter
+}
+
+// Parses one pattern per line, skipping blank lines and "#" comments.
+func Parse(data []byte) []string {
+    var patterns []string
+    scanner := bufio.NewScanner(bytes.NewReader(data))
+    for scanner.Scan() {
+        line := strings.TrimSpace(scanner.Text())
+        if line != "" && !strings.HasPrefix(line, "#") {
+            patterns = append(patterns, line)
+        }
+    }
+    return patterns
+}
+
+// Reads the patterns of a file with one pattern per line.
+func ReadFile(path string) ([]string, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, fmt.Errorf("failed to read URL filter: %w", err)
+    }
+    return Parse(data), nil
+}
+
+// Reads a URLFilter from a file with one pattern per line.
+func LoadFile(path string) (*URLFilter, error) {
+    patterns, err := ReadFile(path)
+    if err != nil {
+        return nil, err
+    }
+    return New(patterns), nil
+}
+
+// Reports whether rawURL matches any pattern. Unparseable URLs are let
+// through for the processor to reject. A nil filter skips nothing.
+func (filter *URLFilter) ShouldSkip(rawURL string) bool {
+    if filter == nil || (len(filter.hosts) == 0 && len(filter.paths) == 0) {
+        return false
+    }
+    parsed, err := url.Parse(strings.TrimSpace(rawURL))
+    if err != nil {
+        return false
+    }
+    return filter.matchHost(strings.ToLower(parsed.Hostname())) ||
+        filter.matchPath(strings.ToLower(parsed.Path))
+}
+
+// Reports whether host or one of its parent domains is a host pattern.
+func (filter *URLFilter) matchHost(host string) bool {
+    for host != "" {
+        if _, ok := filter.hosts[host]; ok {
+            return true
+        }
+        _, parent, found := strings.Cut(host, ".")
+        if !found {
+            break
+        }
+        host = parent
+    }
+    return false
+}
+
+// Reports whether any segment prefix of path is a path pattern.
+func (filter *URLFilter) matchPath(path string) bool {
+    if len(filter.paths) == 0 {
+        return false
+    }
+    for end := 1; end <= len(path); end++ {
+        if end == len(path) || path[end] == '/' {
+            if _, ok := filter.paths[path[:end]]; ok {
+                return true
+            }
+        }
+    }
+    return false
+}
@@ -0,0 +1,79 @@
+package urlfilter
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// Verifies that host and path patterns match the URLs they should.
+func TestShouldSkip(t *testing.T) {
+	filter := New([]string{" DoubleClick.net ", "pixel.example.com", "/login", "/track/", "/Ads", "", "/"})
+	tests := []struct {
+		url  string
+		skip bool
+	}{
+		{"https://example.com/article", false},
+		{"https://doubleclick.net/ad", true},
+		{"https://stats.g.DOUBLECLICK.NET/collect", true},
+		{"https://notdoubleclick.net/page", false},
+		{"https://pixel.example.com/p.gif", true},
+		{"https://example.com/pixel", false},
+		{"https://example.com/login", true},
+		{"https://example.com/login/", true},
+		{"https://example.com/LOGIN/reset?next=/", true},
+		{"https://example.com/loginpage", false},
+		{"https://example.com/blog/login", false},
+		{"https://example.com/track/open", true},
+		{"https://example.com/ads/banner", true},
+		{"  https://example.com/login  ", true},
+		{"http://[::1", false},
+	}
+
+	for _, tc := range tests {
+		if got := filter.ShouldSkip(tc.url); got != tc.skip {
+			t.Errorf("ShouldSkip(%q) = %v, expected %v", tc.url, got, tc.skip)
+		}
+	}
+}
+
+// Verifies that nil and empty filters skip nothing.
+func TestShouldSkipEmpty(t *testing.T) {
+	var nilFilter *URLFilter
+	if nilFilter.ShouldSkip("https://example.com/login") {
+		t.Error("Expected a nil filter to skip nothing")
+	}
+	if New(nil).ShouldSkip("https://example.com/login") {
+		t.Error("Expected an empty filter to skip nothing")
+	}
+}
+
+// Verifies that patterns are read one per line, ignoring blanks and comments.
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "url_filter.txt")
+	data := "# Ad networks\ndoubleclick.net\n\n  /login  \n# /admin\n"
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatalf("Failed to write filter file: %v", err)
+	}
+
+	if patterns, expected := Parse([]byte(data)), []string{"doubleclick.net", "/login"}; !reflect.DeepEqual(patterns, expected) {
+		t.Errorf("Expected patterns %v, got %v", expected, patterns)
+	}
+
+	if patterns, err := ReadFile(path); err != nil || !reflect.DeepEqual(patterns, []string{"doubleclick.net", "/login"}) {
+		t.Errorf("Expected ReadFile to return the file's patterns, got %v (%v)", patterns, err)
+	}
+
+	filter, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to load filter: %v", err)
+	}
+	if !filter.ShouldSkip("https://example.com/login") || filter.ShouldSkip("https://example.com/admin") {
+		t.Error("Expected only uncommented patterns to be loaded")
+	}
+
+	if _, err := LoadFile(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
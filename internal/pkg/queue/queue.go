@@ -1,34 +1,94 @@
 package queue
 
 import (
+	"context"
 	"errors"
+	"indexer/internal/pkg/metrics"
 	"indexer/internal/pkg/models"
 	"sync"
+	"sync/atomic"
 )
 
 type Queue struct {
     q        []models.PageData
+    head     int // index of the oldest item in q
     capacity int
     closed   bool
+    onFull   func(dropped models.PageData)
+    overflow chan models.PageData // full-queue items waiting for onFull
     mu       sync.Mutex
+    notFull  *sync.Cond // signalled when space frees up or the queue closes
+
+    // Lifetime counters, readable without taking mu
+    totalInserted atomic.Int64
+    totalRemoved  atomic.Int64
+}
+
+// Point-in-time queue telemetry
+type QueueStats struct {
+    Capacity      int64 `json:"capacity"`
+    Length        int64 `json:"length"`
+    TotalInserted int64 `json:"total_inserted"` // items accepted since creation
+    TotalRemoved  int64 `json:"total_removed"`  // items dequeued since creation
+}
+
+// Configures optional Queue behaviour
+type Option func(*Queue)
+
+// Full-queue items that may wait for the onFull callback. Further items
+// are discarded and counted until the callback catches up.
+const onFullBacklog = 256
+
+// Hands items that arrive while the queue is full to fn instead of failing
+// the insert. fn runs in a single background goroutine so it never blocks
+// producers, and can be used to emit metrics, log or divert the dropped page
+// elsewhere. If fn falls more than onFullBacklog items behind, the excess is
+// discarded without calling it.
+func WithOnFullCallback(fn func(dropped models.PageData)) Option {
+    return func(q *Queue) {
+        q.onFull = fn
+    }
 }
 
 // First in, first out queue 
 type FifoQueue interface {
-    Insert()
-    Remove()
+    Insert(item models.PageData) error
+    Remove() (models.PageData, error)
+    Peek() (models.PageData, error)
+    Length() int
+    IsEmpty() bool
+    Stats() QueueStats
+    Close()
 }
 
+var _ FifoQueue = (*Queue)(nil)
+
 // Creates an empty queue with a specified capacity
-func CreateQueue(capacity int) (*Queue, error) {
+func CreateQueue(capacity int, opts ...Option) (*Queue, error) {
     if capacity <= 0 {
         return nil, errors.New("capacity should be greater than 0")
     }
-    return &Queue{
+    q := &Queue{
         q:        make([]models.PageData, 0, capacity),
         capacity: capacity,
         closed:   false,
-    }, nil
+    }
+    q.notFull = sync.NewCond(&q.mu)
+    for _, opt := range opts {
+        opt(q)
+    }
+    if q.onFull != nil {
+        q.overflow = make(chan models.PageData, onFullBacklog)
+        go q.runOnFull()
+    }
+    return q, nil
+}
+
+// Calls onFull for every overflowing item until the queue is closed.
+func (q *Queue) runOnFull() {
+    for item := range q.overflow {
+        q.onFull(item)
+    }
 }
 
 // Inserts an item into the queue
@@ -38,38 +98,126 @@ func (q *Queue) Insert(item models.PageData) error {
     if q.closed {
         return errors.New("queue is closed")
     }
-    if len(q.q) < int(q.capacity) {
+    if len(q.q) - q.head < q.capacity {
         q.q = append(q.q, item)
+        q.totalInserted.Add(1)
+        return nil
+    }
+    if q.onFull != nil {
+        select {
+        case q.overflow <- item:
+        default:
+            metrics.QueueOnFullBacklogDrops.Inc()
+        }
         return nil
     }
     return errors.New("queue is full")
 }
 
+// Inserts an item into the queue, waiting for space while it is full.
+// Returns the context error if ctx is done first.
+func (q *Queue) InsertWithContext(ctx context.Context, item models.PageData) error {
+    q.mu.Lock()
+    defer q.mu.Unlock()
+
+    // Wake the waiter below when the context is done
+    stop := context.AfterFunc(ctx, func() {
+        q.mu.Lock()
+        defer q.mu.Unlock()
+        q.notFull.Broadcast()
+    })
+    defer stop()
+
+    for {
+        if q.closed {
+            return errors.New("queue is closed")
+        }
+        if len(q.q) - q.head < q.capacity {
+            q.q = append(q.q, item)
+            q.totalInserted.Add(1)
+            return nil
+        }
+        if err := ctx.Err(); err != nil {
+            return err
+        }
+        q.notFull.Wait()
+    }
+}
+
 // Removes the oldest element from the queue
 func (q *Queue) Remove() (models.PageData, error) {
     q.mu.Lock()
     defer q.mu.Unlock()
-    if len(q.q) > 0 {
-        item := q.q[0]
-        q.q = q.q[1:]
+    if len(q.q) > q.head {
+        item := q.q[q.head]
+        q.q[q.head] = models.PageData{} // release references for the GC
+        q.head++
+        q.totalRemoved.Add(1)
+        q.compact()
+        q.notFull.Broadcast()
         return item, nil
     }
     return models.PageData{}, errors.New("Queue is empty")
 }
 
+// Returns the oldest element without removing it
+func (q *Queue) Peek() (models.PageData, error) {
+    q.mu.Lock()
+    defer q.mu.Unlock()
+    if len(q.q) > q.head {
+        return q.q[q.head], nil
+    }
+    return models.PageData{}, errors.New("Queue is empty")
+}
+
+// Reclaims the space in front of head once it makes up over half the capacity,
+// so the backing array is reused instead of reallocated. Callers must hold mu.
+func (q *Queue) compact() {
+    if q.head == len(q.q) {
+        q.q = q.q[:0]
+        q.head = 0
+        return
+    }
+    if q.head <= q.capacity/2 {
+        return
+    }
+    n := copy(q.q, q.q[q.head:])
+    clear(q.q[n:])
+    q.q = q.q[:n]
+    q.head = 0
+}
+
 // Returns the number of elements in the queue
 func (q *Queue) Length() int {
-    return len(q.q)
+    q.mu.Lock()
+    defer q.mu.Unlock()
+    return len(q.q) - q.head
 }
 
 // Returns true if the queue is empty
 func (q *Queue) IsEmpty() bool {
-    return len(q.q) == 0
+    q.mu.Lock()
+    defer q.mu.Unlock()
+    return len(q.q) == q.head
+}
+
+// Returns the capacity, current length and lifetime insert and remove counts
+func (q *Queue) Stats() QueueStats {
+    return QueueStats{
+        Capacity:      int64(q.capacity),
+        Length:        int64(q.Length()),
+        TotalInserted: q.totalInserted.Load(),
+        TotalRemoved:  q.totalRemoved.Load(),
+    }
 }
 
 // Closes the queue, preventing further insertions
 func (q *Queue) Close() {
     q.mu.Lock()
     defer q.mu.Unlock()
+    if !q.closed && q.overflow != nil {
+        close(q.overflow)
+    }
     q.closed = true
+    q.notFull.Broadcast()
 }
@@ -1,8 +1,14 @@
 package queue
 
 import (
+	"context"
+	"errors"
 	"reflect"
+	"sync"
 	"testing"
+	"time"
+	dto "github.com/prometheus/client_model/go"
+	"indexer/internal/pkg/metrics"
 	"indexer/internal/pkg/models"
 )
 
@@ -231,6 +237,30 @@ func TestLength(t *testing.T) {
 	}
 }
 
+// Tests that Stats reports capacity, length and lifetime counts, excluding
+// inserts rejected by a full queue.
+func TestStats(t *testing.T) {
+	q, err := CreateQueue(2)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	q.Insert(models.PageData{URL: "a"})
+	q.Insert(models.PageData{URL: "b"})
+	if err := q.Insert(models.PageData{URL: "c"}); err == nil {
+		t.Errorf("Expected error inserting into a full queue, got nil")
+	}
+	q.Remove()
+	if err := q.InsertWithContext(context.Background(), models.PageData{URL: "d"}); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	q.Remove()
+
+	expected := QueueStats{Capacity: 2, Length: 1, TotalInserted: 3, TotalRemoved: 2}
+	if stats := q.Stats(); stats != expected {
+		t.Errorf("Expected stats %+v, got %+v", expected, stats)
+	}
+}
+
 // Tests checking if the queue is empty.
 func TestIsEmpty(t *testing.T) {
 	q, err := CreateQueue(3)
@@ -249,3 +279,243 @@ func TestIsEmpty(t *testing.T) {
 		t.Errorf("Expected queue to be empty again")
 	}
 }
+
+// Tests that FIFO order survives repeated wrap-around and compaction.
+func TestSustainedInsertRemove(t *testing.T) {
+	q, err := CreateQueue(4)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	next := 0
+	expected := 0
+	for round := 0; round < 100; round++ {
+		for q.Length() < 4 {
+			if err := q.Insert(models.PageData{URL: string(rune('a' + next%26))}); err != nil {
+				t.Fatalf("Insert error: %v", err)
+			}
+			next++
+		}
+		for i := 0; i < 3; i++ {
+			item, err := q.Remove()
+			if err != nil {
+				t.Fatalf("Remove error: %v", err)
+			}
+			if want := string(rune('a' + expected%26)); item.URL != want {
+				t.Fatalf("Expected %q, got %q", want, item.URL)
+			}
+			expected++
+		}
+	}
+	if cap(q.q) > 8 {
+		t.Errorf("Expected backing array to be reused, got capacity %d", cap(q.q))
+	}
+}
+
+// reslicingQueue mirrors the previous implementation, which re-sliced
+// the backing array on every Remove. It is kept for benchmark comparison.
+type reslicingQueue struct {
+	q        []models.PageData
+	capacity int
+	mu       sync.Mutex
+}
+
+func (q *reslicingQueue) Insert(item models.PageData) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.q) < q.capacity {
+		q.q = append(q.q, item)
+		return nil
+	}
+	return errors.New("queue is full")
+}
+
+func (q *reslicingQueue) Remove() (models.PageData, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.q) > 0 {
+		item := q.q[0]
+		q.q = q.q[1:]
+		return item, nil
+	}
+	return models.PageData{}, errors.New("Queue is empty")
+}
+
+// Keeps the queue half full while cycling items through it.
+func benchmarkSustained(b *testing.B, insert func(models.PageData) error, remove func() (models.PageData, error)) {
+	item := models.PageData{URL: "http://example.com", VisibleText: "benchmark"}
+	for i := 0; i < 512; i++ {
+		insert(item)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		insert(item)
+		remove()
+	}
+}
+
+func BenchmarkQueueSustained(b *testing.B) {
+	q, _ := CreateQueue(1024)
+	benchmarkSustained(b, q.Insert, q.Remove)
+}
+
+func BenchmarkReslicingQueueSustained(b *testing.B) {
+	q := &reslicingQueue{q: make([]models.PageData, 0, 1024), capacity: 1024}
+	benchmarkSustained(b, q.Insert, q.Remove)
+}
+
+// Tests peeking at the oldest element without removing it.
+func TestPeek(t *testing.T) {
+	q, err := CreateQueue(3)
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	if _, err := q.Peek(); err == nil {
+		t.Errorf("Expected error when peeking an empty queue, got nil")
+	}
+
+	q.Insert(models.PageData{URL: "a"})
+	q.Insert(models.PageData{URL: "b"})
+
+	elem, err := q.Peek()
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if elem.URL != "a" {
+		t.Errorf("Expected peeked element URL to be 'a', got '%s'", elem.URL)
+	}
+	if q.Length() != 2 {
+		t.Errorf("Expected queue length to stay 2 after peek, got %d", q.Length())
+	}
+
+	q.Remove()
+	elem, _ = q.Peek()
+	if elem.URL != "b" {
+		t.Errorf("Expected peeked element URL to be 'b', got '%s'", elem.URL)
+	}
+}
+
+// Tests that a full queue hands dropped items to the onFull callback.
+func TestOnFullCallback(t *testing.T) {
+	dropped := make(chan models.PageData, 1)
+	q, err := CreateQueue(1, WithOnFullCallback(func(item models.PageData) {
+		dropped <- item
+	}))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if err := q.Insert(models.PageData{URL: "a"}); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if err := q.Insert(models.PageData{URL: "b"}); err != nil {
+		t.Errorf("Expected full insert to be handed to the callback, got %v", err)
+	}
+	if q.Length() != 1 {
+		t.Errorf("Expected queue length to stay 1, got %d", q.Length())
+	}
+
+	select {
+	case item := <-dropped:
+		if item.URL != "b" {
+			t.Errorf("Expected dropped item %q, got %q", "b", item.URL)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for the onFull callback")
+	}
+}
+
+// Tests that a slow onFull callback never blocks inserts, and that items
+// beyond its backlog are discarded and counted.
+func TestOnFullCallbackBacklog(t *testing.T) {
+	backlogDrops := func() float64 {
+		var metric dto.Metric
+		if err := metrics.QueueOnFullBacklogDrops.Write(&metric); err != nil {
+			t.Fatalf("Failed to read counter: %v", err)
+		}
+		return metric.GetCounter().GetValue()
+	}
+
+	release := make(chan struct{})
+	var handled sync.WaitGroup
+	q, err := CreateQueue(1, WithOnFullCallback(func(item models.PageData) {
+		<-release
+		handled.Done()
+	}))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	q.Insert(models.PageData{URL: "kept"})
+
+	before := backlogDrops()
+	overflow := onFullBacklog + 10
+	for i := 0; i < overflow; i++ {
+		if err := q.Insert(models.PageData{URL: "overflow"}); err != nil {
+			t.Fatalf("Expected full insert to be accepted, got %v", err)
+		}
+	}
+
+	// At most one item is in the callback and onFullBacklog wait behind it
+	dropped := int(backlogDrops() - before)
+	if dropped < overflow-onFullBacklog-1 || dropped > overflow-onFullBacklog {
+		t.Errorf("Expected about %d items discarded, got %d", overflow-onFullBacklog, dropped)
+	}
+
+	handled.Add(overflow - dropped)
+	close(release)
+	handled.Wait()
+	q.Close()
+}
+
+// Tests that InsertWithContext waits for space and honours cancellation.
+func TestInsertWithContext(t *testing.T) {
+	q, err := CreateQueue(1)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := q.InsertWithContext(context.Background(), models.PageData{URL: "a"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// A full queue times out once the context expires.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := q.InsertWithContext(ctx, models.PageData{URL: "b"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+
+	// A blocked insert completes once an item is removed.
+	inserted := make(chan error, 1)
+	go func() {
+		inserted <- q.InsertWithContext(context.Background(), models.PageData{URL: "c"})
+	}()
+	time.Sleep(20 * time.Millisecond)
+	if item, err := q.Remove(); err != nil || item.URL != "a" {
+		t.Fatalf("Expected to remove %q, got %q (%v)", "a", item.URL, err)
+	}
+	select {
+	case err := <-inserted:
+		if err != nil {
+			t.Errorf("Expected blocked insert to succeed, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for blocked insert")
+	}
+
+	// Closing the queue releases blocked inserts.
+	go func() {
+		inserted <- q.InsertWithContext(context.Background(), models.PageData{URL: "d"})
+	}()
+	time.Sleep(20 * time.Millisecond)
+	q.Close()
+	select {
+	case err := <-inserted:
+		if err == nil {
+			t.Error("Expected insert into a closed queue to fail")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for insert to be released by Close")
+	}
+}
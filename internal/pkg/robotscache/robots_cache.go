@@ -0,0 +1,213 @@
+package robotscache
+
+import (
+    "container/list"
+    "context"
+    "io"
+    "net/http"
+    "net/url"
+    "sync"
+    "time"
+    "github.com/temoto/robotstxt"
+    "go.uber.org/zap"
+    "indexer/internal/pkg/logger"
+)
+
+// User agent matched against robots.txt groups
+const UserAgent = "indexer"
+
+// Upper bound on fetching a single robots.txt
+const fetchTimeout = 5 * time.Second
+
+// Longest robots.txt read, the rest is ignored like Google does past 500KB
+const maxRobotsBytes = 500 << 10
+
+// Hosts whose robots.txt is kept before the least recently used is evicted
+const defaultMaxEntries = 10000
+
+// Fetches robots.txt once per host and caches it for a TTL, so pages can
+// be checked against it without a request per page. At most maxEntries
+// hosts are kept, evicting the least recently used.
+//
+// Only the first page of a host waits for its robots.txt, and concurrent
+// pages of that host share the one fetch. Once the TTL passes, the expired
+// copy keeps being served for up to another TTL while a fresh one is
+// fetched in the background.
+type RobotsTxtCache struct {
+    client     *http.Client
+    ttl        time.Duration
+    userAgent  string
+    maxEntries int
+
+    mutex     sync.Mutex
+    entries   map[string]*list.Element // keyed by scheme://host, values are *cacheEntry
+    recency   *list.List               // most recently used entry first
+    inflight  map[string]*fetchCall    // fetches in progress by origin
+    lastSweep time.Time
+}
+
+// Parsed robots.txt of a host and when it must be fetched again.
+type cacheEntry struct {
+    origin  string
+    robots  *robotstxt.RobotsData // nil allows everything
+    expires time.Time
+}
+
+// A robots.txt fetch shared by every caller waiting on the same origin.
+type fetchCall struct {
+    done   chan struct{} // closed once robots is set
+    robots *robotstxt.RobotsData
+}
+
+// Creates a new RobotsTxtCache keeping each host's robots.txt for ttl.
+func New(ttl time.Duration) *RobotsTxtCache {
+    return &RobotsTxtCache{
+        client:     &http.Client{Timeout: fetchTimeout},
+        ttl:        ttl,
+        userAgent:  UserAgent,
+        maxEntries: defaultMaxEntries,
+        entries:    make(map[string]*list.Element),
+        recency:    list.New(),
+        inflight:   make(map[string]*fetchCall),
+        lastSweep:  time.Now(),
+    }
+}
+
+// Reports whether robots.txt allows the page to be indexed. Pages whose
+// robots.txt cannot be fetched are allowed, while a robots.txt answering
+// with a server error disallows the whole host.
+func (cache *RobotsTxtCache) Allowed(pageURL string) bool {
+    parsed, err := url.Parse(pageURL)
+    if err != nil || parsed.Host == "" {
+        return true
+    }
+
+    robots := cache.robotsFor(parsed.Scheme + "://" + parsed.Host)
+    if robots == nil {
+        return true
+    }
+    path := parsed.EscapedPath()
+    if path == "" {
+        path = "/"
+    }
+    if parsed.RawQuery != "" {
+        path += "?" + parsed.RawQuery
+    }
+    return robots.TestAgent(path, cache.userAgent)
+}
+
+// Returns the cached robots.txt of a host. A missing or long expired copy
+// is fetched while the caller waits, a recently expired one is returned
+// and refreshed in the background.
+func (cache *RobotsTxtCache) robotsFor(origin string) *robotstxt.RobotsData {
+    now := time.Now()
+
+    cache.mutex.Lock()
+    if element, ok := cache.entries[origin]; ok {
+        entry := element.Value.(*cacheEntry)
+        if now.Before(entry.expires.Add(cache.ttl)) {
+            cache.recency.MoveToFront(element)
+            if !now.Before(entry.expires) {
+                cache.startFetch(origin)
+            }
+            cache.mutex.Unlock()
+            return entry.robots
+        }
+    }
+    call := cache.startFetch(origin)
+    cache.mutex.Unlock()
+
+    <-call.done
+    return call.robots
+}
+
+// Returns the fetch in progress for origin, starting one if there is none.
+// The fetch runs without holding the lock, so one slow host doesn't block
+// the rest. Callers must hold mutex.
+func (cache *RobotsTxtCache) startFetch(origin string) *fetchCall {
+    if call, ok := cache.inflight[origin]; ok {
+        return call
+    }
+    call := &fetchCall{done: make(chan struct{})}
+    cache.inflight[origin] = call
+
+    go func() {
+        robots := cache.fetch(origin)
+
+        cache.mutex.Lock()
+        cache.store(origin, robots)
+        delete(cache.inflight, origin)
+        cache.mutex.Unlock()
+
+        call.robots = robots
+        close(call.done)
+    }()
+    return call
+}
+
+// Caches the robots.txt of origin, evicting the least recently used hosts
+// beyond maxEntries and, at most once per TTL, every long expired entry.
+// Callers must hold mutex.
+func (cache *RobotsTxtCache) store(origin string, robots *robotstxt.RobotsData) {
+    now := time.Now()
+    entry := &cacheEntry{origin: origin, robots: robots, expires: now.Add(cache.ttl)}
+    if element, ok := cache.entries[origin]; ok {
+        element.Value = entry
+        cache.recency.MoveToFront(element)
+    } else {
+        cache.entries[origin] = cache.recency.PushFront(entry)
+    }
+
+    for cache.recency.Len() > cache.maxEntries {
+        cache.remove(cache.recency.Back())
+    }
+
+    if now.Sub(cache.lastSweep) >= cache.ttl {
+        cache.lastSweep = now
+        for element := cache.recency.Front(); element != nil; {
+            next := element.Next()
+            if !now.Before(element.Value.(*cacheEntry).expires.Add(cache.ttl)) {
+                cache.remove(element)
+            }
+            element = next
+        }
+    }
+}
+
+// Drops an entry from the cache. Callers must hold mutex.
+func (cache *RobotsTxtCache) remove(element *list.Element) {
+    cache.recency.Remove(element)
+    delete(cache.entries, element.Value.(*cacheEntry).origin)
+}
+
+// Fetches and parses robots.txt from origin, reading at most maxRobotsBytes.
+// Returns nil if it could not be fetched or parsed.
+func (cache *RobotsTxtCache) fetch(origin string) *robotstxt.RobotsData {
+    ctx, cancel := context.WithTimeout(context.Background(), fetchTimeout)
+    defer cancel()
+
+    request, err := http.NewRequestWithContext(ctx, http.MethodGet, origin+"/robots.txt", nil)
+    if err != nil {
+        return nil
+    }
+    request.Header.Set("User-Agent", cache.userAgent)
+
+    response, err := cache.client.Do(request)
+    if err != nil {
+        logger.Log.Debug("Failed to fetch robots.txt", zap.String("origin", origin), zap.Error(err))
+        return nil
+    }
+    defer response.Body.Close()
+
+    body, err := io.ReadAll(io.LimitReader(response.Body, maxRobotsBytes))
+    if err != nil {
+        logger.Log.Debug("Failed to read robots.txt", zap.String("origin", origin), zap.Error(err))
+        return nil
+    }
+    robots, err := robotstxt.FromStatusAndBytes(response.StatusCode, body)
+    if err != nil {
+        logger.Log.Debug("Failed to parse robots.txt", zap.String("origin", origin), zap.Error(err))
+        return nil
+    }
+    return robots
+}
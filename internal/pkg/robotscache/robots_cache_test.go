@@ -0,0 +1,174 @@
+package robotscache
+
+import (
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "sync"
+    "sync/atomic"
+    "testing"
+    "time"
+    "go.uber.org/zap"
+    "indexer/internal/pkg/logger"
+)
+
+func init() {
+    // Ensure that the logger is not nil during tests.
+    logger.Log = zap.NewNop()
+}
+
+// Starts a server answering robots.txt with the given status and body,
+// counting how often it was fetched.
+func newRobotsServer(t *testing.T, status int, body string) (*httptest.Server, *atomic.Int64) {
+    t.Helper()
+    var fetches atomic.Int64
+    server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+        if request.URL.Path != "/robots.txt" {
+            http.NotFound(writer, request)
+            return
+        }
+        fetches.Add(1)
+        writer.WriteHeader(status)
+        fmt.Fprint(writer, body)
+    }))
+    t.Cleanup(server.Close)
+    return server, &fetches
+}
+
+// Verifies that disallowed paths are rejected and robots.txt is fetched once per TTL.
+func TestAllowed(t *testing.T) {
+    server, fetches := newRobotsServer(t, http.StatusOK, "User-agent: *\nDisallow: /private\n")
+    cache := New(time.Hour)
+
+    tests := []struct {
+        path    string
+        allowed bool
+    }{
+        {"/", true},
+        {"/public/page", true},
+        {"/private", false},
+        {"/private/page?id=1", false},
+    }
+    for _, tc := range tests {
+        if got := cache.Allowed(server.URL + tc.path); got != tc.allowed {
+            t.Errorf("Allowed(%q) = %v, want %v", tc.path, got, tc.allowed)
+        }
+    }
+
+    if got := fetches.Load(); got != 1 {
+        t.Errorf("Expected robots.txt to be fetched once, got %d", got)
+    }
+}
+
+// Verifies that an expired robots.txt is still served while it is fetched
+// again in the background.
+func TestAllowedRefetchesAfterTTL(t *testing.T) {
+    server, fetches := newRobotsServer(t, http.StatusOK, "User-agent: *\nDisallow: /private\n")
+    cache := New(50 * time.Millisecond)
+
+    cache.Allowed(server.URL + "/page")
+    time.Sleep(60 * time.Millisecond)
+    if cache.Allowed(server.URL + "/private") {
+        t.Error("Expected the expired robots.txt to still apply")
+    }
+
+    deadline := time.Now().Add(time.Second)
+    for fetches.Load() < 2 && time.Now().Before(deadline) {
+        time.Sleep(5 * time.Millisecond)
+    }
+    if got := fetches.Load(); got != 2 {
+        t.Errorf("Expected robots.txt to be fetched twice, got %d", got)
+    }
+}
+
+// Verifies that pages of a new host arriving together share one fetch.
+func TestAllowedSharesConcurrentFetches(t *testing.T) {
+    var fetches atomic.Int64
+    server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+        fetches.Add(1)
+        time.Sleep(50 * time.Millisecond)
+        fmt.Fprint(writer, "User-agent: *\nDisallow: /private\n")
+    }))
+    defer server.Close()
+    cache := New(time.Hour)
+
+    var wg sync.WaitGroup
+    for i := 0; i < 8; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            if cache.Allowed(server.URL + "/private") {
+                t.Error("Expected page to be disallowed")
+            }
+        }()
+    }
+    wg.Wait()
+
+    if got := fetches.Load(); got != 1 {
+        t.Errorf("Expected robots.txt to be fetched once, got %d", got)
+    }
+}
+
+// Verifies that the least recently used hosts are evicted beyond maxEntries.
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+    first, firstFetches := newRobotsServer(t, http.StatusOK, "")
+    second, _ := newRobotsServer(t, http.StatusOK, "")
+    third, _ := newRobotsServer(t, http.StatusOK, "")
+    cache := New(time.Hour)
+    cache.maxEntries = 2
+
+    cache.Allowed(first.URL + "/page")
+    cache.Allowed(second.URL + "/page")
+    cache.Allowed(first.URL + "/page")
+    cache.Allowed(third.URL + "/page")
+
+    if len(cache.entries) != 2 || cache.recency.Len() != 2 {
+        t.Fatalf("Expected 2 cached hosts, got %d", len(cache.entries))
+    }
+    if _, ok := cache.entries[second.URL]; ok {
+        t.Error("Expected the least recently used host to be evicted")
+    }
+    if got := firstFetches.Load(); got != 1 {
+        t.Errorf("Expected the recently used host to stay cached, got %d fetches", got)
+    }
+}
+
+// Verifies that only the first maxRobotsBytes of robots.txt are read.
+func TestAllowedLimitsRobotsSize(t *testing.T) {
+    padding := "# " + strings.Repeat("x", maxRobotsBytes) + "\n"
+    server, _ := newRobotsServer(t, http.StatusOK, "User-agent: *\nDisallow: /private\n"+padding+"Disallow: /hidden\n")
+    cache := New(time.Hour)
+
+    if cache.Allowed(server.URL + "/private") {
+        t.Error("Expected rules before the limit to apply")
+    }
+    if !cache.Allowed(server.URL + "/hidden") {
+        t.Error("Expected rules past the limit to be ignored")
+    }
+}
+
+// Verifies that a missing robots.txt allows everything and a server error disallows everything.
+func TestAllowedStatusCodes(t *testing.T) {
+    missing, _ := newRobotsServer(t, http.StatusNotFound, "")
+    failing, _ := newRobotsServer(t, http.StatusInternalServerError, "")
+    cache := New(time.Hour)
+
+    if !cache.Allowed(missing.URL + "/page") {
+        t.Error("Expected page to be allowed when robots.txt is missing")
+    }
+    if cache.Allowed(failing.URL + "/page") {
+        t.Error("Expected page to be disallowed when robots.txt fails with a server error")
+    }
+}
+
+// Verifies that pages are allowed when robots.txt cannot be fetched at all.
+func TestAllowedUnreachableHost(t *testing.T) {
+    server, _ := newRobotsServer(t, http.StatusOK, "")
+    url := server.URL
+    server.Close()
+
+    if !New(time.Hour).Allowed(url + "/page") {
+        t.Error("Expected page to be allowed when robots.txt is unreachable")
+    }
+}
@@ -0,0 +1,130 @@
+package testutil
+
+import (
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "sync"
+    "time"
+)
+
+// In-memory Elasticsearch bulk endpoint for tests. Every request is
+// recorded, and requests are answered with the configured status codes in
+// order, the last one repeating. Close it like any httptest.Server.
+type EsMockServer struct {
+    *httptest.Server
+
+    mu          sync.Mutex
+    requests    []EsRequest
+    statusCodes []int
+    delay       time.Duration
+    inFlight    int
+    maxInFlight int
+}
+
+// A request received by an EsMockServer.
+type EsRequest struct {
+    Method string
+    Path   string
+    Header http.Header
+    Body   []byte
+}
+
+// Starts a mock bulk endpoint answering each attempt with the next of
+// statusCodes. With no status codes every request gets 200 OK.
+func NewEsMockServer(statusCodes ...int) *EsMockServer {
+    mock := &EsMockServer{statusCodes: statusCodes}
+    mock.Server = httptest.NewServer(http.HandlerFunc(mock.handle))
+    return mock
+}
+
+// Records the request and replies with the status code for this attempt,
+// after the configured delay.
+func (mock *EsMockServer) handle(writer http.ResponseWriter, request *http.Request) {
+    body, _ := io.ReadAll(request.Body)
+
+    mock.mu.Lock()
+    attempt := len(mock.requests)
+    mock.requests = append(mock.requests, EsRequest{
+        Method: request.Method,
+        Path:   request.URL.Path,
+        Header: request.Header.Clone(),
+        Body:   body,
+    })
+    status := http.StatusOK
+    if len(mock.statusCodes) > 0 {
+        status = mock.statusCodes[min(attempt, len(mock.statusCodes)-1)]
+    }
+    delay := mock.delay
+    mock.inFlight++
+    mock.maxInFlight = max(mock.maxInFlight, mock.inFlight)
+    mock.mu.Unlock()
+
+    time.Sleep(delay)
+    writer.WriteHeader(status)
+
+    mock.mu.Lock()
+    mock.inFlight--
+    mock.mu.Unlock()
+}
+
+// Returns the bodies of all requests received so far, in arrival order.
+func (mock *EsMockServer) Payloads() [][]byte {
+    mock.mu.Lock()
+    defer mock.mu.Unlock()
+    payloads := make([][]byte, len(mock.requests))
+    for i, request := range mock.requests {
+        payloads[i] = request.Body
+    }
+    return payloads
+}
+
+// Returns all requests received so far, in arrival order.
+func (mock *EsMockServer) Requests() []EsRequest {
+    mock.mu.Lock()
+    defer mock.mu.Unlock()
+    return append([]EsRequest(nil), mock.requests...)
+}
+
+// Waits until at least n requests have arrived or timeout passes, and
+// returns the requests received by then.
+func (mock *EsMockServer) WaitForRequests(n int, timeout time.Duration) []EsRequest {
+    deadline := time.Now().Add(timeout)
+    for {
+        requests := mock.Requests()
+        if len(requests) >= n || time.Now().After(deadline) {
+            return requests
+        }
+        time.Sleep(5 * time.Millisecond)
+    }
+}
+
+// Replaces the status codes and forgets the recorded requests, so the new
+// codes apply from the next request on.
+func (mock *EsMockServer) SetStatusCodes(statusCodes ...int) {
+    mock.mu.Lock()
+    defer mock.mu.Unlock()
+    mock.statusCodes = statusCodes
+    mock.requests = nil
+}
+
+// Holds every response back by delay, to simulate a slow cluster.
+func (mock *EsMockServer) SetDelay(delay time.Duration) {
+    mock.mu.Lock()
+    defer mock.mu.Unlock()
+    mock.delay = delay
+}
+
+// Returns the most requests that were being handled at once.
+func (mock *EsMockServer) MaxInFlight() int {
+    mock.mu.Lock()
+    defer mock.mu.Unlock()
+    return mock.maxInFlight
+}
+
+// Forgets the recorded requests, so status codes apply from the first again.
+func (mock *EsMockServer) Reset() {
+    mock.mu.Lock()
+    defer mock.mu.Unlock()
+    mock.requests = nil
+}
@@ -0,0 +1,26 @@
+package testutil
+
+import (
+    "sync/atomic"
+    "indexer/internal/pkg/models"
+    "indexer/internal/pkg/processor"
+)
+
+// Implements processor.Processor without touching Redis, NLP or Elasticsearch.
+// Every call increments CallCount and returns Err. Read CallCount with
+// atomic.LoadInt32 while workers are running.
+type MockProcessor struct {
+    Err       error
+    CallCount int32
+}
+
+var _ processor.Processor = (*MockProcessor)(nil)
+
+// Records the call and returns the configured error.
+func (mock *MockProcessor) Process(pageData *models.PageData, doc *models.Document) error {
+    atomic.AddInt32(&mock.CallCount, 1)
+    if mock.Err == nil {
+        doc.URL = pageData.URL
+    }
+    return mock.Err
+}
@@ -2,7 +2,11 @@ package worker
 
 import (
     "context"
+    "errors"
+    "runtime/debug"
+    "sort"
     "sync"
+    "sync/atomic"
     "time"
     
     "go.uber.org/zap"
@@ -10,6 +14,7 @@ import (
     "indexer/internal/pkg/logger"
     "indexer/internal/pkg/processor"
     "indexer/internal/pkg/models"
+    "indexer/internal/pkg/processor/urlfilter"
     "indexer/internal/pkg/queue"
     "indexer/internal/pkg/indexer"
     "indexer/internal/pkg/metrics"
@@ -18,20 +23,139 @@ import (
 // Manages a pool of workers that process queue items in parallel
 type WorkerPool struct {
     numWorkers     int
-    queue          *queue.Queue
+    queue          queue.FifoQueue
     processor      processor.Processor
     indexer        *indexer.BulkIndexer
     wg             sync.WaitGroup
+
+    // Idle tracking: idleCh is closed once every worker is waiting on an
+    // empty queue, and replaced with a fresh channel when work resumes
+    idleMu         sync.Mutex
+    idleCh         chan struct{}
+    idle           bool
+    waitingWorkers int
+
+    // IDs of workers that have not exited yet
+    runningMu      sync.Mutex
+    running        map[int]struct{}
+
+    // Relaunch workers that panic instead of letting the pool shrink
+    autoRestart    bool
+
+    // Pages matching the filter are dropped before processing, nil keeps all
+    urlFilter      *urlfilter.URLFilter
+
+    // Mini-batches are dequeued when the processor supports them
+    batchSize      int
+    batcher        processor.BatchingProcessor
+
+    // Counters reported by Status
+    activeWorkers  atomic.Int64
+    totalProcessed atomic.Int64
+    totalErrors    atomic.Int64
+}
+
+// Snapshot of the worker pool state
+type WorkerPoolStatus struct {
+    NumWorkers     int `json:"num_workers"`
+    ActiveWorkers  int `json:"active_workers"`  // workers currently processing a page
+    TotalProcessed int `json:"total_processed"` // pages taken off the queue
+    TotalErrors    int `json:"total_errors"`    // pages that failed processing
+}
+
+// Configures optional WorkerPool behaviour
+type Option func(*WorkerPool)
+
+// Delay before a panicked worker is relaunched
+var workerRestartBackoff = time.Second
+
+// Pause before a worker checks again whether an unhealthy indexer recovered
+var indexerBackpressureDelay = time.Second
+
+// Recovers worker panics and restarts the worker after a short backoff.
+func WithAutoRestart(enabled bool) Option {
+    return func(wp *WorkerPool) {
+        wp.autoRestart = enabled
+    }
+}
+
+// Dequeues up to size pages at a time and processes them together when the
+// processor implements processor.BatchingProcessor.
+func WithBatchSize(size int) Option {
+    return func(wp *WorkerPool) {
+        wp.batchSize = size
+    }
+}
+
+// Drops pages whose URL the filter rejects before they are processed.
+func WithURLFilter(filter *urlfilter.URLFilter) Option {
+    return func(wp *WorkerPool) {
+        wp.urlFilter = filter
+    }
 }
 
 // Creates a new worker pool with the specified number of workers
-func NewWorkerPool(numWorkers int, queue *queue.Queue, processor processor.Processor, indexer *indexer.BulkIndexer) *WorkerPool {
-    return &WorkerPool{
+func NewWorkerPool(numWorkers int, queue queue.FifoQueue, processor processor.Processor, indexer *indexer.BulkIndexer, opts ...Option) *WorkerPool {
+    wp := &WorkerPool{
         numWorkers: numWorkers,
         queue:      queue,
         processor:  processor,
         indexer:    indexer,
+        idleCh:     make(chan struct{}),
+        running:    make(map[int]struct{}),
+    }
+    for _, opt := range opts {
+        opt(wp)
+    }
+    wp.batcher = batchingProcessor(processor, wp.batchSize)
+    return wp
+}
+
+// Returns proc as a BatchingProcessor if batches are enabled and supported
+func batchingProcessor(proc processor.Processor, batchSize int) processor.BatchingProcessor {
+    if batcher, ok := proc.(processor.BatchingProcessor); ok && batchSize > 1 {
+        return batcher
+    }
+    return nil
+}
+
+// Returns a channel that is closed when the queue is empty and all workers
+// are waiting for work. Call again after work resumes to get a new channel.
+func (wp *WorkerPool) IdleNotify() <-chan struct{} {
+    wp.idleMu.Lock()
+    defer wp.idleMu.Unlock()
+
+    // Items may have been queued since the workers last checked
+    if wp.idle && !wp.queue.IsEmpty() {
+        wp.resume()
     }
+    return wp.idleCh
+}
+
+// Records a worker moving between waiting for work and processing it.
+func (wp *WorkerPool) setWaiting(waiting bool) {
+    wp.idleMu.Lock()
+    defer wp.idleMu.Unlock()
+
+    if waiting {
+        wp.waitingWorkers++
+        if wp.waitingWorkers == wp.numWorkers && !wp.idle {
+            wp.idle = true
+            close(wp.idleCh)
+        }
+        return
+    }
+
+    wp.waitingWorkers--
+    if wp.idle {
+        wp.resume()
+    }
+}
+
+// Swaps in a fresh idle channel. Callers must hold idleMu.
+func (wp *WorkerPool) resume() {
+    wp.idle = false
+    wp.idleCh = make(chan struct{})
 }
 
 // Launches the worker goroutines
@@ -40,8 +164,51 @@ func (wp *WorkerPool) Start(ctx context.Context) {
     
     for i := 0; i < wp.numWorkers; i++ {
         wp.wg.Add(1)
-        go wp.runWorker(ctx, i)
+        wp.runningMu.Lock()
+        wp.running[i] = struct{}{}
+        wp.runningMu.Unlock()
+        go wp.startWorker(ctx, i)
+    }
+}
+
+// Runs a worker until it exits, supervising it when auto restart is enabled
+func (wp *WorkerPool) startWorker(ctx context.Context, id int) {
+    defer wp.wg.Done()
+    defer func() {
+        wp.runningMu.Lock()
+        delete(wp.running, id)
+        wp.runningMu.Unlock()
+    }()
+
+    if !wp.autoRestart {
+        wp.runWorker(ctx, id)
+        return
     }
+
+    for wp.runWorkerRecovered(ctx, id) {
+        metrics.WorkerRestarts.Inc()
+        select {
+        case <-ctx.Done():
+            return
+        case <-time.After(workerRestartBackoff):
+            logger.Log.Info("Restarting worker", zap.Int("worker_id", id))
+        }
+    }
+}
+
+// Runs a worker, returning true if it stopped because of a panic
+func (wp *WorkerPool) runWorkerRecovered(ctx context.Context, id int) (panicked bool) {
+    defer func() {
+        if r := recover(); r != nil {
+            logger.Log.Error("Worker panicked",
+                zap.Int("worker_id", id),
+                zap.Any("panic", r),
+                zap.ByteString("stack", debug.Stack()))
+            panicked = true
+        }
+    }()
+    wp.runWorker(ctx, id)
+    return false
 }
 
 // Blocks until all workers have finished
@@ -49,11 +216,65 @@ func (wp *WorkerPool) Wait() {
     wp.wg.Wait()
 }
 
+// Blocks until all workers have finished or the context is done,
+// in which case the context error is returned.
+func (wp *WorkerPool) WaitContext(ctx context.Context) error {
+    done := make(chan struct{})
+    go func() {
+        wp.wg.Wait()
+        close(done)
+    }()
+
+    select {
+    case <-done:
+        return nil
+    case <-ctx.Done():
+        return ctx.Err()
+    }
+}
+
+// Returns the current worker pool status
+func (wp *WorkerPool) Status() WorkerPoolStatus {
+    return WorkerPoolStatus{
+        NumWorkers:     wp.numWorkers,
+        ActiveWorkers:  int(wp.activeWorkers.Load()),
+        TotalProcessed: int(wp.totalProcessed.Load()),
+        TotalErrors:    int(wp.totalErrors.Load()),
+    }
+}
+
+// Runs the processor on a page, counting the worker as active meanwhile
+func (wp *WorkerPool) process(pageData *models.PageData, document *models.Document) error {
+    wp.addActiveWorkers(1)
+    defer wp.addActiveWorkers(-1)
+    defer wp.totalProcessed.Add(1)
+    return wp.processor.Process(pageData, document)
+}
+
+// Adjusts the active worker count, keeping the ActiveWorkers gauge in sync
+func (wp *WorkerPool) addActiveWorkers(delta int64) {
+    wp.activeWorkers.Add(delta)
+    metrics.ActiveWorkers.Add(float64(delta))
+}
+
+// Returns the IDs of workers that have not exited yet
+func (wp *WorkerPool) RunningWorkers() []int {
+    wp.runningMu.Lock()
+    defer wp.runningMu.Unlock()
+
+    ids := make([]int, 0, len(wp.running))
+    for id := range wp.running {
+        ids = append(ids, id)
+    }
+    sort.Ints(ids)
+    return ids
+}
+
 // The main loop for each worker goroutine
 func (wp *WorkerPool) runWorker(ctx context.Context, id int) {
-    defer wp.wg.Done()
-    
     logger.Log.Info("Worker started", zap.Int("worker_id", id))
+
+    waiting := false
     
     for {
         select {
@@ -61,32 +282,146 @@ func (wp *WorkerPool) runWorker(ctx context.Context, id int) {
             logger.Log.Info("Worker received stop signal", zap.Int("worker_id", id))
             return
         default:
-            pageData, err := wp.queue.Remove()
-            if err != nil {
+            // Leave pages queued rather than losing them to a failing indexer
+            if wp.indexer != nil && !wp.indexer.IsHealthy() {
+                metrics.WorkerBackpressurePauses.Inc()
+                logger.Log.Debug("Indexer unhealthy, pausing worker", zap.Int("worker_id", id))
+                select {
+                case <-ctx.Done():
+                case <-time.After(indexerBackpressureDelay):
+                }
+                continue
+            }
+
+            batch := wp.dequeue()
+            if len(batch) == 0 {
+                if !waiting {
+                    waiting = true
+                    wp.setWaiting(true)
+                }
                 // If queue is empty, wait a bit before trying again
                 time.Sleep(200 * time.Millisecond)
                 continue
             }
-            
-            var document models.Document
-            err = wp.processor.Process(&pageData, &document)
-            if err != nil {
-                logger.Log.Warn("Failed to process page",
-                    zap.Int("worker_id", id),
-                    zap.String("url", pageData.URL),
-                    zap.Error(err))
-                
-                if err.Error() == "duplicate page detected" {
-                    metrics.DuplicatesDetected.Inc()
-                }
-            } else {
-                logger.Log.Debug("Processed page", 
-                    zap.Int("worker_id", id),
-                    zap.String("url", pageData.URL))
-                
-                // Add the document to the indexer
-                wp.indexer.AddDocumentToIndexerPayload(&document)
+            if waiting {
+                waiting = false
+                wp.setWaiting(false)
+            }
+
+            if batch = wp.filterURLs(id, batch); len(batch) == 0 {
+                continue
+            }
+
+            if len(batch) == 1 {
+                var document models.Document
+                err := wp.process(&batch[0], &document)
+                wp.handleResult(id, &batch[0], &document, err)
+                continue
+            }
+
+            results := wp.processBatch(batch)
+            for i := range results {
+                wp.handleResult(id, &batch[i], &results[i].Document, results[i].Err)
             }
         }
     }
+}
+
+// Removes up to batchSize items from the queue, or a single item when the
+// processor cannot handle batches
+func (wp *WorkerPool) dequeue() []models.PageData {
+    size := 1
+    if wp.batcher != nil {
+        size = wp.batchSize
+    }
+
+    var batch []models.PageData
+    for len(batch) < size {
+        pageData, err := wp.queue.Remove()
+        if err != nil {
+            break
+        }
+        batch = append(batch, pageData)
+    }
+    return batch
+}
+
+// Drops the pages the URL filter rejects, counting each rejection
+func (wp *WorkerPool) filterURLs(id int, batch []models.PageData) []models.PageData {
+    if wp.urlFilter == nil {
+        return batch
+    }
+    kept := batch[:0]
+    for _, pageData := range batch {
+        if wp.urlFilter.ShouldSkip(pageData.URL) {
+            metrics.URLFilterRejections.Inc()
+            logger.Log.Debug("Skipping page rejected by URL filter",
+                zap.Int("worker_id", id),
+                zap.String("url", pageData.URL))
+            continue
+        }
+        kept = append(kept, pageData)
+    }
+    return kept
+}
+
+// Runs a mini-batch through the batching processor, counting the worker as active meanwhile
+func (wp *WorkerPool) processBatch(batch []models.PageData) []processor.ProcessResult {
+    wp.addActiveWorkers(1)
+    defer wp.addActiveWorkers(-1)
+    defer wp.totalProcessed.Add(int64(len(batch)))
+
+    items := make([]*models.PageData, len(batch))
+    for i := range batch {
+        items[i] = &batch[i]
+    }
+    // Per-item errors are handled from the results
+    results, _ := wp.batcher.ProcessBatch(items)
+    return results
+}
+
+// Indexes a processed document, or records why the page was skipped
+func (wp *WorkerPool) handleResult(id int, pageData *models.PageData, document *models.Document, err error) {
+    if err != nil {
+        var statusErr processor.ErrNonIndexableStatus
+        if errors.As(err, &statusErr) ||
+            errors.Is(err, processor.ErrSkippedDomain) ||
+            errors.Is(err, processor.ErrRobotsTxtDisallowed) ||
+            errors.Is(err, processor.ErrBinaryContent) {
+            // Expected skip, already logged by the processor
+            return
+        }
+        if errors.Is(err, processor.ErrEmptyContent) {
+            metrics.EmptyContentSkipped.Inc()
+            logger.Log.Debug("Skipping page with empty content",
+                zap.Int("worker_id", id),
+                zap.String("url", pageData.URL))
+            return
+        }
+        if errors.Is(err, processor.ErrRobotsNoIndex) {
+            metrics.RobotsNoIndexSkipped.Inc()
+            logger.Log.Info("Skipping page marked noindex",
+                zap.Int("worker_id", id),
+                zap.String("url", pageData.URL))
+            return
+        }
+
+        wp.totalErrors.Add(1)
+        logger.Log.Warn("Failed to process page",
+            zap.Int("worker_id", id),
+            zap.String("url", pageData.URL),
+            zap.Error(err))
+        
+        if err.Error() == "duplicate page detected" {
+            metrics.DuplicatesDetected.Inc()
+        }
+        return
+    }
+
+    logger.Log.Debug("Processed page", 
+        zap.Int("worker_id", id),
+        zap.String("url", pageData.URL))
+    
+    // Add the document to the indexer
+    wp.indexer.AddDocumentToIndexerPayload(document)
 }
\ No newline at end of file
@@ -0,0 +1,435 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+	"go.uber.org/zap"
+	dto "github.com/prometheus/client_model/go"
+	"indexer/internal/pkg/indexer"
+	"indexer/internal/pkg/logger"
+	"indexer/internal/pkg/metrics"
+	"indexer/internal/pkg/models"
+	"indexer/internal/pkg/processor"
+	"indexer/internal/pkg/processor/urlfilter"
+	"indexer/internal/pkg/queue"
+	"indexer/internal/pkg/testutil"
+)
+
+func init() {
+	// Ensure that the logger is not nil during tests.
+	logger.Log = zap.NewNop()
+}
+
+// Waits for the channel to close or fails the test.
+func waitIdle(t *testing.T, idle <-chan struct{}) {
+	t.Helper()
+	select {
+	case <-idle:
+	case <-time.After(3 * time.Second):
+		t.Fatal("Timed out waiting for worker pool to become idle")
+	}
+}
+
+// Verifies that IdleNotify fires once the queue is drained and is re-armed
+// when new work arrives.
+func TestWorkerPoolIdleNotify(t *testing.T) {
+	q, err := queue.CreateQueue(10)
+	if err != nil {
+		t.Fatalf("Failed to create queue: %v", err)
+	}
+	proc := &testutil.MockProcessor{Err: errors.New("rejected")}
+	wp := NewWorkerPool(2, q, proc, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		wp.Wait()
+	}()
+	wp.Start(ctx)
+
+	waitIdle(t, wp.IdleNotify())
+
+	for _, url := range []string{"a", "b", "c"} {
+		if err := q.Insert(models.PageData{URL: url}); err != nil {
+			t.Fatalf("Insert error: %v", err)
+		}
+	}
+
+	idle := wp.IdleNotify()
+	select {
+	case <-idle:
+		t.Fatal("Expected idle channel to be re-armed while work is queued")
+	default:
+	}
+
+	waitIdle(t, idle)
+	if got := atomic.LoadInt32(&proc.CallCount); got != 3 {
+		t.Errorf("Expected 3 pages to be processed before idle, got %d", got)
+	}
+}
+
+// blockingProcessor blocks until released, simulating a hung NLP call.
+type blockingProcessor struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (bp *blockingProcessor) Process(pageData *models.PageData, doc *models.Document) error {
+	bp.started <- struct{}{}
+	<-bp.release
+	return errors.New("rejected")
+}
+
+// Verifies that WaitContext gives up on stuck workers and reports them.
+func TestWorkerPoolWaitContextTimeout(t *testing.T) {
+	q, err := queue.CreateQueue(10)
+	if err != nil {
+		t.Fatalf("Failed to create queue: %v", err)
+	}
+	q.Insert(models.PageData{URL: "stuck"})
+
+	proc := &blockingProcessor{started: make(chan struct{}, 1), release: make(chan struct{})}
+	wp := NewWorkerPool(1, q, proc, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	wp.Start(ctx)
+	<-proc.started
+	cancel()
+
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer waitCancel()
+	if err := wp.WaitContext(waitCtx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+	if running := wp.RunningWorkers(); len(running) != 1 || running[0] != 0 {
+		t.Errorf("Expected worker 0 to still be running, got %v", running)
+	}
+
+	close(proc.release)
+	if err := wp.WaitContext(context.Background()); err != nil {
+		t.Errorf("Expected workers to finish once released, got %v", err)
+	}
+	if running := wp.RunningWorkers(); len(running) != 0 {
+		t.Errorf("Expected no running workers, got %v", running)
+	}
+}
+
+// Verifies that the ActiveWorkers gauge counts workers busy processing a page.
+func TestWorkerPoolActiveWorkersGauge(t *testing.T) {
+	q, err := queue.CreateQueue(10)
+	if err != nil {
+		t.Fatalf("Failed to create queue: %v", err)
+	}
+	q.Insert(models.PageData{URL: "stuck"})
+
+	proc := &blockingProcessor{started: make(chan struct{}, 1), release: make(chan struct{})}
+	wp := NewWorkerPool(2, q, proc, nil)
+
+	activeWorkers := func() float64 {
+		var metric dto.Metric
+		if err := metrics.ActiveWorkers.Write(&metric); err != nil {
+			t.Fatalf("Failed to read gauge: %v", err)
+		}
+		return metric.GetGauge().GetValue()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	wp.Start(ctx)
+	<-proc.started
+
+	if active := activeWorkers(); active != 1 {
+		t.Errorf("Expected 1 active worker while processing, got %v", active)
+	}
+
+	close(proc.release)
+	cancel()
+	if err := wp.WaitContext(context.Background()); err != nil {
+		t.Fatalf("Expected workers to finish, got %v", err)
+	}
+	if active := activeWorkers(); active != 0 {
+		t.Errorf("Expected 0 active workers once idle, got %v", active)
+	}
+}
+
+// panickingProcessor panics on its first call and rejects every later page.
+type panickingProcessor struct {
+	calls int32
+}
+
+func (pp *panickingProcessor) Process(pageData *models.PageData, doc *models.Document) error {
+	if atomic.AddInt32(&pp.calls, 1) == 1 {
+		panic("processor crashed")
+	}
+	return errors.New("rejected")
+}
+
+// Verifies that a panicking worker is relaunched when auto restart is enabled.
+func TestWorkerPoolAutoRestart(t *testing.T) {
+	defer func(backoff time.Duration) { workerRestartBackoff = backoff }(workerRestartBackoff)
+	workerRestartBackoff = 10 * time.Millisecond
+
+	q, err := queue.CreateQueue(10)
+	if err != nil {
+		t.Fatalf("Failed to create queue: %v", err)
+	}
+	q.Insert(models.PageData{URL: "crash"})
+	q.Insert(models.PageData{URL: "after-restart"})
+
+	proc := &panickingProcessor{}
+	wp := NewWorkerPool(1, q, proc, nil, WithAutoRestart(true))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		wp.Wait()
+	}()
+	wp.Start(ctx)
+
+	waitIdle(t, wp.IdleNotify())
+	if got := atomic.LoadInt32(&proc.calls); got != 2 {
+		t.Errorf("Expected restarted worker to process the remaining page, got %d calls", got)
+	}
+	if running := wp.RunningWorkers(); len(running) != 1 {
+		t.Errorf("Expected the worker to still be running, got %v", running)
+	}
+}
+
+// Verifies that the pool starts the configured number of workers.
+func TestWorkerPoolWorkerCount(t *testing.T) {
+	q, err := queue.CreateQueue(10)
+	if err != nil {
+		t.Fatalf("Failed to create queue: %v", err)
+	}
+	wp := NewWorkerPool(3, q, &testutil.MockProcessor{}, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		wp.Wait()
+	}()
+	wp.Start(ctx)
+
+	if running := wp.RunningWorkers(); len(running) != 3 {
+		t.Errorf("Expected 3 running workers, got %v", running)
+	}
+}
+
+// Verifies that processing errors are skipped and successful pages reach the indexer.
+func TestWorkerPoolProcessing(t *testing.T) {
+	bulkIndexer, err := indexer.NewBulkIndexer(context.Background(), 100, "http://localhost:9200/_bulk", "test_index", 60, 0, indexer.WithDryRun(true))
+	if err != nil {
+		t.Fatalf("Failed to create BulkIndexer: %v", err)
+	}
+	defer bulkIndexer.Stop()
+
+	for _, tc := range []struct {
+		name string
+		err  error
+	}{
+		{"success", nil},
+		{"error", errors.New("rejected")},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			q, err := queue.CreateQueue(10)
+			if err != nil {
+				t.Fatalf("Failed to create queue: %v", err)
+			}
+			for _, url := range []string{"https://example.com/a", "https://example.com/b"} {
+				q.Insert(models.PageData{URL: url})
+			}
+
+			proc := &testutil.MockProcessor{Err: tc.err}
+			wp := NewWorkerPool(2, q, proc, bulkIndexer)
+			ctx, cancel := context.WithCancel(context.Background())
+			wp.Start(ctx)
+
+			waitIdle(t, wp.IdleNotify())
+			cancel()
+			wp.Wait()
+
+			if got := atomic.LoadInt32(&proc.CallCount); got != 2 {
+				t.Errorf("Expected 2 pages to be processed, got %d", got)
+			}
+		})
+	}
+}
+
+// Verifies that pages rejected by the URL filter never reach the processor.
+func TestWorkerPoolURLFilter(t *testing.T) {
+	q, err := queue.CreateQueue(10)
+	if err != nil {
+		t.Fatalf("Failed to create queue: %v", err)
+	}
+	for _, url := range []string{"https://example.com/a", "https://example.com/login", "https://ads.tracker.test/p.gif"} {
+		q.Insert(models.PageData{URL: url})
+	}
+
+	var before dto.Metric
+	metrics.URLFilterRejections.Write(&before)
+
+	bulkIndexer, err := indexer.NewBulkIndexer(context.Background(), 100, "http://localhost:9200/_bulk", "test_index", 60, 0, indexer.WithDryRun(true))
+	if err != nil {
+		t.Fatalf("Failed to create BulkIndexer: %v", err)
+	}
+	defer bulkIndexer.Stop()
+
+	proc := &testutil.MockProcessor{}
+	filter := urlfilter.New([]string{"tracker.test", "/login"})
+	wp := NewWorkerPool(1, q, proc, bulkIndexer, WithURLFilter(filter))
+	ctx, cancel := context.WithCancel(context.Background())
+	wp.Start(ctx)
+
+	waitIdle(t, wp.IdleNotify())
+	cancel()
+	wp.Wait()
+
+	if got := atomic.LoadInt32(&proc.CallCount); got != 1 {
+		t.Errorf("Expected 1 page to be processed, got %d", got)
+	}
+	var after dto.Metric
+	metrics.URLFilterRejections.Write(&after)
+	if got := after.GetCounter().GetValue() - before.GetCounter().GetValue(); got != 2 {
+		t.Errorf("Expected 2 URL filter rejections, got %v", got)
+	}
+}
+
+// Verifies that workers leave pages queued while the indexer is unhealthy.
+func TestWorkerPoolBackpressure(t *testing.T) {
+	defer func(delay time.Duration) { indexerBackpressureDelay = delay }(indexerBackpressureDelay)
+	indexerBackpressureDelay = 10 * time.Millisecond
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	bulkIndexer, err := indexer.NewBulkIndexer(context.Background(), 100, server.URL, "test_index", 60, 0)
+	if err != nil {
+		t.Fatalf("Failed to create BulkIndexer: %v", err)
+	}
+	defer bulkIndexer.Stop()
+	bulkIndexer.AddDocumentToIndexerPayload(&models.Document{URL: "https://example.com/failed"})
+	bulkIndexer.ForceFlush()
+	if bulkIndexer.IsHealthy() {
+		t.Fatal("Expected the indexer to be unhealthy after a failed flush")
+	}
+
+	q, err := queue.CreateQueue(10)
+	if err != nil {
+		t.Fatalf("Failed to create queue: %v", err)
+	}
+	q.Insert(models.PageData{URL: "https://example.com/a"})
+
+	proc := &testutil.MockProcessor{}
+	wp := NewWorkerPool(1, q, proc, bulkIndexer)
+	ctx, cancel := context.WithCancel(context.Background())
+	wp.Start(ctx)
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	wp.Wait()
+
+	if got := atomic.LoadInt32(&proc.CallCount); got != 0 {
+		t.Errorf("Expected no pages to be processed while unhealthy, got %d", got)
+	}
+	if q.Length() != 1 {
+		t.Errorf("Expected the page to stay queued, got queue length %d", q.Length())
+	}
+}
+
+// Verifies that cancelling the context stops every worker.
+func TestWorkerPoolGracefulShutdown(t *testing.T) {
+	q, err := queue.CreateQueue(10)
+	if err != nil {
+		t.Fatalf("Failed to create queue: %v", err)
+	}
+	wp := NewWorkerPool(4, q, &testutil.MockProcessor{}, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	wp.Start(ctx)
+	cancel()
+
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer waitCancel()
+	if err := wp.WaitContext(waitCtx); err != nil {
+		t.Fatalf("Expected workers to stop after cancellation, got %v", err)
+	}
+	if running := wp.RunningWorkers(); len(running) != 0 {
+		t.Errorf("Expected no running workers, got %v", running)
+	}
+}
+
+// Verifies that Status reports processed pages and errors.
+func TestWorkerPoolStatus(t *testing.T) {
+	q, err := queue.CreateQueue(10)
+	if err != nil {
+		t.Fatalf("Failed to create queue: %v", err)
+	}
+	for _, url := range []string{"a", "b", "c"} {
+		q.Insert(models.PageData{URL: url})
+	}
+	wp := NewWorkerPool(2, q, &testutil.MockProcessor{Err: errors.New("rejected")}, nil)
+
+	if status := wp.Status(); status != (WorkerPoolStatus{NumWorkers: 2}) {
+		t.Errorf("Expected an untouched pool, got %+v", status)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		wp.Wait()
+	}()
+	wp.Start(ctx)
+	waitIdle(t, wp.IdleNotify())
+
+	expected := WorkerPoolStatus{NumWorkers: 2, ActiveWorkers: 0, TotalProcessed: 3, TotalErrors: 3}
+	if status := wp.Status(); status != expected {
+		t.Errorf("Expected status %+v, got %+v", expected, status)
+	}
+}
+
+// Verifies that workers dequeue mini-batches for a batching processor.
+func TestWorkerPoolBatches(t *testing.T) {
+	bulkIndexer, err := indexer.NewBulkIndexer(context.Background(), 100, "http://localhost:9200/_bulk", "test_index", 60, 0, indexer.WithDryRun(true))
+	if err != nil {
+		t.Fatalf("Failed to create BulkIndexer: %v", err)
+	}
+	defer bulkIndexer.Stop()
+
+	q, err := queue.CreateQueue(10)
+	if err != nil {
+		t.Fatalf("Failed to create queue: %v", err)
+	}
+	for _, url := range []string{"a", "b", "c", "d", "e"} {
+		q.Insert(models.PageData{URL: "https://example.com/" + url})
+	}
+
+	mock := &testutil.MockProcessor{}
+	wp := NewWorkerPool(1, q, processor.NewConcurrentProcessor(mock, 2), bulkIndexer, WithBatchSize(3))
+	if wp.batcher == nil {
+		t.Fatal("Expected the batching processor to be used")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		wp.Wait()
+	}()
+	wp.Start(ctx)
+	waitIdle(t, wp.IdleNotify())
+
+	if got := atomic.LoadInt32(&mock.CallCount); got != 5 {
+		t.Errorf("Expected 5 pages to be processed, got %d", got)
+	}
+	if status := wp.Status(); status.TotalProcessed != 5 || status.TotalErrors != 0 {
+		t.Errorf("Unexpected status %+v", status)
+	}
+
+	if NewWorkerPool(1, q, mock, nil, WithBatchSize(3)).batcher != nil {
+		t.Error("Expected a plain processor to be used one page at a time")
+	}
+}